@@ -0,0 +1,23 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/tracevt/chirpy/internal/auth"
+)
+
+// isAdmin reports whether the request carries the ADMIN_KEY api key. Admin
+// endpoints are closed when no key is configured.
+func (cfg *apiConfig) isAdmin(r *http.Request) bool {
+	if cfg.adminKey == "" {
+		return false
+	}
+
+	providedKey, err := auth.GetAPIKey(r.Header)
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(providedKey), []byte(cfg.adminKey)) == 1
+}
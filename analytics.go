@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/auth"
+)
+
+type ChirpAnalytics struct {
+	ChirpID      uuid.UUID `json:"chirp_id"`
+	Views        int64     `json:"views"`
+	LikeCount    int64     `json:"like_count"`
+	RechirpCount int64     `json:"rechirp_count"`
+	ReplyCount   int64     `json:"reply_count"`
+}
+
+func (cfg *apiConfig) getChirpAnalytics(w http.ResponseWriter, r *http.Request) {
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, r, problemInvalidID, "Chirp UUID is not in the correct format", err)
+		return
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpUUID)
+
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, r, problemNotFound, "Chirp not found", err)
+		return
+	}
+
+	// Only the author gets to see how a chirp is doing
+	if userID != chirp.UserID {
+		respondWithError(w, r, problemForbidden, "You're not allowed to see that", nil)
+		return
+	}
+
+	if !cfg.requireFeature(w, r, userID, FeatureChirpAnalytics) {
+		return
+	}
+
+	analytics, err := cfg.db.GetChirpAnalytics(r.Context(), chirpUUID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve analytics", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, ChirpAnalytics{
+		ChirpID:      chirpUUID,
+		Views:        analytics.Views,
+		LikeCount:    analytics.LikeCount,
+		RechirpCount: analytics.RechirpCount,
+		ReplyCount:   analytics.ReplyCount,
+	})
+}
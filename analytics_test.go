@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestChirpAnalytics(t *testing.T) {
+	ts := newTestServer(t)
+	walt := ts.signUp("walt@example.com")
+	jesse := ts.signUp("jesse@example.com")
+	chirp := ts.createChirp(walt.Token, "Say my name")
+	path := "/api/chirps/" + chirp.ID.String()
+
+	for i := 0; i < 3; i++ {
+		expectStatus(t, ts.do("GET", path, nil, ""), http.StatusOK)
+	}
+	expectStatus(t, ts.do("PUT", path+"/like", nil, bearer(jesse.Token)), http.StatusNoContent)
+	expectStatus(t, ts.do("POST", "/api/chirps", map[string]string{"body": "Heisenberg", "in_reply_to": chirp.ID.String()}, bearer(jesse.Token)), http.StatusCreated)
+
+	expectStatus(t, ts.do("GET", path+"/analytics", nil, bearer(walt.Token)), http.StatusForbidden)
+
+	ts.upgrade(walt.ID)
+	ts.upgrade(jesse.ID)
+
+	expectStatus(t, ts.do("GET", path+"/analytics", nil, bearer(jesse.Token)), http.StatusForbidden)
+
+	rec := ts.do("GET", path+"/analytics", nil, bearer(walt.Token))
+	expectStatus(t, rec, http.StatusOK)
+	want := ChirpAnalytics{ChirpID: chirp.ID, Views: 3, LikeCount: 1, ReplyCount: 1}
+	if got := decodeBody[ChirpAnalytics](t, rec); got != want {
+		t.Errorf("analytics = %+v, want %+v", got, want)
+	}
+}
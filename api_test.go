@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/mail"
+	"github.com/tracevt/chirpy/internal/moderation"
+	"github.com/tracevt/chirpy/internal/store"
+	"github.com/tracevt/chirpy/internal/webhook"
+)
+
+const (
+	testSecret   = "test-jwt-secret"
+	testPolkaKey = "test-polka-key"
+	testAdminKey = "test-admin-key"
+)
+
+// testLogs receives everything the server logs during the tests.
+var testLogs = &logRecorder{}
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	out := io.Discard
+	if testing.Verbose() {
+		out = os.Stderr
+	}
+	testLogs.Handler = slog.NewTextHandler(out, nil)
+	slog.SetDefault(slog.New(requestIDHandler{testLogs}))
+
+	code := m.Run()
+
+	// Only a full run is expected to reach every route
+	if code == 0 && flag.Lookup("test.run").Value.String() == "" {
+		if missing := untestedRoutes(); len(missing) > 0 {
+			fmt.Fprintf(os.Stderr, "routes without tests:\n  %s\n", strings.Join(missing, "\n  "))
+			code = 1
+		}
+	}
+
+	os.Exit(code)
+}
+
+// logRecorder keeps the access log lines, which tell which routes the suite
+// reached and who made each request.
+type logRecorder struct {
+	slog.Handler
+	requests sync.Map // request ID to the access log attributes
+	routes   sync.Map // every route pattern a request matched
+}
+
+func (l *logRecorder) Handle(ctx context.Context, record slog.Record) error {
+	if record.Message == "request" {
+		attrs := map[string]string{}
+		record.Attrs(func(attr slog.Attr) bool {
+			attrs[attr.Key] = attr.Value.String()
+			return true
+		})
+		l.requests.Store(attrs["request_id"], attrs)
+		// Patterns are registered with or without a method
+		l.routes.Store(attrs["method"]+" "+attrs["route"], true)
+		l.routes.Store(attrs["route"], true)
+	}
+	return l.Handler.Handle(ctx, record)
+}
+
+// accessLog returns the access log attributes of a request.
+func (l *logRecorder) accessLog(requestID string) map[string]string {
+	attrs, _ := l.requests.Load(requestID)
+	log, _ := attrs.(map[string]string)
+	return log
+}
+
+func untestedRoutes() []string {
+	src, err := os.ReadFile("main.go")
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	missing := make([]string, 0)
+	for _, match := range regexp.MustCompile(`mux\.Handle(?:Func)?\("([^"]+)"`).FindAllStringSubmatch(string(src), -1) {
+		if _, ok := testLogs.routes.Load(match[1]); !ok {
+			missing = append(missing, match[1])
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// testServer is the API running on a fresh store, see testStore.
+type testServer struct {
+	t       *testing.T
+	cfg     *apiConfig
+	db      store.Store
+	mail    *mail.Memory
+	handler http.Handler
+}
+
+// testStore is the in-memory store, or a new SQLite database when
+// CHIRPY_TEST_STORE=sqlite, to run the suite against SQLite.
+func testStore(t *testing.T) store.Store {
+	t.Helper()
+
+	if os.Getenv("CHIRPY_TEST_STORE") != "sqlite" {
+		return store.NewMemory()
+	}
+
+	backend, db, err := store.Open("sqlite:" + filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatalf("store.Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := newMigrationProvider(backend, db)
+	if err != nil {
+		t.Fatalf("newMigrationProvider() error = %v", err)
+	}
+	if _, err := migrations.Up(context.Background()); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+
+	return store.New(backend, db)
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	keyring, err := loadKeyring(testSecret, "")
+	if err != nil {
+		t.Fatalf("loadKeyring() error = %v", err)
+	}
+
+	db := testStore(t)
+	mailer := mail.NewMemory()
+	cfg := &apiConfig{
+		db:            db,
+		platform:      "dev",
+		keyring:       keyring,
+		polka:         testPolkaKey,
+		adminKey:      testAdminKey,
+		moderation:    moderation.NewFilter(moderation.ActionMask, false),
+		webhookSender: webhook.NewSender(webhook.NewClient(webhookSendTimeout, true)),
+		metrics:       newServerMetrics(nil),
+		mailer:        mailer,
+		publicURL:     "http://chirpy.test",
+	}
+	cfg.registerHealthCheck("database", cfg.pingDatabase)
+
+	if err := cfg.reloadBadWords(context.Background()); err != nil {
+		t.Fatalf("reloadBadWords() error = %v", err)
+	}
+
+	return &testServer{
+		t:       t,
+		cfg:     cfg,
+		db:      db,
+		mail:    mailer,
+		handler: cfg.routes(),
+	}
+}
+
+// do sends a request with an optional JSON body and Authorization header.
+func (ts *testServer) do(method, target string, body interface{}, authorization string) *httptest.ResponseRecorder {
+	ts.t.Helper()
+
+	var reader *bytes.Reader
+	switch b := body.(type) {
+	case nil:
+		reader = bytes.NewReader(nil)
+	case []byte:
+		reader = bytes.NewReader(b)
+	case string:
+		reader = bytes.NewReader([]byte(b))
+	default:
+		dat, err := json.Marshal(b)
+		if err != nil {
+			ts.t.Fatalf("json.Marshal() error = %v", err)
+		}
+		reader = bytes.NewReader(dat)
+	}
+
+	req := httptest.NewRequest(method, target, reader)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	rec := httptest.NewRecorder()
+	ts.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func bearer(token string) string {
+	return "Bearer " + token
+}
+
+func adminKey() string {
+	return "ApiKey " + testAdminKey
+}
+
+// westOfUTC runs the rest of the test with the local time zone behind UTC,
+// where a local time stored in a timestamp column ends up in the past.
+func westOfUTC(t *testing.T) {
+	t.Helper()
+
+	local := time.Local
+	time.Local = time.FixedZone("UTC-7", -7*60*60)
+	t.Cleanup(func() { time.Local = local })
+}
+
+// expectStatus fails the test unless rec has the wanted status code.
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+
+	if rec.Code != want {
+		t.Fatalf("status = %d, want %d, body: %s", rec.Code, want, rec.Body.String())
+	}
+}
+
+// expectProblem fails the test unless rec is a problem of the wanted type.
+func expectProblem(t *testing.T, rec *httptest.ResponseRecorder, want problemType) Problem {
+	t.Helper()
+
+	expectStatus(t, rec, want.status)
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/problem+json" {
+		t.Errorf("Content-Type = %q, want application/problem+json", contentType)
+	}
+
+	problem := decodeBody[Problem](t, rec)
+	if problem.Code != want.code || problem.Type != problemTypeBase+want.code || problem.Detail == "" {
+		t.Errorf("problem = %+v, want code %s", problem, want.code)
+	}
+	return problem
+}
+
+func decodeBody[T any](t *testing.T, rec *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	var v T
+	if err := json.Unmarshal(rec.Body.Bytes(), &v); err != nil {
+		t.Fatalf("couldn't decode %q: %v", rec.Body.String(), err)
+	}
+	return v
+}
+
+// signUp creates a user and logs them in.
+func (ts *testServer) signUp(email string) UserWithToken {
+	ts.t.Helper()
+
+	rec := ts.do("POST", "/api/users", UserCredentials{Email: email, Password: "hunter2"}, "")
+	expectStatus(ts.t, rec, http.StatusCreated)
+
+	return ts.login(email, "hunter2")
+}
+
+func (ts *testServer) login(email, password string) UserWithToken {
+	ts.t.Helper()
+
+	rec := ts.do("POST", "/api/login", UserCredentials{Email: email, Password: password}, "")
+	expectStatus(ts.t, rec, http.StatusOK)
+
+	user := decodeBody[UserWithToken](ts.t, rec)
+	if user.Token == "" {
+		ts.t.Fatalf("login didn't return a token: %s", rec.Body.String())
+	}
+	return user
+}
+
+func (ts *testServer) createChirp(token, body string) Chirp {
+	ts.t.Helper()
+
+	rec := ts.do("POST", "/api/chirps", map[string]string{"body": body}, bearer(token))
+	expectStatus(ts.t, rec, http.StatusCreated)
+	return decodeBody[Chirp](ts.t, rec)
+}
+
+// sendPolkaEvent delivers a signed Polka webhook.
+func (ts *testServer) sendPolkaEvent(event WebhookEvent) *httptest.ResponseRecorder {
+	ts.t.Helper()
+
+	return ts.sendPolkaEventAt(event, time.Now())
+}
+
+// sendPolkaEventAt delivers a Polka webhook signed at signedAt.
+func (ts *testServer) sendPolkaEventAt(event WebhookEvent, signedAt time.Time) *httptest.ResponseRecorder {
+	ts.t.Helper()
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		ts.t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/api/polka/webhooks", bytes.NewReader(payload))
+	req.Header.Set(polkaSignatureHeader, webhook.Sign(testPolkaKey, signedAt, payload))
+
+	rec := httptest.NewRecorder()
+	ts.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// upgrade gives the user Chirpy Red.
+func (ts *testServer) upgrade(userID uuid.UUID) {
+	ts.t.Helper()
+
+	rec := ts.sendPolkaEvent(WebhookEvent{
+		ID:    uuid.NewString(),
+		Event: "user.upgraded",
+		Data:  UserData{UserID: userID.String()},
+	})
+	expectStatus(ts.t, rec, http.StatusNoContent)
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	ts := newTestServer(t)
+
+	expectStatus(t, ts.do("GET", "/api/healthz", nil, ""), http.StatusOK)
+	expectStatus(t, ts.do("GET", "/api/livez", nil, ""), http.StatusOK)
+
+	rec := ts.do("GET", "/api/readyz", nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	if report := decodeBody[ReadinessReport](t, rec); report.Checks["database"].Status != "ok" {
+		t.Errorf("database check = %+v, want ok", report.Checks["database"])
+	}
+
+	ts.cfg.registerHealthCheck("broken", func(ctx context.Context) error {
+		return errors.New("unreachable")
+	})
+	rec = ts.do("GET", "/api/readyz", nil, "")
+	expectStatus(t, rec, http.StatusServiceUnavailable)
+	if strings.Contains(rec.Body.String(), "unreachable") {
+		t.Errorf("readiness body = %s, want the check error left out", rec.Body.String())
+	}
+	if report := decodeBody[ReadinessReport](t, rec); report.Status != "fail" {
+		t.Errorf("status = %q, want fail", report.Status)
+	}
+
+	ts.cfg.draining.Store(true)
+	expectStatus(t, ts.do("GET", "/api/healthz", nil, ""), http.StatusServiceUnavailable)
+	expectStatus(t, ts.do("GET", "/api/livez", nil, ""), http.StatusOK)
+}
+
+func TestFileserverAndAdminMetrics(t *testing.T) {
+	ts := newTestServer(t)
+
+	expectStatus(t, ts.do("GET", "/app/", nil, ""), http.StatusOK)
+	expectStatus(t, ts.do("GET", "/app/", nil, ""), http.StatusOK)
+
+	rec := ts.do("GET", "/admin/metrics", nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	if !strings.Contains(rec.Body.String(), "visited 2 times") {
+		t.Errorf("admin metrics = %q, want 2 visits", rec.Body.String())
+	}
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	ts := newTestServer(t)
+
+	ts.signUp("walt@example.com")
+
+	rec := ts.do("GET", "/metrics", nil, "")
+	expectStatus(t, rec, http.StatusOK)
+
+	for _, want := range []string{
+		`chirpy_http_requests_total{method="POST",route="/api/users",status="201"} 1`,
+		`chirpy_logins_total{result="succeeded"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("metrics don't contain %q", want)
+		}
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	ts := newTestServer(t)
+
+	rec := ts.do("GET", "/.well-known/jwks.json", nil, "")
+	expectStatus(t, rec, http.StatusOK)
+
+	// The HMAC secret must never be published
+	if strings.Contains(rec.Body.String(), testSecret) {
+		t.Errorf("JWKS leaks the signing secret: %s", rec.Body.String())
+	}
+}
+
+func TestReset(t *testing.T) {
+	ts := newTestServer(t)
+
+	user := ts.signUp("walt@example.com")
+	expectStatus(t, ts.do("POST", "/admin/reset", nil, ""), http.StatusOK)
+
+	if _, err := ts.db.GetUser(context.Background(), user.ID); err == nil {
+		t.Errorf("user still exists after reset")
+	}
+
+	ts.cfg.platform = "prod"
+	expectStatus(t, ts.do("POST", "/admin/reset", nil, ""), http.StatusForbidden)
+}
@@ -1,191 +1,431 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"database/sql"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"sort"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/tracevt/chirpy/internal/auth"
 	"github.com/tracevt/chirpy/internal/database"
+	"github.com/tracevt/chirpy/internal/moderation"
 )
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Body          string     `json:"body"`
+	UserID        uuid.UUID  `json:"user_id"`
+	InReplyTo     *uuid.UUID `json:"in_reply_to,omitempty"`
+	RootID        *uuid.UUID `json:"root_id,omitempty"`
+	Deleted       bool       `json:"deleted,omitempty"`
+	RechirpedBy   *uuid.UUID `json:"rechirped_by,omitempty"`
+	LikeCount     int64      `json:"like_count"`
+	RechirpCount  int64      `json:"rechirp_count"`
+	LikedByMe     *bool      `json:"liked_by_me,omitempty"`
+	RechirpedByMe *bool      `json:"rechirped_by_me,omitempty"`
 }
 
-func containsBadWords(s string) bool {
-	if s == "kerfuffle" {
-		return true
-	} else if s == "sharbert" {
-		return true
-	} else if s == "fornax" {
-		return true
+// ThreadChirp is a node of the conversation tree returned by getThread.
+type ThreadChirp struct {
+	Chirp
+	Replies []*ThreadChirp `json:"replies"`
+}
+
+// chirpRow is a chirp as the chirp queries select it. sqlc generates a row
+// type for each of those queries, and every one of them is assignable to it.
+type chirpRow = struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	ParentID  uuid.NullUUID
+	RootID    uuid.NullUUID
+	DeletedAt sql.NullTime
+}
+
+func chirpFromDB(chirp chirpRow) Chirp {
+	jsonChirp := Chirp{
+		ID:        chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+		Body:      chirp.Body,
+		UserID:    chirp.UserID,
+		Deleted:   chirp.DeletedAt.Valid,
+	}
+
+	if chirp.ParentID.Valid {
+		jsonChirp.InReplyTo = &chirp.ParentID.UUID
 	}
 
-	return false
+	if chirp.RootID.Valid {
+		jsonChirp.RootID = &chirp.RootID.UUID
+	}
+
+	return jsonChirp
 }
 
 func (cfg *apiConfig) handleChirps(w http.ResponseWriter, r *http.Request) {
 	type message struct {
-		Body string `json:"body"`
+		Body      string     `json:"body"`
+		InReplyTo string     `json:"in_reply_to"`
+		PublishAt *time.Time `json:"publish_at"`
 	}
 
 	// Validate if the Token is valid
 	bearerToken, err := auth.GetBearerToken(r.Header)
 
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Please provide an auth token", err)
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
 		return
 	}
 
-	userIDFromToken, err := auth.ValidateJWT(bearerToken, cfg.secret)
+	userIDFromToken, err := cfg.keyring.ValidateJWT(bearerToken)
 
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
 		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
 	params := message{}
-	err = decoder.Decode(&params)
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	entitlements, err := cfg.entitlements(r.Context(), userIDFromToken)
+
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		respondWithError(w, r, problemInternal, "Couldn't retrieve subscription", err)
 		return
 	}
 
-	const maxChirpLength = 140
-	if len(params.Body) > maxChirpLength {
-		respondWithError(w, http.StatusBadRequest, "Chirp is too long", nil)
+	mayPost, err := cfg.mayPostChirps(r.Context(), userIDFromToken)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't check email verification", err)
 		return
 	}
 
-	splitMsg := strings.Split(params.Body, " ")
+	if !mayPost {
+		respondWithError(w, r, problemEmailUnverified, "Please verify your email address before posting", nil)
+		return
+	}
 
-	for idx, component := range splitMsg {
-		if containsBadWords(strings.ToLower(component)) {
-			splitMsg[idx] = "****"
-		}
+	if chirpLength(params.Body) > entitlements.MaxChirpLength {
+		respondWithFieldErrors(w, r, problemChirpTooLong, FieldError{
+			Field:  "body",
+			Code:   "too_long",
+			Detail: fmt.Sprintf("Chirp is longer than %d characters", entitlements.MaxChirpLength),
+		})
+		return
 	}
 
-	joinMsg := strings.Join(splitMsg, " ")
+	body, flagMatches, ok := cfg.moderateChirp(w, r, params.Body)
+	if !ok {
+		return
+	}
 
-	chirp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   joinMsg,
+	if params.PublishAt != nil {
+		cfg.scheduleChirp(w, r, userIDFromToken, entitlements, body, flagMatches, *params.PublishAt, params.InReplyTo)
+		return
+	}
+
+	createParams := database.CreateChirpParams{
+		Body:   body,
 		UserID: userIDFromToken,
-	})
+	}
+
+	// Replies keep a pointer to their parent and to the first chirp of the thread
+	if params.InReplyTo != "" {
+		parentUUID, err := uuid.Parse(params.InReplyTo)
+
+		if err != nil {
+			respondWithError(w, r, problemInvalidID, "in_reply_to is not in the correct format", err)
+			return
+		}
+
+		parent, err := cfg.db.GetChirp(r.Context(), parentUUID)
+
+		if err != nil || parent.DeletedAt.Valid {
+			respondWithError(w, r, problemNotFound, "Chirp to reply to not found", err)
+			return
+		}
+
+		createParams.ParentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		createParams.RootID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		if parent.RootID.Valid {
+			createParams.RootID = parent.RootID
+		}
+	}
+
+	chirp, err := cfg.db.CreateChirp(r.Context(), createParams)
 
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't create chirp", err)
+		respondWithError(w, r, problemInternal, "Couldn't create chirp", err)
 		return
 	}
 
-	jsonChirp := &Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+	err = flagChirp(r.Context(), cfg.db, chirp.ID, flagMatches)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't flag chirp for review", err)
+		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, jsonChirp)
+	cfg.metrics.chirpsCreated.Inc()
+	cfg.publishEvent(r.Context(), eventChirpCreated, []uuid.UUID{chirp.UserID}, chirpFromDB(chirp))
+
+	respondWithJSON(w, http.StatusCreated, chirpFromDB(chirp))
+}
+
+// moderateChirp runs body through the moderation filter. It returns the text
+// to store and, with the flag action, the words to flag the chirp for. When
+// the chirp is rejected it responds and returns ok false.
+func (cfg *apiConfig) moderateChirp(w http.ResponseWriter, r *http.Request, body string) (string, []string, bool) {
+	moderated := cfg.moderation.Check(body)
+
+	if len(moderated.Matches) == 0 {
+		return body, nil, true
+	}
+
+	switch cfg.moderation.Action() {
+	case moderation.ActionMask:
+		return moderated.Text, nil, true
+	case moderation.ActionReject:
+		respondWithError(w, r, problemChirpNotAllowed, "Chirp contains words that aren't allowed", nil)
+		return "", nil, false
+	}
+
+	return body, moderated.Matches, true
+}
+
+// flagChirp queues a chirp for review when moderation matched any words.
+func flagChirp(ctx context.Context, db database.Querier, chirpID uuid.UUID, matches []string) error {
+	if len(matches) == 0 {
+		return nil
+	}
+
+	return db.CreateChirpFlag(ctx, database.CreateChirpFlagParams{
+		ChirpID:      chirpID,
+		MatchedWords: strings.Join(matches, ","),
+	})
+}
+
+// authorFeedItems turns an author's feed, which mixes their chirps with the
+// ones they rechirped, into feed items.
+func authorFeedItems[T database.GetChirpsByAuthorAscRow | database.GetChirpsByAuthorDescRow](rows []T) []feedItem {
+	items := make([]feedItem, 0, len(rows))
+	for _, r := range rows {
+		row := database.GetChirpsByAuthorDescRow(r)
+		items = append(items, feedItem{
+			Chirp:       rowChirp(row.ID, row.CreatedAt, row.UpdatedAt, row.Body, row.UserID, row.ParentID, row.RootID, row.DeletedAt),
+			FeedAt:      row.FeedAt,
+			RechirpedBy: row.RechirpedBy,
+		})
+	}
+	return items
 }
 
 func (cfg *apiConfig) getChirps(w http.ResponseWriter, r *http.Request) {
 	// Check for author_id
 	author := r.URL.Query().Get("author_id")
 
-	// sort direction
-	sortDirection := "asc"
-	sortDirectionParam := r.URL.Query().Get("sort")
-	if sortDirectionParam == "desc" {
-		sortDirection = "desc"
+	page, err := parsePageParams(r, orderByTime)
+	if err != nil {
+		respondWithError(w, r, problemInvalidQuery, "Invalid pagination parameters", err)
+		return
 	}
 
 	if author != "" {
 		authorUUID, err := uuid.Parse(author)
 		if err != nil {
-			respondWithError(w, http.StatusBadRequest, "Author ID in the wrong format", err)
+			respondWithError(w, r, problemInvalidQuery, "Author ID in the wrong format", err)
 			return
 		}
 
-		chirps, err := cfg.db.GetChirpsByAuthor(r.Context(), authorUUID)
-
-		parseChirps(chirps, err, w, sortDirection)
+		if page.SortDirection == "desc" {
+			rows, err := cfg.db.GetChirpsByAuthorDesc(r.Context(), database.GetChirpsByAuthorDescParams{
+				UserID:          authorUUID,
+				CursorCreatedAt: page.cursorCreatedAt(),
+				CursorID:        page.cursorID(),
+				PageLimit:       page.queryLimit(),
+			})
+
+			cfg.respondWithFeed(w, r, authorFeedItems(rows), err, page)
+		} else {
+			rows, err := cfg.db.GetChirpsByAuthorAsc(r.Context(), database.GetChirpsByAuthorAscParams{
+				UserID:          authorUUID,
+				CursorCreatedAt: page.cursorCreatedAt(),
+				CursorID:        page.cursorID(),
+				PageLimit:       page.queryLimit(),
+			})
+
+			cfg.respondWithFeed(w, r, authorFeedItems(rows), err, page)
+		}
 	} else {
-		chirps, err := cfg.db.GetChirps(r.Context())
-
-		parseChirps(chirps, err, w, sortDirection)
+		if page.SortDirection == "desc" {
+			chirps, err := cfg.db.GetChirpsDesc(r.Context(), database.GetChirpsDescParams{
+				CursorCreatedAt: page.cursorCreatedAt(),
+				CursorID:        page.cursorID(),
+				PageLimit:       page.queryLimit(),
+			})
+
+			cfg.respondWithFeed(w, r, feedItemsFromChirps(chirps), err, page)
+		} else {
+			chirps, err := cfg.db.GetChirpsAsc(r.Context(), database.GetChirpsAscParams{
+				CursorCreatedAt: page.cursorCreatedAt(),
+				CursorID:        page.cursorID(),
+				PageLimit:       page.queryLimit(),
+			})
+
+			cfg.respondWithFeed(w, r, feedItemsFromChirps(chirps), err, page)
+		}
 	}
 }
 
-func parseChirps(chirps []database.Chirp, err error, w http.ResponseWriter, sortDirection string) {
+func (cfg *apiConfig) respondWithFeed(w http.ResponseWriter, r *http.Request, items []feedItem, err error, page pageParams) {
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't retrieve chirps", err)
+		respondWithError(w, r, problemInternal, "Couldn't retrieve chirps", err)
 		return
 	}
 
+	items, nextCursor := pageFeed(items, page)
+
 	jsonChirps := make([]Chirp, 0)
-	for _, chirp := range chirps {
-		t := &Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
+	for _, item := range items {
+		jsonChirp := chirpFromDB(item.Chirp)
+		if item.RechirpedBy.Valid {
+			jsonChirp.RechirpedBy = &item.RechirpedBy.UUID
 		}
+		jsonChirps = append(jsonChirps, jsonChirp)
+	}
 
-		jsonChirps = append(jsonChirps, *t)
+	err = cfg.addEngagement(r, jsonChirps)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve chirps", err)
+		return
 	}
 
-	sort.Slice(jsonChirps, func(i, j int) bool {
-		if sortDirection == "desc" {
-			return jsonChirps[i].CreatedAt.After(jsonChirps[j].CreatedAt)
-		}
-		return jsonChirps[i].CreatedAt.Before(jsonChirps[j].CreatedAt)
+	respondWithJSON(w, http.StatusOK, ChirpsPage{
+		Chirps:     jsonChirps,
+		NextCursor: nextCursor,
 	})
-
-	respondWithJSON(w, http.StatusOK, jsonChirps)
 }
 
 func (cfg *apiConfig) getChirp(w http.ResponseWriter, r *http.Request) {
 	chirpId := r.PathValue("chirpID")
 
 	if chirpId == "" {
-		respondWithError(w, http.StatusBadRequest, "Please provide a ChirpID", fmt.Errorf("ChirpID not provided"))
+		respondWithError(w, r, problemInvalidID, "Please provide a ChirpID", fmt.Errorf("ChirpID not provided"))
 		return
 	}
 
 	chirpUUID, err := uuid.Parse(chirpId)
 
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Chirp UUID is not in the correct format", err)
+		respondWithError(w, r, problemInvalidID, "Chirp UUID is not in the correct format", err)
 		return
 	}
 
 	chirp, err := cfg.db.GetChirp(r.Context(), chirpUUID)
 
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, r, problemNotFound, "Chirp not found", err)
+		return
+	}
+
+	// A lost view isn't worth failing the request over
+	err = cfg.db.IncrementChirpViews(r.Context(), chirpUUID)
 	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+		slog.WarnContext(r.Context(), "Couldn't count chirp view", "chirp_id", chirpUUID, "error", err)
+	}
+
+	jsonChirps := []Chirp{chirpFromDB(chirp)}
+	err = cfg.addEngagement(r, jsonChirps)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve chirp", err)
 		return
 	}
 
-	jsonChirp := &Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+	respondWithJSON(w, http.StatusOK, jsonChirps[0])
+}
+
+func (cfg *apiConfig) getThread(w http.ResponseWriter, r *http.Request) {
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, r, problemInvalidID, "Chirp UUID is not in the correct format", err)
+		return
 	}
 
-	respondWithJSON(w, http.StatusOK, jsonChirp)
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpUUID)
+
+	if err != nil {
+		respondWithError(w, r, problemNotFound, "Chirp not found", err)
+		return
+	}
+
+	rootID := chirp.ID
+	if chirp.RootID.Valid {
+		rootID = chirp.RootID.UUID
+	}
+
+	chirps, err := cfg.db.GetThread(r.Context(), rootID)
+
+	if err != nil || len(chirps) == 0 {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve thread", err)
+		return
+	}
+
+	jsonChirps := make([]Chirp, 0, len(chirps))
+	for _, c := range chirps {
+		jsonChirps = append(jsonChirps, chirpFromDB(c))
+	}
+
+	err = cfg.addEngagement(r, jsonChirps)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve thread", err)
+		return
+	}
+
+	// Chirps come back oldest first, so every parent is seen before its replies
+	// and each list of replies is already in chronological order.
+	nodes := make(map[uuid.UUID]*ThreadChirp, len(chirps))
+	var root *ThreadChirp
+	for i, c := range chirps {
+		node := &ThreadChirp{Chirp: jsonChirps[i], Replies: make([]*ThreadChirp, 0)}
+		nodes[c.ID] = node
+
+		if c.ID == rootID {
+			root = node
+			continue
+		}
+
+		parent, ok := nodes[c.ParentID.UUID]
+		if !c.ParentID.Valid || !ok {
+			// The parent is gone for good, hang the reply off the root
+			parent = root
+		}
+		if parent != nil {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+
+	if root == nil {
+		respondWithError(w, r, problemNotFound, "Thread not found", fmt.Errorf("root chirp %s not found", rootID))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, root)
 }
 
 func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
@@ -193,49 +433,180 @@ func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.GetBearerToken(r.Header)
 
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Please provide an auth token", err)
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.secret)
+	userID, err := cfg.keyring.ValidateJWT(token)
 
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
 		return
 	}
 
 	chirpId := r.PathValue("chirpID")
 
 	if chirpId == "" {
-		respondWithError(w, http.StatusBadRequest, "Please provide a ChirpID", fmt.Errorf("ChirpID not provided"))
+		respondWithError(w, r, problemInvalidID, "Please provide a ChirpID", fmt.Errorf("ChirpID not provided"))
 		return
 	}
 
 	chirpUUID, err := uuid.Parse(chirpId)
 
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Chirp UUID is not in the correct format", err)
+		respondWithError(w, r, problemInvalidID, "Chirp UUID is not in the correct format", err)
 		return
 	}
 
 	chirp, err := cfg.db.GetChirp(r.Context(), chirpUUID)
 
-	if err != nil {
-		respondWithError(w, http.StatusNotFound, "Chirp not found", err)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, r, problemNotFound, "Chirp not found", err)
 		return
 	}
 
 	if userID != chirp.UserID {
-		respondWithError(w, http.StatusForbidden, "You're not allowed to delete that", err)
+		respondWithError(w, r, problemForbidden, "You're not allowed to delete that", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context())
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't delete the chirp", err)
 		return
 	}
+	defer tx.Rollback()
 
-	err = cfg.db.DeleteChirp(r.Context(), chirpUUID)
+	// A chirp with replies is turned into a tombstone so the thread stays
+	// intact. Checking for replies in the DELETE itself means one posted
+	// in the meantime can't end up detached.
+	deleted, err := tx.DeleteChirpWithoutReplies(r.Context(), chirpUUID)
 
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't delete the chirp", err)
+		respondWithError(w, r, problemInternal, "Couldn't delete the chirp", err)
 		return
 	}
 
+	if deleted == 0 {
+		_, err = tx.TombstoneChirp(r.Context(), chirpUUID)
+
+		if err != nil {
+			respondWithError(w, r, problemInternal, "Couldn't delete the chirp", err)
+			return
+		}
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't delete the chirp", err)
+		return
+	}
+
+	cfg.publishEvent(r.Context(), eventChirpDeleted, []uuid.UUID{chirp.UserID}, struct {
+		ID     uuid.UUID `json:"id"`
+		UserID uuid.UUID `json:"user_id"`
+	}{chirp.ID, chirp.UserID})
+
 	respondWithNoContent(w)
 }
+
+func (cfg *apiConfig) editChirp(w http.ResponseWriter, r *http.Request) {
+	type message struct {
+		Body string `json:"body"`
+	}
+
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, r, problemInvalidID, "Chirp UUID is not in the correct format", err)
+		return
+	}
+
+	var chirp chirpRow
+	chirp, err = cfg.db.GetChirp(r.Context(), chirpUUID)
+
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, r, problemNotFound, "Chirp not found", err)
+		return
+	}
+
+	if userID != chirp.UserID {
+		respondWithError(w, r, problemForbidden, "You're not allowed to edit that", nil)
+		return
+	}
+
+	entitlements, err := cfg.entitlements(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve subscription", err)
+		return
+	}
+
+	if !entitlements.Can(FeatureEditChirps) {
+		respondWithError(w, r, problemRedRequired, "This feature requires Chirpy Red", nil)
+		return
+	}
+
+	params := message{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	if chirpLength(params.Body) > entitlements.MaxChirpLength {
+		respondWithFieldErrors(w, r, problemChirpTooLong, FieldError{
+			Field:  "body",
+			Code:   "too_long",
+			Detail: fmt.Sprintf("Chirp is longer than %d characters", entitlements.MaxChirpLength),
+		})
+		return
+	}
+
+	body, flagMatches, ok := cfg.moderateChirp(w, r, params.Body)
+	if !ok {
+		return
+	}
+
+	chirp, err = cfg.db.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{
+		ID:   chirpUUID,
+		Body: body,
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't update chirp", err)
+		return
+	}
+
+	err = flagChirp(r.Context(), cfg.db, chirp.ID, flagMatches)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't flag chirp for review", err)
+		return
+	}
+
+	jsonChirps := []Chirp{chirpFromDB(chirp)}
+	err = cfg.addEngagement(r, jsonChirps)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, jsonChirps[0])
+}
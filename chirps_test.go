@@ -0,0 +1,191 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateChirp(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	tests := []struct {
+		name     string
+		body     map[string]string
+		token    string
+		want     int
+		wantBody string
+	}{
+		{name: "valid", body: map[string]string{"body": "Say my name"}, token: user.Token, want: http.StatusCreated, wantBody: "Say my name"},
+		{name: "masked", body: map[string]string{"body": "What a Kerfuffle"}, token: user.Token, want: http.StatusCreated, wantBody: "What a ****"},
+		{name: "too long", body: map[string]string{"body": strings.Repeat("a", 141)}, token: user.Token, want: http.StatusBadRequest},
+		{name: "no token", body: map[string]string{"body": "Say my name"}, want: http.StatusUnauthorized},
+		{name: "bad token", body: map[string]string{"body": "Say my name"}, token: "nope", want: http.StatusUnauthorized},
+		{name: "unknown parent", body: map[string]string{"body": "Reply", "in_reply_to": uuid.NewString()}, token: user.Token, want: http.StatusNotFound},
+		{name: "malformed parent", body: map[string]string{"body": "Reply", "in_reply_to": "nope"}, token: user.Token, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authorization := ""
+			if tt.token != "" {
+				authorization = bearer(tt.token)
+			}
+
+			rec := ts.do("POST", "/api/chirps", tt.body, authorization)
+			expectStatus(t, rec, tt.want)
+
+			if tt.want == http.StatusCreated {
+				chirp := decodeBody[Chirp](t, rec)
+				if chirp.Body != tt.wantBody || chirp.UserID != user.ID {
+					t.Errorf("chirp = %+v, want body %q by %s", chirp, tt.wantBody, user.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestGetChirpsPagination(t *testing.T) {
+	ts := newTestServer(t)
+	walt := ts.signUp("walt@example.com")
+	jesse := ts.signUp("jesse@example.com")
+
+	created := []Chirp{
+		ts.createChirp(walt.Token, "one"),
+		ts.createChirp(jesse.Token, "two"),
+		ts.createChirp(walt.Token, "three"),
+	}
+
+	rec := ts.do("GET", "/api/chirps?limit=2", nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	page := decodeBody[ChirpsPage](t, rec)
+	if len(page.Chirps) != 2 || page.Chirps[0].ID != created[0].ID || page.NextCursor == "" {
+		t.Fatalf("first page = %+v", page)
+	}
+
+	rec = ts.do("GET", "/api/chirps?limit=2&cursor="+url.QueryEscape(page.NextCursor), nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	page = decodeBody[ChirpsPage](t, rec)
+	if len(page.Chirps) != 1 || page.Chirps[0].ID != created[2].ID || page.NextCursor != "" {
+		t.Fatalf("second page = %+v", page)
+	}
+
+	rec = ts.do("GET", "/api/chirps?sort=desc&author_id="+walt.ID.String(), nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	page = decodeBody[ChirpsPage](t, rec)
+	if len(page.Chirps) != 2 || page.Chirps[0].ID != created[2].ID || page.Chirps[1].ID != created[0].ID {
+		t.Errorf("walt's chirps = %+v", page.Chirps)
+	}
+
+	expectStatus(t, ts.do("GET", "/api/chirps?limit=0", nil, ""), http.StatusBadRequest)
+	expectStatus(t, ts.do("GET", "/api/chirps?cursor=nope", nil, ""), http.StatusBadRequest)
+	expectStatus(t, ts.do("GET", "/api/chirps?author_id=nope", nil, ""), http.StatusBadRequest)
+}
+
+func TestGetChirp(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+	chirp := ts.createChirp(user.Token, "Say my name")
+
+	rec := ts.do("GET", "/api/chirps/"+chirp.ID.String(), nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	if got := decodeBody[Chirp](t, rec); got.ID != chirp.ID || got.Body != chirp.Body {
+		t.Errorf("chirp = %+v, want %+v", got, chirp)
+	}
+
+	expectStatus(t, ts.do("GET", "/api/chirps/"+uuid.NewString(), nil, ""), http.StatusNotFound)
+	expectStatus(t, ts.do("GET", "/api/chirps/nope", nil, ""), http.StatusBadRequest)
+}
+
+func TestThread(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+	root := ts.createChirp(user.Token, "root")
+
+	rec := ts.do("POST", "/api/chirps", map[string]string{"body": "reply", "in_reply_to": root.ID.String()}, bearer(user.Token))
+	expectStatus(t, rec, http.StatusCreated)
+	reply := decodeBody[Chirp](t, rec)
+
+	rec = ts.do("POST", "/api/chirps", map[string]string{"body": "nested", "in_reply_to": reply.ID.String()}, bearer(user.Token))
+	expectStatus(t, rec, http.StatusCreated)
+	if nested := decodeBody[Chirp](t, rec); nested.RootID == nil || *nested.RootID != root.ID {
+		t.Errorf("nested reply root = %v, want %s", nested.RootID, root.ID)
+	}
+
+	// Asking for any chirp of the thread returns the whole tree
+	rec = ts.do("GET", "/api/chirps/"+reply.ID.String()+"/thread", nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	thread := decodeBody[ThreadChirp](t, rec)
+	if thread.ID != root.ID || len(thread.Replies) != 1 || len(thread.Replies[0].Replies) != 1 {
+		t.Errorf("thread = %s", rec.Body.String())
+	}
+
+	expectStatus(t, ts.do("GET", "/api/chirps/"+uuid.NewString()+"/thread", nil, ""), http.StatusNotFound)
+}
+
+func TestDeleteChirp(t *testing.T) {
+	ts := newTestServer(t)
+	walt := ts.signUp("walt@example.com")
+	jesse := ts.signUp("jesse@example.com")
+	chirp := ts.createChirp(walt.Token, "Say my name")
+
+	expectStatus(t, ts.do("DELETE", "/api/chirps/"+chirp.ID.String(), nil, ""), http.StatusUnauthorized)
+	expectStatus(t, ts.do("DELETE", "/api/chirps/"+chirp.ID.String(), nil, bearer(jesse.Token)), http.StatusForbidden)
+	expectStatus(t, ts.do("DELETE", "/api/chirps/"+chirp.ID.String(), nil, bearer(walt.Token)), http.StatusNoContent)
+	expectStatus(t, ts.do("GET", "/api/chirps/"+chirp.ID.String(), nil, ""), http.StatusNotFound)
+	expectStatus(t, ts.do("DELETE", "/api/chirps/"+chirp.ID.String(), nil, bearer(walt.Token)), http.StatusNotFound)
+}
+
+func TestDeleteChirpWithRepliesLeavesTombstone(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+	root := ts.createChirp(user.Token, "root")
+
+	rec := ts.do("POST", "/api/chirps", map[string]string{"body": "reply", "in_reply_to": root.ID.String()}, bearer(user.Token))
+	expectStatus(t, rec, http.StatusCreated)
+
+	expectStatus(t, ts.do("DELETE", "/api/chirps/"+root.ID.String(), nil, bearer(user.Token)), http.StatusNoContent)
+
+	rec = ts.do("GET", "/api/chirps/"+root.ID.String()+"/thread", nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	if thread := decodeBody[ThreadChirp](t, rec); !thread.Deleted || thread.Body != "" || len(thread.Replies) != 1 {
+		t.Errorf("thread after deleting the root = %s", rec.Body.String())
+	}
+}
+
+func TestEditChirp(t *testing.T) {
+	ts := newTestServer(t)
+	walt := ts.signUp("walt@example.com")
+	jesse := ts.signUp("jesse@example.com")
+	chirp := ts.createChirp(walt.Token, "Say my name")
+	edit := map[string]string{"body": "Say my name, please"}
+
+	expectStatus(t, ts.do("PUT", "/api/chirps/"+chirp.ID.String(), edit, bearer(walt.Token)), http.StatusForbidden)
+
+	ts.upgrade(walt.ID)
+	ts.upgrade(jesse.ID)
+
+	expectStatus(t, ts.do("PUT", "/api/chirps/"+chirp.ID.String(), edit, bearer(jesse.Token)), http.StatusForbidden)
+	expectStatus(t, ts.do("PUT", "/api/chirps/"+uuid.NewString(), edit, bearer(walt.Token)), http.StatusNotFound)
+
+	rec := ts.do("PUT", "/api/chirps/"+chirp.ID.String(), edit, bearer(walt.Token))
+	expectStatus(t, rec, http.StatusOK)
+	if edited := decodeBody[Chirp](t, rec); edited.Body != edit["body"] {
+		t.Errorf("body = %q, want %q", edited.Body, edit["body"])
+	}
+}
+
+func TestChirpyRedLongerChirps(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+	long := map[string]string{"body": strings.Repeat("a", 300)}
+
+	expectStatus(t, ts.do("POST", "/api/chirps", long, bearer(user.Token)), http.StatusBadRequest)
+
+	ts.upgrade(user.ID)
+	expectStatus(t, ts.do("POST", "/api/chirps", long, bearer(user.Token)), http.StatusCreated)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// serverConfig is how the HTTP server and the database pool are tuned. Every
+// setting has a default so nothing has to be set for local development.
+type serverConfig struct {
+	ListenAddr        string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownTimeout is how long in-flight requests get to finish after
+	// SIGTERM or SIGINT.
+	ShutdownTimeout time.Duration
+	// DrainDelay keeps serving, with healthz failing, before shutting down
+	// so load balancers stop sending traffic first.
+	DrainDelay time.Duration
+
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+}
+
+func loadServerConfig() (serverConfig, error) {
+	var err error
+	cfg := serverConfig{
+		ListenAddr: os.Getenv("LISTEN_ADDR"),
+	}
+	if cfg.ListenAddr == "" {
+		cfg.ListenAddr = ":8080"
+	}
+
+	durations := []struct {
+		name  string
+		value *time.Duration
+		def   time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", &cfg.ReadTimeout, 15 * time.Second},
+		{"HTTP_READ_HEADER_TIMEOUT", &cfg.ReadHeaderTimeout, 5 * time.Second},
+		{"HTTP_WRITE_TIMEOUT", &cfg.WriteTimeout, 30 * time.Second},
+		{"HTTP_IDLE_TIMEOUT", &cfg.IdleTimeout, 2 * time.Minute},
+		{"SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout, 20 * time.Second},
+		{"SHUTDOWN_DRAIN_DELAY", &cfg.DrainDelay, 0},
+		{"DB_CONN_MAX_LIFETIME", &cfg.DBConnMaxLifetime, 30 * time.Minute},
+		{"DB_CONN_MAX_IDLE_TIME", &cfg.DBConnMaxIdleTime, 5 * time.Minute},
+	}
+	for _, d := range durations {
+		*d.value, err = envDuration(d.name, d.def)
+		if err != nil {
+			return serverConfig{}, err
+		}
+	}
+
+	ints := []struct {
+		name  string
+		value *int
+		def   int
+	}{
+		{"DB_MAX_OPEN_CONNS", &cfg.DBMaxOpenConns, 25},
+		{"DB_MAX_IDLE_CONNS", &cfg.DBMaxIdleConns, 25},
+	}
+	for _, i := range ints {
+		*i.value, err = envInt(i.name, i.def)
+		if err != nil {
+			return serverConfig{}, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// envDuration reads a duration like "30s" from the environment.
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s must be a duration like 30s, got %q", name, value)
+	}
+	return d, nil
+}
+
+func envInt(name string, def int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("%s must be a whole number, got %q", name, value)
+	}
+	return i, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadServerConfig(t *testing.T) {
+	// Empty is the same as unset
+	for _, name := range []string{"LISTEN_ADDR", "SHUTDOWN_TIMEOUT", "SHUTDOWN_DRAIN_DELAY", "DB_MAX_OPEN_CONNS"} {
+		t.Setenv(name, "")
+	}
+
+	cfg, err := loadServerConfig()
+	if err != nil {
+		t.Fatalf("loadServerConfig() error = %v", err)
+	}
+	if cfg.ListenAddr != ":8080" || cfg.ShutdownTimeout != 20*time.Second || cfg.DrainDelay != 0 || cfg.DBMaxOpenConns != 25 {
+		t.Errorf("loadServerConfig() defaults = %+v", cfg)
+	}
+
+	t.Setenv("LISTEN_ADDR", "127.0.0.1:9000")
+	t.Setenv("SHUTDOWN_TIMEOUT", "45s")
+	t.Setenv("SHUTDOWN_DRAIN_DELAY", "5s")
+	t.Setenv("DB_MAX_OPEN_CONNS", "10")
+
+	cfg, err = loadServerConfig()
+	if err != nil {
+		t.Fatalf("loadServerConfig() error = %v", err)
+	}
+	if cfg.ListenAddr != "127.0.0.1:9000" || cfg.ShutdownTimeout != 45*time.Second || cfg.DrainDelay != 5*time.Second || cfg.DBMaxOpenConns != 10 {
+		t.Errorf("loadServerConfig() = %+v", cfg)
+	}
+}
+
+func TestLoadServerConfigInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"HTTP_READ_TIMEOUT", "15"},
+		{"HTTP_WRITE_TIMEOUT", "-1s"},
+		{"DB_MAX_IDLE_CONNS", "lots"},
+		{"DB_MAX_OPEN_CONNS", "-5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.name, tt.value)
+
+			if _, err := loadServerConfig(); err == nil {
+				t.Errorf("loadServerConfig() with %s=%q succeeded", tt.name, tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/auth"
+	"github.com/tracevt/chirpy/internal/database"
+	"github.com/tracevt/chirpy/internal/mail"
+	"github.com/tracevt/chirpy/internal/store"
+)
+
+const emailVerificationExpiration = 48 * time.Hour
+
+// sendEmailVerification emails a link that makes email the user's verified
+// address: their own on signup, or the one they're changing to.
+func (cfg *apiConfig) sendEmailVerification(ctx context.Context, userID uuid.UUID, email string) error {
+	token, tokenHash := auth.MakeSingleUseToken()
+
+	err := cfg.db.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: tokenHash,
+		UserID:    userID,
+		Email:     email,
+		ExpiresAt: time.Now().UTC().Add(emailVerificationExpiration),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := cfg.publicURL + "/api/email/verify?" + url.Values{"token": {token}}.Encode()
+
+	return cfg.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf("Follow this link within two days to confirm %s is your Chirpy email address:\n\n"+
+			"%s\n\nIf you don't have a Chirpy account, you can ignore this email.\n", email, link),
+	})
+}
+
+// verifyEmail is where the emailed link leads.
+func (cfg *apiConfig) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	if token == "" {
+		respondWithError(w, r, problemInvalidQuery, "Please provide a token", nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context())
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't verify email", err)
+		return
+	}
+	defer tx.Rollback()
+
+	verification, err := tx.UseEmailVerificationToken(r.Context(), auth.HashSingleUseToken(token))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, problemInvalidToken, "Verification token is invalid or has expired", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't verify email", err)
+		return
+	}
+
+	// Someone else may have taken the address since the link was sent
+	owner, err := tx.GetUserByEmail(r.Context(), verification.Email)
+
+	if err == nil && owner.ID != verification.UserID {
+		respondWithError(w, r, problemConflict, "Email is already in use", nil)
+		return
+	}
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, problemInternal, "Couldn't check the email address", err)
+		return
+	}
+
+	user, err := tx.VerifyUserEmail(r.Context(), database.VerifyUserEmailParams{
+		ID:    verification.UserID,
+		Email: verification.Email,
+	})
+
+	if store.IsUniqueViolation(err) {
+		respondWithError(w, r, problemConflict, "Email is already in use", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't verify email", err)
+		return
+	}
+
+	// Links sent before this one, for this or another address, are no good
+	// anymore
+	err = tx.DeleteEmailVerificationTokens(r.Context(), user.ID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't verify email", err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't verify email", err)
+		return
+	}
+
+	isChirpyRed, err := cfg.isChirpyRed(r.Context(), user.ID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve subscription", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, User{
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		IsChirpyRed:   isChirpyRed,
+	})
+}
+
+// resendEmailVerification sends a new link to a user who hasn't verified
+// their address yet.
+func (cfg *apiConfig) resendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemNotFound, "User not found", err)
+		return
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		respondWithError(w, r, problemConflict, "Email is already verified", nil)
+		return
+	}
+
+	err = cfg.sendEmailVerification(r.Context(), user.ID, user.Email)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't send the verification email", err)
+		return
+	}
+
+	respondWithStatusCode(w, http.StatusAccepted)
+}
+
+// mayPostChirps reports whether the user can post, which takes a verified
+// email address unless the admins allow unverified users to.
+func (cfg *apiConfig) mayPostChirps(ctx context.Context, userID uuid.UUID) (bool, error) {
+	settings, err := cfg.db.GetSiteSettings(ctx)
+	if err != nil {
+		return false, err
+	}
+	if settings.AllowUnverifiedChirps {
+		return true, nil
+	}
+
+	user, err := cfg.db.GetUser(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return user.EmailVerifiedAt.Valid, nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tracevt/chirpy/internal/database"
+	"github.com/tracevt/chirpy/internal/store"
+)
+
+// verificationLink returns the path of the last verification link emailed
+// to the address.
+func (ts *testServer) verificationLink(to string) string {
+	ts.t.Helper()
+
+	messages := ts.mail.Messages()
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].To != to {
+			continue
+		}
+		for _, line := range strings.Split(messages[i].Body, "\n") {
+			if !strings.HasPrefix(line, ts.cfg.publicURL+"/api/email/verify?") {
+				continue
+			}
+			link, err := url.Parse(line)
+			if err != nil {
+				ts.t.Fatalf("url.Parse(%q) error = %v", line, err)
+			}
+			return link.RequestURI()
+		}
+	}
+	ts.t.Fatalf("no verification link sent to %s in %+v", to, messages)
+	return ""
+}
+
+func TestEmailVerification(t *testing.T) {
+	ts := newTestServer(t)
+
+	rec := ts.do("POST", "/api/users", UserCredentials{Email: "walt@example.com", Password: "hunter2"}, "")
+	expectStatus(t, rec, http.StatusCreated)
+	if user := decodeBody[User](t, rec); user.EmailVerified {
+		t.Errorf("new user = %+v, want unverified", user)
+	}
+
+	user := ts.login("walt@example.com", "hunter2")
+	if user.EmailVerified {
+		t.Errorf("logged in user = %+v, want unverified", user)
+	}
+
+	first := ts.verificationLink("walt@example.com")
+	expectStatus(t, ts.do("POST", "/api/email/verify", nil, ""), http.StatusUnauthorized)
+	expectStatus(t, ts.do("POST", "/api/email/verify", nil, bearer(user.Token)), http.StatusAccepted)
+	link := ts.verificationLink("walt@example.com")
+	if link == first {
+		t.Fatalf("resent link = %q, want a new one", link)
+	}
+
+	expectProblem(t, ts.do("GET", "/api/email/verify", nil, ""), problemInvalidQuery)
+	expectProblem(t, ts.do("GET", "/api/email/verify?token=wrong", nil, ""), problemInvalidToken)
+
+	rec = ts.do("GET", link, nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	if verified := decodeBody[User](t, rec); !verified.EmailVerified || verified.Email != "walt@example.com" {
+		t.Errorf("verified user = %+v", verified)
+	}
+
+	// Links are single use, and verifying makes the earlier ones stale
+	expectProblem(t, ts.do("GET", link, nil, ""), problemInvalidToken)
+	expectProblem(t, ts.do("GET", first, nil, ""), problemInvalidToken)
+	expectProblem(t, ts.do("POST", "/api/email/verify", nil, bearer(user.Token)), problemConflict)
+
+	if user := ts.login("walt@example.com", "hunter2"); !user.EmailVerified {
+		t.Errorf("logged in user = %+v, want verified", user)
+	}
+}
+
+// expiryRecorder keeps the expiry of the last email verification token
+// created, which the API never shows.
+type expiryRecorder struct {
+	store.Store
+	expiresAt time.Time
+}
+
+func (s *expiryRecorder) CreateEmailVerificationToken(ctx context.Context, arg database.CreateEmailVerificationTokenParams) error {
+	s.expiresAt = arg.ExpiresAt
+	return s.Store.CreateEmailVerificationToken(ctx, arg)
+}
+
+func TestEmailVerificationWestOfUTC(t *testing.T) {
+	westOfUTC(t)
+
+	ts := newTestServer(t)
+	recorder := &expiryRecorder{Store: ts.cfg.db}
+	ts.cfg.db = recorder
+
+	expectStatus(t, ts.do("POST", "/api/users", UserCredentials{Email: "walt@example.com", Password: "hunter2"}, ""), http.StatusCreated)
+
+	// A local time would be stored as its wall clock, hours off from NOW()
+	if recorder.expiresAt.Location() != time.UTC {
+		t.Errorf("expires_at = %v, want it in UTC", recorder.expiresAt)
+	}
+
+	rec := ts.do("GET", ts.verificationLink("walt@example.com"), nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	if verified := decodeBody[User](t, rec); !verified.EmailVerified {
+		t.Errorf("verified user = %+v", verified)
+	}
+}
+
+func TestEmailChange(t *testing.T) {
+	ts := newTestServer(t)
+	walt := ts.signUp("walt@example.com")
+	ts.signUp("jesse@example.com")
+	ts.do("GET", ts.verificationLink("walt@example.com"), nil, "")
+
+	expectProblem(t, ts.do("PUT", "/api/users", UserCredentials{Email: "jesse@example.com", Password: "hunter2"}, bearer(walt.Token)), problemConflict)
+
+	expectStatus(t, ts.do("PUT", "/api/users", UserCredentials{Email: "heisenberg@example.com", Password: "hunter2"}, bearer(walt.Token)), http.StatusOK)
+	link := ts.verificationLink("heisenberg@example.com")
+
+	// Nothing changes until the new address is confirmed
+	ts.login("walt@example.com", "hunter2")
+
+	// Someone else signing up with the address first wins it
+	ts.signUp("heisenberg@example.com")
+	expectProblem(t, ts.do("GET", link, nil, ""), problemConflict)
+
+	expectStatus(t, ts.do("PUT", "/api/users", UserCredentials{Email: "blue@example.com", Password: "hunter2"}, bearer(walt.Token)), http.StatusOK)
+	rec := ts.do("GET", ts.verificationLink("blue@example.com"), nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	if changed := decodeBody[User](t, rec); changed.Email != "blue@example.com" || !changed.EmailVerified {
+		t.Errorf("changed user = %+v", changed)
+	}
+	ts.login("blue@example.com", "hunter2")
+}
+
+func TestUnverifiedChirps(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	expectProblem(t, ts.do("GET", "/admin/settings", nil, bearer(user.Token)), problemAdminRequired)
+	expectProblem(t, ts.do("PUT", "/admin/settings", map[string]bool{"allow_unverified_chirps": false}, bearer(user.Token)), problemAdminRequired)
+
+	rec := ts.do("GET", "/admin/settings", nil, adminKey())
+	expectStatus(t, rec, http.StatusOK)
+	if settings := decodeBody[SiteSettings](t, rec); !settings.AllowUnverifiedChirps {
+		t.Errorf("default settings = %+v, want unverified chirps allowed", settings)
+	}
+	ts.createChirp(user.Token, "Say my name")
+
+	expectProblem(t, ts.do("PUT", "/admin/settings", map[string]string{}, adminKey()), problemValidation)
+	rec = ts.do("PUT", "/admin/settings", map[string]bool{"allow_unverified_chirps": false}, adminKey())
+	expectStatus(t, rec, http.StatusOK)
+	if settings := decodeBody[SiteSettings](t, rec); settings.AllowUnverifiedChirps {
+		t.Errorf("updated settings = %+v", settings)
+	}
+
+	expectProblem(t, ts.do("POST", "/api/chirps", map[string]string{"body": "Say my name"}, bearer(user.Token)), problemEmailUnverified)
+
+	expectStatus(t, ts.do("GET", ts.verificationLink("walt@example.com"), nil, ""), http.StatusOK)
+	ts.createChirp(user.Token, "Say my name")
+}
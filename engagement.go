@@ -0,0 +1,178 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/auth"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+// viewerID returns the caller of an endpoint that works with or without a
+// token. A missing or invalid token is treated as an anonymous caller.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.NullUUID {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
+
+// addEngagement fills in like and rechirp counts for the given chirps and,
+// for authenticated callers, whether they liked or rechirped each of them.
+func (cfg *apiConfig) addEngagement(r *http.Request, chirps []Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	viewer := cfg.viewerID(r)
+
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+
+	rows, err := cfg.db.GetChirpEngagement(r.Context(), database.GetChirpEngagementParams{
+		ViewerID: viewer,
+		ChirpIds: chirpIDs,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	engagement := make(map[uuid.UUID]database.GetChirpEngagementRow, len(rows))
+	for _, row := range rows {
+		engagement[row.ID] = row
+	}
+
+	for i := range chirps {
+		row := engagement[chirps[i].ID]
+		chirps[i].LikeCount = row.LikeCount
+		chirps[i].RechirpCount = row.RechirpCount
+
+		if viewer.Valid {
+			chirps[i].LikedByMe = &row.LikedByMe
+			chirps[i].RechirpedByMe = &row.RechirpedByMe
+		}
+	}
+
+	return nil
+}
+
+// engagementTarget authenticates the caller and loads the chirp from the URL
+// for the like and rechirp endpoints. It writes the error response itself.
+func (cfg *apiConfig) engagementTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, chirpRow, bool) {
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return uuid.Nil, chirpRow{}, false
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return uuid.Nil, chirpRow{}, false
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, r, problemInvalidID, "Chirp UUID is not in the correct format", err)
+		return uuid.Nil, chirpRow{}, false
+	}
+
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpUUID)
+
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, r, problemNotFound, "Chirp not found", err)
+		return uuid.Nil, chirpRow{}, false
+	}
+
+	return userID, chirp, true
+}
+
+func (cfg *apiConfig) likeChirp(w http.ResponseWriter, r *http.Request) {
+	userID, chirp, ok := cfg.engagementTarget(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.CreateChirpLike(r.Context(), database.CreateChirpLikeParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't like the chirp", err)
+		return
+	}
+
+	respondWithNoContent(w)
+}
+
+func (cfg *apiConfig) unlikeChirp(w http.ResponseWriter, r *http.Request) {
+	userID, chirp, ok := cfg.engagementTarget(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.DeleteChirpLike(r.Context(), database.DeleteChirpLikeParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't unlike the chirp", err)
+		return
+	}
+
+	respondWithNoContent(w)
+}
+
+func (cfg *apiConfig) rechirp(w http.ResponseWriter, r *http.Request) {
+	userID, chirp, ok := cfg.engagementTarget(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.CreateRechirp(r.Context(), database.CreateRechirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't rechirp the chirp", err)
+		return
+	}
+
+	respondWithNoContent(w)
+}
+
+func (cfg *apiConfig) undoRechirp(w http.ResponseWriter, r *http.Request) {
+	userID, chirp, ok := cfg.engagementTarget(w, r)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't undo the rechirp", err)
+		return
+	}
+
+	respondWithNoContent(w)
+}
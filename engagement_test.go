@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestLikesAndRechirps(t *testing.T) {
+	ts := newTestServer(t)
+	walt := ts.signUp("walt@example.com")
+	jesse := ts.signUp("jesse@example.com")
+	chirp := ts.createChirp(walt.Token, "Say my name")
+	path := "/api/chirps/" + chirp.ID.String()
+
+	expectStatus(t, ts.do("PUT", path+"/like", nil, ""), http.StatusUnauthorized)
+	expectStatus(t, ts.do("PUT", "/api/chirps/"+uuid.NewString()+"/like", nil, bearer(jesse.Token)), http.StatusNotFound)
+
+	// Liking and rechirping are idempotent
+	for i := 0; i < 2; i++ {
+		expectStatus(t, ts.do("PUT", path+"/like", nil, bearer(jesse.Token)), http.StatusNoContent)
+		expectStatus(t, ts.do("PUT", path+"/rechirp", nil, bearer(jesse.Token)), http.StatusNoContent)
+	}
+
+	rec := ts.do("GET", path, nil, bearer(jesse.Token))
+	expectStatus(t, rec, http.StatusOK)
+	got := decodeBody[Chirp](t, rec)
+	if got.LikeCount != 1 || got.RechirpCount != 1 || got.LikedByMe == nil || !*got.LikedByMe || got.RechirpedByMe == nil || !*got.RechirpedByMe {
+		t.Errorf("chirp as seen by jesse = %s", rec.Body.String())
+	}
+
+	// A rechirp shows up in the rechirper's feed
+	rec = ts.do("GET", "/api/chirps?author_id="+jesse.ID.String(), nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	feed := decodeBody[ChirpsPage](t, rec).Chirps
+	if len(feed) != 1 || feed[0].ID != chirp.ID || feed[0].RechirpedBy == nil || *feed[0].RechirpedBy != jesse.ID {
+		t.Errorf("jesse's feed = %s", rec.Body.String())
+	}
+
+	expectStatus(t, ts.do("DELETE", path+"/like", nil, bearer(jesse.Token)), http.StatusNoContent)
+	expectStatus(t, ts.do("DELETE", path+"/rechirp", nil, bearer(jesse.Token)), http.StatusNoContent)
+
+	rec = ts.do("GET", path, nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	got = decodeBody[Chirp](t, rec)
+	if got.LikeCount != 0 || got.RechirpCount != 0 || got.LikedByMe != nil {
+		t.Errorf("chirp after undoing = %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Feature is a premium feature a plan may include.
+type Feature string
+
+const (
+	FeatureEditChirps     Feature = "edit_chirps"
+	FeatureScheduledPosts Feature = "scheduled_posts"
+	FeatureChirpAnalytics Feature = "chirp_analytics"
+)
+
+// Entitlements is what a user's plan allows. Handlers ask it instead of
+// checking for Chirpy Red themselves, so plans only change here.
+type Entitlements struct {
+	MaxChirpLength int
+	features       map[Feature]bool
+}
+
+var (
+	freeEntitlements = Entitlements{
+		MaxChirpLength: 140,
+	}
+	chirpyRedEntitlements = Entitlements{
+		MaxChirpLength: 500,
+		features: map[Feature]bool{
+			FeatureEditChirps:     true,
+			FeatureScheduledPosts: true,
+			FeatureChirpAnalytics: true,
+		},
+	}
+)
+
+func (e Entitlements) Can(feature Feature) bool {
+	return e.features[feature]
+}
+
+func (cfg *apiConfig) entitlements(ctx context.Context, userID uuid.UUID) (Entitlements, error) {
+	isChirpyRed, err := cfg.isChirpyRed(ctx, userID)
+	if err != nil {
+		return Entitlements{}, err
+	}
+
+	if isChirpyRed {
+		return chirpyRedEntitlements, nil
+	}
+	return freeEntitlements, nil
+}
+
+// requireFeature responds with 403 when the user's plan doesn't include
+// feature. It reports whether the handler may go on.
+func (cfg *apiConfig) requireFeature(w http.ResponseWriter, r *http.Request, userID uuid.UUID, feature Feature) bool {
+	entitlements, err := cfg.entitlements(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve subscription", err)
+		return false
+	}
+
+	if !entitlements.Can(feature) {
+		respondWithError(w, r, problemRedRequired, "This feature requires Chirpy Red", nil)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestEntitlements(t *testing.T) {
+	features := []Feature{FeatureEditChirps, FeatureScheduledPosts, FeatureChirpAnalytics}
+
+	for _, feature := range features {
+		if freeEntitlements.Can(feature) {
+			t.Errorf("the free plan includes %s", feature)
+		}
+		if !chirpyRedEntitlements.Can(feature) {
+			t.Errorf("Chirpy Red doesn't include %s", feature)
+		}
+	}
+
+	if freeEntitlements.MaxChirpLength >= chirpyRedEntitlements.MaxChirpLength {
+		t.Errorf("Chirpy Red allows %d characters, no more than the free plan's %d", chirpyRedEntitlements.MaxChirpLength, freeEntitlements.MaxChirpLength)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/auth"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+type FollowedUser struct {
+	ID         uuid.UUID `json:"id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type FollowedUsersPage struct {
+	Users      []FollowedUser `json:"users"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// pageFollowedUsers trims the extra row fetched by queryLimit and builds the
+// cursor for the following page, the way pageFeed does for chirps.
+func pageFollowedUsers(users []FollowedUser, page pageParams) FollowedUsersPage {
+	if len(users) <= int(page.Limit) {
+		return FollowedUsersPage{Users: users}
+	}
+
+	users = users[:page.Limit]
+	last := users[len(users)-1]
+
+	return FollowedUsersPage{
+		Users:      users,
+		NextCursor: encodeCursor(chirpCursor{CreatedAt: last.FollowedAt, ID: last.ID}),
+	}
+}
+
+func (cfg *apiConfig) followUser(w http.ResponseWriter, r *http.Request) {
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return
+	}
+
+	followerID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		respondWithError(w, r, problemInvalidID, "User UUID is not in the correct format", err)
+		return
+	}
+
+	if followerID == followeeID {
+		respondWithError(w, r, problemSelfFollow, "You can't follow yourself", fmt.Errorf("user %s tried to follow themselves", followerID))
+		return
+	}
+
+	_, err = cfg.db.GetUser(r.Context(), followeeID)
+
+	if err != nil {
+		respondWithError(w, r, problemNotFound, "User not found", err)
+		return
+	}
+
+	created, err := cfg.db.CreateFollow(r.Context(), database.CreateFollowParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't follow user", err)
+		return
+	}
+
+	// Following someone twice isn't news
+	if created > 0 {
+		cfg.publishEvent(r.Context(), eventUserFollowed, []uuid.UUID{followerID, followeeID}, struct {
+			FollowerID uuid.UUID `json:"follower_id"`
+			FolloweeID uuid.UUID `json:"followee_id"`
+		}{followerID, followeeID})
+	}
+
+	respondWithNoContent(w)
+}
+
+func (cfg *apiConfig) unfollowUser(w http.ResponseWriter, r *http.Request) {
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return
+	}
+
+	followerID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		respondWithError(w, r, problemInvalidID, "User UUID is not in the correct format", err)
+		return
+	}
+
+	err = cfg.db.DeleteFollow(r.Context(), database.DeleteFollowParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't unfollow user", err)
+		return
+	}
+
+	respondWithNoContent(w)
+}
+
+// getFollowers lists who follows a user, newest follow first. The list is
+// public, so it only has IDs.
+func (cfg *apiConfig) getFollowers(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		respondWithError(w, r, problemInvalidID, "User UUID is not in the correct format", err)
+		return
+	}
+
+	page, err := parsePageParams(r, orderByTime)
+	if err != nil {
+		respondWithError(w, r, problemInvalidQuery, "Invalid pagination parameters", err)
+		return
+	}
+
+	followers, err := cfg.db.GetFollowers(r.Context(), database.GetFollowersParams{
+		FolloweeID:      userID,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		PageLimit:       page.queryLimit(),
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve followers", err)
+		return
+	}
+
+	jsonUsers := make([]FollowedUser, 0)
+	for _, follower := range followers {
+		jsonUsers = append(jsonUsers, FollowedUser{
+			ID:         follower.ID,
+			FollowedAt: follower.FollowedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, pageFollowedUsers(jsonUsers, page))
+}
+
+// getFollowing lists who a user follows, newest follow first.
+func (cfg *apiConfig) getFollowing(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+
+	if err != nil {
+		respondWithError(w, r, problemInvalidID, "User UUID is not in the correct format", err)
+		return
+	}
+
+	page, err := parsePageParams(r, orderByTime)
+	if err != nil {
+		respondWithError(w, r, problemInvalidQuery, "Invalid pagination parameters", err)
+		return
+	}
+
+	following, err := cfg.db.GetFollowing(r.Context(), database.GetFollowingParams{
+		FollowerID:      userID,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		PageLimit:       page.queryLimit(),
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve followed users", err)
+		return
+	}
+
+	jsonUsers := make([]FollowedUser, 0)
+	for _, followee := range following {
+		jsonUsers = append(jsonUsers, FollowedUser{
+			ID:         followee.ID,
+			FollowedAt: followee.FollowedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, pageFollowedUsers(jsonUsers, page))
+}
+
+func (cfg *apiConfig) getTimeline(w http.ResponseWriter, r *http.Request) {
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return
+	}
+
+	page, err := parsePageParams(r, orderByTime)
+	if err != nil {
+		respondWithError(w, r, problemInvalidQuery, "Invalid pagination parameters", err)
+		return
+	}
+
+	if page.SortDirection == "desc" {
+		chirps, err := cfg.db.GetTimelineDesc(r.Context(), database.GetTimelineDescParams{
+			FollowerID:      userID,
+			CursorCreatedAt: page.cursorCreatedAt(),
+			CursorID:        page.cursorID(),
+			PageLimit:       page.queryLimit(),
+		})
+
+		cfg.respondWithFeed(w, r, feedItemsFromChirps(chirps), err, page)
+	} else {
+		chirps, err := cfg.db.GetTimelineAsc(r.Context(), database.GetTimelineAscParams{
+			FollowerID:      userID,
+			CursorCreatedAt: page.cursorCreatedAt(),
+			CursorID:        page.cursorID(),
+			PageLimit:       page.queryLimit(),
+		})
+
+		cfg.respondWithFeed(w, r, feedItemsFromChirps(chirps), err, page)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFollows(t *testing.T) {
+	ts := newTestServer(t)
+	walt := ts.signUp("walt@example.com")
+	jesse := ts.signUp("jesse@example.com")
+	waltPath := "/api/users/" + walt.ID.String()
+
+	expectStatus(t, ts.do("POST", waltPath+"/follow", nil, ""), http.StatusUnauthorized)
+	expectStatus(t, ts.do("POST", waltPath+"/follow", nil, bearer(walt.Token)), http.StatusBadRequest)
+	expectStatus(t, ts.do("POST", "/api/users/"+uuid.NewString()+"/follow", nil, bearer(jesse.Token)), http.StatusNotFound)
+	expectStatus(t, ts.do("POST", waltPath+"/follow", nil, bearer(jesse.Token)), http.StatusNoContent)
+	expectStatus(t, ts.do("POST", waltPath+"/follow", nil, bearer(jesse.Token)), http.StatusNoContent)
+
+	rec := ts.do("GET", waltPath+"/followers", nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	if followers := decodeBody[FollowedUsersPage](t, rec).Users; len(followers) != 1 || followers[0].ID != jesse.ID {
+		t.Errorf("walt's followers = %s", rec.Body.String())
+	}
+	// Anyone can see the lists, so they don't give away email addresses
+	if strings.Contains(rec.Body.String(), "jesse@example.com") {
+		t.Errorf("followers list leaks emails: %s", rec.Body.String())
+	}
+
+	rec = ts.do("GET", "/api/users/"+jesse.ID.String()+"/following", nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	if following := decodeBody[FollowedUsersPage](t, rec).Users; len(following) != 1 || following[0].ID != walt.ID {
+		t.Errorf("jesse follows = %s", rec.Body.String())
+	}
+
+	expectStatus(t, ts.do("GET", "/api/users/nope/followers", nil, ""), http.StatusBadRequest)
+	expectStatus(t, ts.do("GET", waltPath+"/followers?limit=0", nil, ""), http.StatusBadRequest)
+
+	expectStatus(t, ts.do("DELETE", waltPath+"/follow", nil, bearer(jesse.Token)), http.StatusNoContent)
+
+	rec = ts.do("GET", waltPath+"/followers", nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	if followers := decodeBody[FollowedUsersPage](t, rec).Users; len(followers) != 0 {
+		t.Errorf("walt still has followers: %s", rec.Body.String())
+	}
+}
+
+func TestFollowListPagination(t *testing.T) {
+	ts := newTestServer(t)
+	walt := ts.signUp("walt@example.com")
+	waltPath := "/api/users/" + walt.ID.String()
+
+	followers := make(map[uuid.UUID]bool)
+	for _, email := range []string{"jesse@example.com", "skyler@example.com", "hank@example.com"} {
+		user := ts.signUp(email)
+		expectStatus(t, ts.do("POST", waltPath+"/follow", nil, bearer(user.Token)), http.StatusNoContent)
+		expectStatus(t, ts.do("POST", "/api/users/"+user.ID.String()+"/follow", nil, bearer(walt.Token)), http.StatusNoContent)
+		followers[user.ID] = true
+	}
+
+	for _, list := range []string{"/followers", "/following"} {
+		t.Run(list, func(t *testing.T) {
+			seen := make(map[uuid.UUID]bool)
+			cursor := ""
+			for pages := 0; pages < 3; pages++ {
+				rec := ts.do("GET", waltPath+list+"?limit=2&cursor="+cursor, nil, "")
+				expectStatus(t, rec, http.StatusOK)
+				page := decodeBody[FollowedUsersPage](t, rec)
+				for _, user := range page.Users {
+					if seen[user.ID] || !followers[user.ID] {
+						t.Fatalf("unexpected user %v on page %d", user.ID, pages)
+					}
+					seen[user.ID] = true
+				}
+
+				cursor = page.NextCursor
+				if cursor == "" {
+					break
+				}
+			}
+
+			if len(seen) != len(followers) || cursor != "" {
+				t.Errorf("paged through %d users, want %d", len(seen), len(followers))
+			}
+		})
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	ts := newTestServer(t)
+	walt := ts.signUp("walt@example.com")
+	jesse := ts.signUp("jesse@example.com")
+	skyler := ts.signUp("skyler@example.com")
+
+	expectStatus(t, ts.do("POST", "/api/users/"+walt.ID.String()+"/follow", nil, bearer(jesse.Token)), http.StatusNoContent)
+
+	first := ts.createChirp(walt.Token, "one")
+	ts.createChirp(skyler.Token, "not followed")
+	second := ts.createChirp(walt.Token, "two")
+
+	expectStatus(t, ts.do("GET", "/api/timeline", nil, ""), http.StatusUnauthorized)
+
+	rec := ts.do("GET", "/api/timeline?sort=desc", nil, bearer(jesse.Token))
+	expectStatus(t, rec, http.StatusOK)
+	timeline := decodeBody[ChirpsPage](t, rec).Chirps
+	if len(timeline) != 2 || timeline[0].ID != second.ID || timeline[1].ID != first.ID {
+		t.Errorf("timeline = %s", rec.Body.String())
+	}
+}
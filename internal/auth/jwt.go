@@ -18,6 +18,8 @@ type TokenType string
 const (
 	// TokenTypeAccess -
 	TokenTypeAccess TokenType = "chirpy-access"
+	// TokenTypeTwoFactorChallenge -
+	TokenTypeTwoFactorChallenge TokenType = "chirpy-2fa-challenge"
 )
 
 func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
@@ -41,34 +43,51 @@ func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (str
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	return validateJWT(
+		tokenString,
+		TokenTypeAccess,
+		func(token *jwt.Token) (interface{}, error) { return []byte(tokenSecret), nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+	)
+}
+
+func validateJWT(tokenString string, tokenType TokenType, keyFunc jwt.Keyfunc, opts ...jwt.ParserOption) (uuid.UUID, error) {
+	id, _, err := parseJWT(tokenString, tokenType, keyFunc, opts...)
+	return id, err
+}
+
+// parseJWT validates a token of the given type and returns the user ID
+// along with the claims.
+func parseJWT(tokenString string, tokenType TokenType, keyFunc jwt.Keyfunc, opts ...jwt.ParserOption) (uuid.UUID, *jwt.RegisteredClaims, error) {
 	claimsStruct := jwt.RegisteredClaims{}
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&claimsStruct,
-		func(token *jwt.Token) (interface{}, error) { return []byte(tokenSecret), nil },
+		keyFunc,
+		opts...,
 	)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, nil, err
 	}
 
 	userIDString, err := token.Claims.GetSubject()
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, nil, err
 	}
 
 	issuer, err := token.Claims.GetIssuer()
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, nil, err
 	}
-	if issuer != string(TokenTypeAccess) {
-		return uuid.Nil, errors.New("invalid issuer")
+	if issuer != string(tokenType) {
+		return uuid.Nil, nil, errors.New("invalid issuer")
 	}
 
 	id, err := uuid.Parse(userIDString)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid user ID: %w", err)
+		return uuid.Nil, nil, fmt.Errorf("invalid user ID: %w", err)
 	}
-	return id, nil
+	return id, &claimsStruct, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
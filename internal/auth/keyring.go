@@ -0,0 +1,293 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// hmacKeyID is the kid of the shared secret key. Tokens issued before kids
+// were introduced have none and are checked against this key too.
+const hmacKeyID = "hs256"
+
+// Key is one signing key of a Keyring. Public is nil for HMAC keys, which
+// are never published.
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	Signer interface{}
+	Public crypto.PublicKey
+}
+
+// Keyring signs access tokens with its current key and validates tokens
+// signed by any key it holds, so keys can be rotated without logging
+// everybody out.
+type Keyring struct {
+	mu      sync.RWMutex
+	keys    map[string]Key
+	current string
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{
+		keys: make(map[string]Key),
+	}
+}
+
+// NewHMACKey wraps a shared secret as an HS256 key.
+func NewHMACKey(secret string) Key {
+	return Key{
+		ID:     hmacKeyID,
+		Method: jwt.SigningMethodHS256,
+		Signer: []byte(secret),
+	}
+}
+
+// NewKey wraps an RSA or Ed25519 private key. Its ID is the RFC 7638
+// thumbprint of the public key.
+func NewKey(signer crypto.Signer) (Key, error) {
+	key := Key{
+		Signer: signer,
+		Public: signer.Public(),
+	}
+
+	switch signer.(type) {
+	case *rsa.PrivateKey:
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return Key{}, fmt.Errorf("unsupported key type %T", signer)
+	}
+
+	jwk, err := publicJWK(key)
+	if err != nil {
+		return Key{}, err
+	}
+	key.ID = jwk.thumbprint()
+
+	return key, nil
+}
+
+// ParsePrivateKeyPEM reads a PKCS#8 or PKCS#1 encoded private key.
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+
+	return signer, nil
+}
+
+// Add puts a key on the ring. With current set, it becomes the signing key.
+func (k *Keyring) Add(key Key, current bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys[key.ID] = key
+	if current || k.current == "" {
+		k.current = key.ID
+	}
+}
+
+// Remove takes a retired key off the ring. Tokens it signed stop validating.
+func (k *Keyring) Remove(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if id == k.current {
+		return errors.New("can't remove the current signing key")
+	}
+	delete(k.keys, id)
+	return nil
+}
+
+func (k *Keyring) MakeJWT(userID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return k.makeJWT(userID, TokenTypeAccess, "", expiresIn)
+}
+
+func (k *Keyring) ValidateJWT(tokenString string) (uuid.UUID, error) {
+	return validateJWT(tokenString, TokenTypeAccess, k.verificationKey)
+}
+
+// MakeChallengeJWT issues the token a user with two-factor authentication
+// gets after a correct password. It only proves the first factor and is not
+// accepted where an access token is expected. challengeID names the
+// server-side record that counts the attempts made with it.
+func (k *Keyring) MakeChallengeJWT(userID, challengeID uuid.UUID, expiresIn time.Duration) (string, error) {
+	return k.makeJWT(userID, TokenTypeTwoFactorChallenge, challengeID.String(), expiresIn)
+}
+
+// ValidateChallengeJWT returns the user and the challenge ID of a token
+// made by MakeChallengeJWT.
+func (k *Keyring) ValidateChallengeJWT(tokenString string) (uuid.UUID, uuid.UUID, error) {
+	userID, claims, err := parseJWT(tokenString, TokenTypeTwoFactorChallenge, k.verificationKey)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	challengeID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid challenge ID: %w", err)
+	}
+	return userID, challengeID, nil
+}
+
+func (k *Keyring) makeJWT(userID uuid.UUID, tokenType TokenType, id string, expiresIn time.Duration) (string, error) {
+	k.mu.RLock()
+	key, ok := k.keys[k.current]
+	k.mu.RUnlock()
+
+	if !ok {
+		return "", errors.New("no signing key configured")
+	}
+
+	claims := jwt.RegisteredClaims{
+		Issuer:    string(tokenType),
+		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+		Subject:   userID.String(),
+		ID:        id,
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.Signer)
+}
+
+// verificationKey picks the key named by the token's kid and makes sure the
+// token was signed with that key's algorithm.
+func (k *Keyring) verificationKey(token *jwt.Token) (interface{}, error) {
+	id, _ := token.Header["kid"].(string)
+	if id == "" {
+		id = hmacKeyID
+	}
+
+	k.mu.RLock()
+	key, ok := k.keys[id]
+	k.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", id)
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), id)
+	}
+
+	if key.Public != nil {
+		return key.Public, nil
+	}
+	return key.Signer, nil
+}
+
+// JWK is the public half of a key as published in a JWKS document.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS lists the public keys of the ring. HMAC keys are left out.
+func (k *Keyring) JWKS() JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0)}
+	for _, key := range k.keys {
+		if key.Public == nil {
+			continue
+		}
+		jwk, err := publicJWK(key)
+		if err != nil {
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+func publicJWK(key Key) (JWK, error) {
+	switch pub := key.Public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   "RSA",
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+			N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType:   "OKP",
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: key.Method.Alg(),
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(pub),
+		}, nil
+	}
+	return JWK{}, fmt.Errorf("unsupported public key type %T", key.Public)
+}
+
+// thumbprint implements RFC 7638: the hash of the required members only,
+// in lexicographic order.
+func (j JWK) thumbprint() string {
+	var members interface{}
+	if j.KeyType == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{j.E, j.KeyType, j.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{j.Curve, j.KeyType, j.X}
+	}
+
+	dat, _ := json.Marshal(members)
+	sum := sha256.Sum256(dat)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestKeyringValidateJWT(t *testing.T) {
+	userID := uuid.New()
+
+	rsaPrivate, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaKey, _ := NewKey(rsaPrivate)
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	edKey, _ := NewKey(edPrivate)
+	hmacKey := NewHMACKey("secret")
+
+	// Signed while the RSA key was current, then rotated to Ed25519
+	rotating := NewKeyring()
+	rotating.Add(rsaKey, true)
+	rsaToken, _ := rotating.MakeJWT(userID, time.Hour)
+	rotating.Add(edKey, true)
+	edToken, _ := rotating.MakeJWT(userID, time.Hour)
+
+	legacyToken, _ := MakeJWT(userID, "secret", time.Hour)
+	challengeToken, _ := rotating.MakeChallengeJWT(userID, uuid.New(), time.Hour)
+
+	hmacRing := NewKeyring()
+	hmacRing.Add(hmacKey, true)
+
+	edOnly := NewKeyring()
+	edOnly.Add(edKey, true)
+
+	// HS256 token whose "secret" is the published RSA modulus, claiming the RSA kid
+	confused := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    string(TokenTypeAccess),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		Subject:   userID.String(),
+	})
+	confused.Header["kid"] = rsaKey.ID
+	confusedToken, _ := confused.SignedString(rsaPrivate.PublicKey.N.Bytes())
+
+	tests := []struct {
+		name        string
+		keyring     *Keyring
+		tokenString string
+		wantUserID  uuid.UUID
+		wantErr     bool
+	}{
+		{
+			name:        "Current key",
+			keyring:     rotating,
+			tokenString: edToken,
+			wantUserID:  userID,
+		},
+		{
+			name:        "Previous key",
+			keyring:     rotating,
+			tokenString: rsaToken,
+			wantUserID:  userID,
+		},
+		{
+			name:        "Key no longer on the ring",
+			keyring:     edOnly,
+			tokenString: rsaToken,
+			wantUserID:  uuid.Nil,
+			wantErr:     true,
+		},
+		{
+			name:        "HS256 token without kid",
+			keyring:     hmacRing,
+			tokenString: legacyToken,
+			wantUserID:  userID,
+		},
+		{
+			name:        "HS256 token on an asymmetric ring",
+			keyring:     edOnly,
+			tokenString: legacyToken,
+			wantUserID:  uuid.Nil,
+			wantErr:     true,
+		},
+		{
+			name:        "Challenge token used as access token",
+			keyring:     rotating,
+			tokenString: challengeToken,
+			wantUserID:  uuid.Nil,
+			wantErr:     true,
+		},
+		{
+			name:        "Algorithm confusion",
+			keyring:     rotating,
+			tokenString: confusedToken,
+			wantUserID:  uuid.Nil,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUserID, err := tt.keyring.ValidateJWT(tt.tokenString)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateJWT() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotUserID != tt.wantUserID {
+				t.Errorf("ValidateJWT() gotUserID = %v, want %v", gotUserID, tt.wantUserID)
+			}
+		})
+	}
+}
+
+func TestKeyringChallengeJWT(t *testing.T) {
+	keyring := NewKeyring()
+	keyring.Add(NewHMACKey("secret"), true)
+	userID := uuid.New()
+	challengeID := uuid.New()
+
+	token, err := keyring.MakeChallengeJWT(userID, challengeID, time.Hour)
+	if err != nil {
+		t.Fatalf("MakeChallengeJWT() error = %v", err)
+	}
+
+	gotUserID, gotChallengeID, err := keyring.ValidateChallengeJWT(token)
+	if err != nil || gotUserID != userID || gotChallengeID != challengeID {
+		t.Errorf("ValidateChallengeJWT() = %v, %v, %v, want %v, %v", gotUserID, gotChallengeID, err, userID, challengeID)
+	}
+
+	accessToken, _ := keyring.MakeJWT(userID, time.Hour)
+	if _, _, err := keyring.ValidateChallengeJWT(accessToken); err == nil {
+		t.Errorf("ValidateChallengeJWT() accepted an access token")
+	}
+}
+
+func TestKeyringJWKS(t *testing.T) {
+	rsaPrivate, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaKey, _ := NewKey(rsaPrivate)
+
+	keyring := NewKeyring()
+	keyring.Add(NewHMACKey("secret"), true)
+	keyring.Add(rsaKey, false)
+
+	jwks := keyring.JWKS()
+	if len(jwks.Keys) != 1 {
+		t.Fatalf("JWKS() returned %d keys, want 1", len(jwks.Keys))
+	}
+	if jwks.Keys[0].KeyID != rsaKey.ID || jwks.Keys[0].Algorithm != "RS256" || jwks.Keys[0].E != "AQAB" {
+		t.Errorf("JWKS() = %+v, want the RSA key", jwks.Keys[0])
+	}
+}
@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// MakeSingleUseToken returns a token to email to a user, and the hash to
+// store in its place, so a leaked database can't be used to redeem it.
+func MakeSingleUseToken() (token, hash string) {
+	token = MakeRefreshToken()
+	return token, HashSingleUseToken(token)
+}
+
+func HashSingleUseToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, SHA1, truncated to 6 digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		name string
+		unix int64
+		want string
+	}{
+		{name: "59", unix: 59, want: "287082"},
+		{name: "1111111109", unix: 1111111109, want: "081804"},
+		{name: "1111111111", unix: 1111111111, want: "050471"},
+		{name: "1234567890", unix: 1234567890, want: "005924"},
+		{name: "2000000000", unix: 2000000000, want: "279037"},
+		{name: "20000000000", unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TOTPCode(secret, TOTPStep(time.Unix(tt.unix, 0)))
+			if err != nil {
+				t.Fatalf("TOTPCode() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("TOTPCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := MakeTOTPSecret()
+	now := time.Now()
+	step := TOTPStep(now)
+	current, _ := TOTPCode(secret, step)
+	previous, _ := TOTPCode(secret, step-1)
+	stale, _ := TOTPCode(secret, step-5)
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "Current code", code: current, wantStep: step, wantOK: true},
+		{name: "Previous code", code: previous, wantStep: step - 1, wantOK: true},
+		{name: "Stale code", code: stale, wantOK: false},
+		{name: "Garbage", code: "abcdef", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, gotOK := ValidateTOTP(secret, tt.code, now)
+			if gotOK != tt.wantOK {
+				t.Fatalf("ValidateTOTP() ok = %v, want %v", gotOK, tt.wantOK)
+			}
+			if gotOK && gotStep != tt.wantStep {
+				t.Errorf("ValidateTOTP() step = %v, want %v", gotStep, tt.wantStep)
+			}
+		})
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	code := MakeRecoveryCodes(1)[0]
+
+	if HashRecoveryCode(code) != HashRecoveryCode(" "+code+" ") {
+		t.Errorf("HashRecoveryCode() should ignore surrounding whitespace")
+	}
+	if HashRecoveryCode(code) == HashRecoveryCode(MakeRecoveryCodes(1)[0]) {
+		t.Errorf("HashRecoveryCode() returned the same hash for different codes")
+	}
+}
@@ -7,36 +7,61 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_id, root_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body     string
+	UserID   uuid.UUID
+	ParentID uuid.NullUUID
+	RootID   uuid.NullUUID
 }
 
-func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
-	var i Chirp
+type CreateChirpRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	ParentID  uuid.NullUUID
+	RootID    uuid.NullUUID
+	DeletedAt sql.NullTime
+}
+
+func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (CreateChirpRow, error) {
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.ParentID,
+		arg.RootID,
+	)
+	var i CreateChirpRow
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
@@ -50,6 +75,19 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteChirpWithoutReplies = `-- name: DeleteChirpWithoutReplies :execrows
+DELETE FROM chirps WHERE id = $1
+    AND NOT EXISTS (SELECT 1 FROM chirps AS replies WHERE replies.parent_id = $1)
+`
+
+func (q *Queries) DeleteChirpWithoutReplies(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteChirpWithoutReplies, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const dropChirps = `-- name: DropChirps :exec
 DELETE FROM chirps
 `
@@ -60,43 +98,156 @@ func (q *Queries) DropChirps(ctx context.Context) error {
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
 WHERE id = $1
 `
 
-func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+type GetChirpRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	ParentID  uuid.NullUUID
+	RootID    uuid.NullUUID
+	DeletedAt sql.NullTime
+}
+
+func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (GetChirpRow, error) {
 	row := q.db.QueryRowContext(ctx, getChirp, id)
-	var i Chirp
+	var i GetChirpRow
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-ORDER BY created_at
+const getChirpsAsc = `-- name: GetChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
+WHERE deleted_at IS NULL
+    AND ($1::timestamp IS NULL
+        OR (created_at, id) > ($1::timestamp, $2::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $3::int
+`
+
+type GetChirpsAscParams struct {
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+type GetChirpsAscRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	ParentID  uuid.NullUUID
+	RootID    uuid.NullUUID
+	DeletedAt sql.NullTime
+}
+
+func (q *Queries) GetChirpsAsc(ctx context.Context, arg GetChirpsAscParams) ([]GetChirpsAscRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsAsc, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpsAscRow
+	for rows.Next() {
+		var i GetChirpsAscRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsByAuthorAsc = `-- name: GetChirpsByAuthorAsc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, feed_at, rechirped_by FROM (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, chirps.created_at AS feed_at, NULL::uuid AS rechirped_by FROM chirps
+    WHERE chirps.user_id = $1
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, rechirps.created_at AS feed_at, rechirps.user_id AS rechirped_by FROM rechirps
+    JOIN chirps ON chirps.id = rechirps.chirp_id
+    WHERE rechirps.user_id = $1
+) AS feed
+WHERE deleted_at IS NULL
+    AND ($2::timestamp IS NULL
+        OR (feed_at, id) > ($2::timestamp, $3::uuid))
+ORDER BY feed_at ASC, id ASC
+LIMIT $4::int
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+type GetChirpsByAuthorAscParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+type GetChirpsByAuthorAscRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Body        string
+	UserID      uuid.UUID
+	ParentID    uuid.NullUUID
+	RootID      uuid.NullUUID
+	DeletedAt   sql.NullTime
+	FeedAt      time.Time
+	RechirpedBy uuid.NullUUID
+}
+
+func (q *Queries) GetChirpsByAuthorAsc(ctx context.Context, arg GetChirpsByAuthorAscParams) ([]GetChirpsByAuthorAscRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByAuthorAsc,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Chirp
+	var items []GetChirpsByAuthorAscRow
 	for rows.Next() {
-		var i Chirp
+		var i GetChirpsByAuthorAscRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.FeedAt,
+			&i.RechirpedBy,
 		); err != nil {
 			return nil, err
 		}
@@ -111,26 +262,67 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getChirpsByAuthor = `-- name: GetChirpsByAuthor :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps where user_id = $1
-ORDER BY created_at
+const getChirpsByAuthorDesc = `-- name: GetChirpsByAuthorDesc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, feed_at, rechirped_by FROM (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, chirps.created_at AS feed_at, NULL::uuid AS rechirped_by FROM chirps
+    WHERE chirps.user_id = $1
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, rechirps.created_at AS feed_at, rechirps.user_id AS rechirped_by FROM rechirps
+    JOIN chirps ON chirps.id = rechirps.chirp_id
+    WHERE rechirps.user_id = $1
+) AS feed
+WHERE deleted_at IS NULL
+    AND ($2::timestamp IS NULL
+        OR (feed_at, id) < ($2::timestamp, $3::uuid))
+ORDER BY feed_at DESC, id DESC
+LIMIT $4::int
 `
 
-func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByAuthor, userID)
+type GetChirpsByAuthorDescParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+type GetChirpsByAuthorDescRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Body        string
+	UserID      uuid.UUID
+	ParentID    uuid.NullUUID
+	RootID      uuid.NullUUID
+	DeletedAt   sql.NullTime
+	FeedAt      time.Time
+	RechirpedBy uuid.NullUUID
+}
+
+func (q *Queries) GetChirpsByAuthorDesc(ctx context.Context, arg GetChirpsByAuthorDescParams) ([]GetChirpsByAuthorDescRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByAuthorDesc,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Chirp
+	var items []GetChirpsByAuthorDescRow
 	for rows.Next() {
-		var i Chirp
+		var i GetChirpsByAuthorDescRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.FeedAt,
+			&i.RechirpedBy,
 		); err != nil {
 			return nil, err
 		}
@@ -144,3 +336,179 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Ch
 	}
 	return items, nil
 }
+
+const getChirpsDesc = `-- name: GetChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
+WHERE deleted_at IS NULL
+    AND ($1::timestamp IS NULL
+        OR (created_at, id) < ($1::timestamp, $2::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $3::int
+`
+
+type GetChirpsDescParams struct {
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+type GetChirpsDescRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	ParentID  uuid.NullUUID
+	RootID    uuid.NullUUID
+	DeletedAt sql.NullTime
+}
+
+func (q *Queries) GetChirpsDesc(ctx context.Context, arg GetChirpsDescParams) ([]GetChirpsDescRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsDesc, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpsDescRow
+	for rows.Next() {
+		var i GetChirpsDescRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getThread = `-- name: GetThread :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
+WHERE id = $1 OR root_id = $1
+ORDER BY created_at ASC, id ASC
+`
+
+type GetThreadRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	ParentID  uuid.NullUUID
+	RootID    uuid.NullUUID
+	DeletedAt sql.NullTime
+}
+
+func (q *Queries) GetThread(ctx context.Context, rootID uuid.UUID) ([]GetThreadRow, error) {
+	rows, err := q.db.QueryContext(ctx, getThread, rootID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetThreadRow
+	for rows.Next() {
+		var i GetThreadRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const tombstoneChirp = `-- name: TombstoneChirp :one
+UPDATE chirps SET body = '', deleted_at = NOW(), updated_at = NOW() WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at
+`
+
+type TombstoneChirpRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	ParentID  uuid.NullUUID
+	RootID    uuid.NullUUID
+	DeletedAt sql.NullTime
+}
+
+func (q *Queries) TombstoneChirp(ctx context.Context, id uuid.UUID) (TombstoneChirpRow, error) {
+	row := q.db.QueryRowContext(ctx, tombstoneChirp, id)
+	var i TombstoneChirpRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps SET body = $2, updated_at = NOW() WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at
+`
+
+type UpdateChirpBodyParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+type UpdateChirpBodyRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	ParentID  uuid.NullUUID
+	RootID    uuid.NullUUID
+	DeletedAt sql.NullTime
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (UpdateChirpBodyRow, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body)
+	var i UpdateChirpBodyRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.DeletedAt,
+	)
+	return i, err
+}
@@ -11,28 +11,168 @@ import (
 	"github.com/google/uuid"
 )
 
+type BadWord struct {
+	Word      string
+	CreatedAt time.Time
+}
+
 type Chirp struct {
-	ID        uuid.UUID
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Body         string
+	UserID       uuid.UUID
+	ParentID     uuid.NullUUID
+	RootID       uuid.NullUUID
+	DeletedAt    sql.NullTime
+	SearchVector interface{}
+}
+
+type ChirpFlag struct {
+	ChirpID      uuid.UUID
+	MatchedWords string
+	CreatedAt    time.Time
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
+}
+
+type ChirpView struct {
+	ChirpID uuid.UUID
+	Views   int64
+}
+
+type EmailVerificationToken struct {
+	TokenHash string
 	UserID    uuid.UUID
+	Email     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
 }
 
-type RefreshToken struct {
-	Token     string
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type Rechirp struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
 	CreatedAt time.Time
-	UpdatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	Token      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+	FamilyID   uuid.UUID
+	UserAgent  string
+	IpAddress  string
+	LastUsedAt time.Time
+}
+
+type ScheduledChirp struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Body         string
+	MatchedWords string
+	PublishAt    time.Time
+	CreatedAt    time.Time
+}
+
+type SiteSetting struct {
+	ID                    bool
+	AllowUnverifiedChirps bool
+}
+
+type Subscription struct {
+	UserID           uuid.UUID
+	Plan             string
+	Status           string
+	CurrentPeriodEnd time.Time
+	GracePeriodEnd   sql.NullTime
+	CanceledAt       sql.NullTime
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+type TwoFactorChallenge struct {
+	ID        uuid.UUID
 	UserID    uuid.UUID
+	Attempts  int32
+	CreatedAt time.Time
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
 }
 
 type User struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Email           string
+	HashedPassword  string
+	EmailVerifiedAt sql.NullTime
+}
+
+type UserTotp struct {
+	UserID    uuid.UUID
+	Secret    string
+	CreatedAt time.Time
+	EnabledAt sql.NullTime
+	LastStep  int64
+}
+
+type WebhookDelivery struct {
 	ID             uuid.UUID
+	EndpointID     uuid.UUID
+	EventType      string
+	Payload        string
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastStatusCode int32
+	LastError      string
 	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	DeliveredAt    sql.NullTime
+}
+
+type WebhookEndpoint struct {
+	ID        uuid.UUID
+	UserID    uuid.NullUUID
+	Url       string
+	Secret    string
+	Events    string
+	CreatedAt time.Time
+}
+
+type WebhookEvent struct {
+	ID          string
+	EventType   string
+	Payload     string
+	Status      string
+	LastError   string
+	Attempts    int32
+	ReceivedAt  time.Time
+	ProcessedAt sql.NullTime
 }
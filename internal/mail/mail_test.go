@@ -0,0 +1,160 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	date := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	data, err := format("chirpy@example.com", Message{
+		To:      "walt@example.com",
+		Subject: "Réinitialiser",
+		Body:    "line one\nline two",
+	}, date)
+	if err != nil {
+		t.Fatalf("format() error = %v", err)
+	}
+
+	want := "From: chirpy@example.com\r\n" +
+		"To: walt@example.com\r\n" +
+		"Subject: =?utf-8?q?R=C3=A9initialiser?=\r\n" +
+		"Date: Fri, 01 Mar 2024 12:00:00 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"line one\r\nline two"
+	if string(data) != want {
+		t.Errorf("format() = %q, want %q", data, want)
+	}
+
+	tests := []Message{
+		{To: "walt@example.com\r\nBcc: jesse@example.com", Subject: "hi"},
+		{To: "walt@example.com", Subject: "hi\nBcc: jesse@example.com"},
+	}
+	for _, msg := range tests {
+		if _, err := format("chirpy@example.com", msg, date); err == nil {
+			t.Errorf("format(%q) error = nil, want an error for the line break", msg)
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer, err := NewFile(dir, "chirpy@example.com")
+	if err != nil {
+		t.Fatalf("NewFile() error = %v", err)
+	}
+
+	for _, to := range []string{"walt@example.com", "jesse@example.com"} {
+		if err := mailer.Send(context.Background(), Message{To: to, Subject: "hi", Body: "hello"}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("got files %v, %v, want 2 .eml files", files, err)
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !strings.Contains(string(data), "Subject: hi\r\n") || !strings.HasSuffix(string(data), "\r\n\r\nhello") {
+		t.Errorf("file = %q", data)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	mailer := NewMemory()
+
+	msg := Message{To: "walt@example.com", Subject: "hi", Body: "hello"}
+	if err := mailer.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if err := mailer.Send(context.Background(), Message{To: "walt@example.com\nBcc: x", Subject: "hi"}); err == nil {
+		t.Error("Send() error = nil, want an error for the line break")
+	}
+
+	messages := mailer.Messages()
+	if len(messages) != 1 || messages[0] != msg {
+		t.Errorf("Messages() = %+v, want [%+v]", messages, msg)
+	}
+}
+
+func TestSMTP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go serveSMTP(t, listener, received)
+
+	mailer := NewSMTP(listener.Addr().String(), "chirpy@example.com", "", "")
+	err = mailer.Send(context.Background(), Message{To: "walt@example.com", Subject: "hi", Body: "hello"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	transcript := <-received
+	for _, want := range []string{"MAIL FROM:<chirpy@example.com>", "RCPT TO:<walt@example.com>", "Subject: hi", "hello"} {
+		if !strings.Contains(transcript, want) {
+			t.Errorf("transcript %q doesn't contain %q", transcript, want)
+		}
+	}
+}
+
+// serveSMTP accepts one connection and speaks just enough SMTP for
+// smtp.SendMail, reporting everything the client sent.
+func serveSMTP(t *testing.T, listener net.Listener, received chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Errorf("Accept() error = %v", err)
+		received <- ""
+		return
+	}
+	defer conn.Close()
+
+	var transcript strings.Builder
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	inData := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		transcript.WriteString(line)
+
+		switch {
+		case inData && line == ".\r\n":
+			inData = false
+			reply("250 OK")
+		case inData:
+		case strings.HasPrefix(line, "EHLO"):
+			reply("250 localhost")
+		case strings.HasPrefix(line, "DATA"):
+			inData = true
+			reply("354 Go ahead")
+		case strings.HasPrefix(line, "QUIT"):
+			reply("221 Bye")
+			received <- transcript.String()
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+	received <- transcript.String()
+}
@@ -0,0 +1,263 @@
+package moderation
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Mask replaces every matched word when the filter runs with ActionMask.
+const Mask = "****"
+
+type Action string
+
+const (
+	// ActionMask replaces matched words with Mask
+	ActionMask Action = "mask"
+	// ActionReject refuses the whole text
+	ActionReject Action = "reject"
+	// ActionFlag keeps the text as is and marks it for review
+	ActionFlag Action = "flag"
+)
+
+func ParseAction(s string) (Action, error) {
+	switch Action(s) {
+	case "":
+		return ActionMask, nil
+	case ActionMask, ActionReject, ActionFlag:
+		return Action(s), nil
+	}
+	return "", fmt.Errorf("unknown moderation action %q", s)
+}
+
+var leetspeak = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'9': 'g',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+	'|': 'l',
+	'+': 't',
+}
+
+// homoglyphs maps lower case Cyrillic and Greek letters to the Latin letters
+// they are usually mistaken for.
+var homoglyphs = map[rune]rune{
+	'а': 'a',
+	'в': 'b',
+	'е': 'e',
+	'к': 'k',
+	'о': 'o',
+	'р': 'p',
+	'с': 'c',
+	'у': 'y',
+	'х': 'x',
+	'і': 'i',
+	'ј': 'j',
+	'ѕ': 's',
+	'ԁ': 'd',
+	'һ': 'h',
+	'ԛ': 'q',
+	'ԝ': 'w',
+	'α': 'a',
+	'ε': 'e',
+	'ι': 'i',
+	'κ': 'k',
+	'ν': 'v',
+	'ο': 'o',
+	'ρ': 'p',
+	'τ': 't',
+	'υ': 'u',
+	'χ': 'x',
+}
+
+type Filter struct {
+	mu        sync.RWMutex
+	words     map[string]struct{}
+	action    Action
+	normalize bool
+}
+
+// Result is the outcome of checking a text. Text has the matched words
+// masked, whatever the configured action is.
+type Result struct {
+	Text    string
+	Matches []string
+}
+
+// NewFilter creates a filter with an empty word list. With normalize set,
+// leetspeak, homoglyphs and full-width letters are folded to plain Latin
+// letters before words are compared.
+func NewFilter(action Action, normalize bool) *Filter {
+	return &Filter{
+		words:     make(map[string]struct{}),
+		action:    action,
+		normalize: normalize,
+	}
+}
+
+func (f *Filter) Action() Action {
+	return f.action
+}
+
+// SetWords replaces the word list.
+func (f *Filter) SetWords(words []string) {
+	set := make(map[string]struct{}, len(words))
+	for _, word := range words {
+		key := f.key(word)
+		if key != "" {
+			set[key] = struct{}{}
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.words = set
+}
+
+func (f *Filter) Check(text string) Result {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var b strings.Builder
+	matches := make([]string, 0)
+	last := 0
+
+	for _, s := range f.spans(text) {
+		start, end, ok := f.match(text, s[0], s[1])
+		if !ok {
+			continue
+		}
+
+		b.WriteString(text[last:start])
+		b.WriteString(Mask)
+		last = end
+		matches = append(matches, text[start:end])
+	}
+	b.WriteString(text[last:])
+
+	return Result{
+		Text:    b.String(),
+		Matches: matches,
+	}
+}
+
+// match looks the word between start and end up in the list. Leetspeak
+// symbols at the edges of a word are usually punctuation ("fornax!"), so when
+// the whole word doesn't match it is retried without them.
+func (f *Filter) match(text string, start, end int) (int, int, bool) {
+	if _, ok := f.words[f.key(text[start:end])]; ok {
+		return start, end, true
+	}
+
+	for start < end {
+		r, size := utf8.DecodeRuneInString(text[start:end])
+		if isLetter(r) {
+			break
+		}
+		start += size
+	}
+
+	for end > start {
+		r, size := utf8.DecodeLastRuneInString(text[start:end])
+		if isLetter(r) {
+			break
+		}
+		end -= size
+	}
+
+	if start == end {
+		return 0, 0, false
+	}
+
+	_, ok := f.words[f.key(text[start:end])]
+	return start, end, ok
+}
+
+// spans returns the byte offsets of every word in text. A word is a run of
+// letters and digits, plus leetspeak symbols when normalizing, so apostrophes
+// and hyphens end one: "x-word" is two words.
+func (f *Filter) spans(text string) [][2]int {
+	spans := make([][2]int, 0)
+	start := -1
+
+	for i, r := range text {
+		if f.isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+
+		if start >= 0 {
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+
+	return spans
+}
+
+// IsWord reports whether word is a single word as Check splits text. An entry
+// that isn't can never match.
+func (f *Filter) IsWord(word string) bool {
+	spans := f.spans(word)
+	return len(spans) == 1 && spans[0] == [2]int{0, len(word)}
+}
+
+func (f *Filter) isWordRune(r rune) bool {
+	if isLetter(r) {
+		return true
+	}
+	if f.normalize {
+		_, ok := leetspeak[r]
+		return ok
+	}
+	return false
+}
+
+// key is the form a word is compared in.
+func (f *Filter) key(word string) string {
+	var b strings.Builder
+
+	for _, r := range word {
+		// Full-width forms of ASCII, e.g. ｋｅｒｆｕｆｆｌｅ
+		if f.normalize && r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFEE0
+		}
+
+		r = unicode.ToLower(r)
+
+		if f.normalize {
+			if unicode.Is(unicode.Mn, r) {
+				// Drop combining accents
+				continue
+			}
+			if latin, ok := homoglyphs[r]; ok {
+				r = latin
+			} else if latin, ok := leetspeak[r]; ok {
+				r = latin
+			}
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func isLetter(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r)
+}
@@ -0,0 +1,142 @@
+package moderation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	words := []string{"kerfuffle", "sharbert", "fornax"}
+
+	tests := []struct {
+		name        string
+		normalize   bool
+		text        string
+		wantText    string
+		wantMatches []string
+	}{
+		{
+			name:        "Clean text",
+			text:        "I had something interesting for breakfast",
+			wantText:    "I had something interesting for breakfast",
+			wantMatches: []string{},
+		},
+		{
+			name:        "Plain word",
+			text:        "I hear Mastodon is better than Chirpy. sharbert I need to migrate",
+			wantText:    "I hear Mastodon is better than Chirpy. **** I need to migrate",
+			wantMatches: []string{"sharbert"},
+		},
+		{
+			name:        "Punctuation and case",
+			text:        "What a Kerfuffle, really. kerfuffle!",
+			wantText:    "What a ****, really. ****!",
+			wantMatches: []string{"Kerfuffle", "kerfuffle"},
+		},
+		{
+			name:        "Non-ASCII boundaries",
+			text:        "«fornax»—¿sharbert?",
+			wantText:    "«****»—¿****?",
+			wantMatches: []string{"fornax", "sharbert"},
+		},
+		{
+			name:        "Part of a longer word",
+			text:        "fornaxes are fine",
+			wantText:    "fornaxes are fine",
+			wantMatches: []string{},
+		},
+		{
+			name:        "Leetspeak without normalization",
+			text:        "k3rfuffl3",
+			wantText:    "k3rfuffl3",
+			wantMatches: []string{},
+		},
+		{
+			name:        "Leetspeak",
+			normalize:   true,
+			text:        "what a k3rfuffl3 and a sh@rb3rt!",
+			wantText:    "what a **** and a ****!",
+			wantMatches: []string{"k3rfuffl3", "sh@rb3rt"},
+		},
+		{
+			name:        "Homoglyphs",
+			normalize:   true,
+			text:        "fоrnах",
+			wantText:    "****",
+			wantMatches: []string{"fоrnах"},
+		},
+		{
+			name:        "Full-width letters",
+			normalize:   true,
+			text:        "ｆｏｒｎａｘ.",
+			wantText:    "****.",
+			wantMatches: []string{"ｆｏｒｎａｘ"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFilter(ActionMask, tt.normalize)
+			f.SetWords(words)
+
+			got := f.Check(tt.text)
+			if got.Text != tt.wantText {
+				t.Errorf("Check() text = %q, want %q", got.Text, tt.wantText)
+			}
+			if !reflect.DeepEqual(got.Matches, tt.wantMatches) {
+				t.Errorf("Check() matches = %q, want %q", got.Matches, tt.wantMatches)
+			}
+		})
+	}
+}
+
+func TestIsWord(t *testing.T) {
+	tests := []struct {
+		word      string
+		normalize bool
+		want      bool
+	}{
+		{word: "fornax", want: true},
+		{word: "ｋｅｒｆｕｆｆｌｅ", want: true},
+		{word: "f0rnax", want: true},
+		{word: "f@rnax", want: false},
+		{word: "f@rnax", normalize: true, want: true},
+		{word: "f'ing", want: false},
+		{word: "x-word", want: false},
+		{word: "two words", want: false},
+		{word: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := NewFilter(ActionMask, tt.normalize).IsWord(tt.word); got != tt.want {
+			t.Errorf("IsWord(%q) with normalize %v = %v, want %v", tt.word, tt.normalize, got, tt.want)
+		}
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Action
+		wantErr bool
+	}{
+		{name: "Default", input: "", want: ActionMask},
+		{name: "Reject", input: "reject", want: ActionReject},
+		{name: "Flag", input: "flag", want: ActionFlag},
+		{name: "Unknown", input: "ban", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAction(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseAction() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseAction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
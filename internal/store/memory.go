@@ -0,0 +1,235 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+// Errors the in-memory store returns where Postgres would reject a write
+// because of a constraint.
+var (
+	ErrUniqueViolation     = errors.New("store: duplicate key violates unique constraint")
+	ErrForeignKeyViolation = errors.New("store: foreign key violation")
+	ErrCheckViolation      = errors.New("store: check constraint violation")
+)
+
+type pairKey [2]uuid.UUID
+
+// tables is the in-memory copy of the schema. Rows are stored by value, so
+// copying the maps copies the data.
+type tables struct {
+	users             map[uuid.UUID]database.User
+	chirps            map[uuid.UUID]database.Chirp
+	refreshTokens     map[string]database.RefreshToken
+	follows           map[pairKey]database.Follow
+	chirpLikes        map[pairKey]database.ChirpLike
+	rechirps          map[pairKey]database.Rechirp
+	badWords          map[string]database.BadWord
+	chirpFlags        map[uuid.UUID]database.ChirpFlag
+	userTOTP          map[uuid.UUID]database.UserTotp
+	recoveryCodes     map[uuid.UUID]database.RecoveryCode
+	challenges        map[uuid.UUID]database.TwoFactorChallenge
+	webhookEvents     map[string]database.WebhookEvent
+	subscriptions     map[uuid.UUID]database.Subscription
+	scheduledChirps   map[uuid.UUID]database.ScheduledChirp
+	chirpViews        map[uuid.UUID]int64
+	webhookEndpoints  map[uuid.UUID]database.WebhookEndpoint
+	webhookDeliveries map[uuid.UUID]database.WebhookDelivery
+	passwordResets    map[string]database.PasswordResetToken
+	emailTokens       map[string]database.EmailVerificationToken
+	siteSettings      database.SiteSetting
+}
+
+func newTables() *tables {
+	return &tables{
+		users:             map[uuid.UUID]database.User{},
+		chirps:            map[uuid.UUID]database.Chirp{},
+		refreshTokens:     map[string]database.RefreshToken{},
+		follows:           map[pairKey]database.Follow{},
+		chirpLikes:        map[pairKey]database.ChirpLike{},
+		rechirps:          map[pairKey]database.Rechirp{},
+		badWords:          map[string]database.BadWord{},
+		chirpFlags:        map[uuid.UUID]database.ChirpFlag{},
+		userTOTP:          map[uuid.UUID]database.UserTotp{},
+		recoveryCodes:     map[uuid.UUID]database.RecoveryCode{},
+		challenges:        map[uuid.UUID]database.TwoFactorChallenge{},
+		webhookEvents:     map[string]database.WebhookEvent{},
+		subscriptions:     map[uuid.UUID]database.Subscription{},
+		scheduledChirps:   map[uuid.UUID]database.ScheduledChirp{},
+		chirpViews:        map[uuid.UUID]int64{},
+		webhookEndpoints:  map[uuid.UUID]database.WebhookEndpoint{},
+		webhookDeliveries: map[uuid.UUID]database.WebhookDelivery{},
+		passwordResets:    map[string]database.PasswordResetToken{},
+		emailTokens:       map[string]database.EmailVerificationToken{},
+	}
+}
+
+func (t *tables) clone() *tables {
+	return &tables{
+		users:             maps.Clone(t.users),
+		chirps:            maps.Clone(t.chirps),
+		refreshTokens:     maps.Clone(t.refreshTokens),
+		follows:           maps.Clone(t.follows),
+		chirpLikes:        maps.Clone(t.chirpLikes),
+		rechirps:          maps.Clone(t.rechirps),
+		badWords:          maps.Clone(t.badWords),
+		chirpFlags:        maps.Clone(t.chirpFlags),
+		userTOTP:          maps.Clone(t.userTOTP),
+		recoveryCodes:     maps.Clone(t.recoveryCodes),
+		challenges:        maps.Clone(t.challenges),
+		webhookEvents:     maps.Clone(t.webhookEvents),
+		subscriptions:     maps.Clone(t.subscriptions),
+		scheduledChirps:   maps.Clone(t.scheduledChirps),
+		chirpViews:        maps.Clone(t.chirpViews),
+		webhookEndpoints:  maps.Clone(t.webhookEndpoints),
+		webhookDeliveries: maps.Clone(t.webhookDeliveries),
+		passwordResets:    maps.Clone(t.passwordResets),
+		emailTokens:       maps.Clone(t.emailTokens),
+		siteSettings:      t.siteSettings,
+	}
+}
+
+// Memory is a Store that keeps everything in process memory. It follows the
+// semantics of the Postgres queries, constraints and cascades included, and
+// is safe for concurrent use. A transaction holds the store's lock until it
+// commits or rolls back.
+type Memory struct {
+	mu   *sync.Mutex
+	data *tables
+	// inTx is set on the view a transaction works on, whose lock is already
+	// held
+	inTx bool
+}
+
+// NewMemory returns a store holding what the migrations seed.
+func NewMemory() *Memory {
+	m := &Memory{
+		mu:   &sync.Mutex{},
+		data: newTables(),
+	}
+
+	for _, word := range []string{"kerfuffle", "sharbert", "fornax"} {
+		m.data.badWords[word] = database.BadWord{Word: word, CreatedAt: now()}
+	}
+	m.data.siteSettings = database.SiteSetting{ID: true, AllowUnverifiedChirps: true}
+
+	return m
+}
+
+func (m *Memory) lock() func() {
+	if m.inTx {
+		return func() {}
+	}
+	m.mu.Lock()
+	return m.mu.Unlock
+}
+
+func (m *Memory) BeginTx(ctx context.Context) (Tx, error) {
+	m.mu.Lock()
+
+	return &memoryTx{
+		Memory: &Memory{mu: m.mu, data: m.data.clone(), inTx: true},
+		parent: m,
+	}, nil
+}
+
+func (m *Memory) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+// memoryTx works on a copy of the tables, which replaces the store's on
+// commit.
+type memoryTx struct {
+	*Memory
+	parent *Memory
+	done   bool
+}
+
+func (t *memoryTx) Commit() error {
+	if t.done {
+		return errors.New("store: transaction has already been committed or rolled back")
+	}
+	t.done = true
+
+	t.parent.data = t.data
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *memoryTx) Rollback() error {
+	if t.done {
+		return nil
+	}
+	t.done = true
+
+	t.mu.Unlock()
+	return nil
+}
+
+// now is NOW() in Postgres, which keeps microseconds.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func nullNow() sql.NullTime {
+	return sql.NullTime{Time: now(), Valid: true}
+}
+
+// timestamp is t as a Postgres timestamp column keeps it. lib/pq sends the
+// wall clock with an offset the column ignores, so it reads back as that
+// wall clock in UTC, and only times already in UTC survive unchanged.
+func timestamp(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC).Truncate(time.Microsecond)
+}
+
+func nullTimestamp(t sql.NullTime) sql.NullTime {
+	if !t.Valid {
+		return t
+	}
+	return sql.NullTime{Time: timestamp(t.Time), Valid: true}
+}
+
+// compareKeys orders rows the way (timestamp, id) row comparisons do.
+func compareKeys(at time.Time, id uuid.UUID, otherAt time.Time, otherID uuid.UUID) int {
+	if c := at.Compare(otherAt); c != 0 {
+		return c
+	}
+	return compareIDs(id, otherID)
+}
+
+// afterCursor reports whether a row is past a keyset cursor in the given
+// direction. A missing cursor lets every row through.
+func afterCursor(at time.Time, id uuid.UUID, cursorAt sql.NullTime, cursorID uuid.NullUUID, desc bool) bool {
+	if !cursorAt.Valid {
+		return true
+	}
+
+	c := compareKeys(at, id, cursorAt.Time, cursorID.UUID)
+	if desc {
+		return c < 0
+	}
+	return c > 0
+}
+
+func limit[T any](rows []T, n int32) []T {
+	if n >= 0 && len(rows) > int(n) {
+		return rows[:n]
+	}
+	return rows
+}
+
+func compareIDs(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
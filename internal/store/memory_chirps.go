@@ -0,0 +1,306 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func (m *Memory) CreateChirp(ctx context.Context, arg database.CreateChirpParams) (database.CreateChirpRow, error) {
+	defer m.lock()()
+
+	if _, ok := m.data.users[arg.UserID]; !ok {
+		return database.CreateChirpRow{}, ErrForeignKeyViolation
+	}
+	for _, ref := range []uuid.NullUUID{arg.ParentID, arg.RootID} {
+		if _, ok := m.data.chirps[ref.UUID]; ref.Valid && !ok {
+			return database.CreateChirpRow{}, ErrForeignKeyViolation
+		}
+	}
+
+	chirp := database.Chirp{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		UpdatedAt: now(),
+		Body:      arg.Body,
+		UserID:    arg.UserID,
+		ParentID:  arg.ParentID,
+		RootID:    arg.RootID,
+	}
+	m.data.chirps[chirp.ID] = chirp
+	return selectChirp[database.CreateChirpRow](chirp), nil
+}
+
+func (m *Memory) DropChirps(ctx context.Context) error {
+	defer m.lock()()
+
+	for id := range m.data.chirps {
+		m.deleteChirp(id)
+	}
+	return nil
+}
+
+func (m *Memory) GetChirp(ctx context.Context, id uuid.UUID) (database.GetChirpRow, error) {
+	defer m.lock()()
+
+	chirp, ok := m.data.chirps[id]
+	if !ok {
+		return database.GetChirpRow{}, sql.ErrNoRows
+	}
+	return selectChirp[database.GetChirpRow](chirp), nil
+}
+
+func (m *Memory) DeleteChirp(ctx context.Context, id uuid.UUID) error {
+	defer m.lock()()
+
+	m.deleteChirp(id)
+	return nil
+}
+
+func (m *Memory) DeleteChirpWithoutReplies(ctx context.Context, id uuid.UUID) (int64, error) {
+	defer m.lock()()
+
+	if _, ok := m.data.chirps[id]; !ok || m.countReplies(uuid.NullUUID{UUID: id, Valid: true}) > 0 {
+		return 0, nil
+	}
+	m.deleteChirp(id)
+	return 1, nil
+}
+
+// deleteChirp removes a chirp with everything that cascades from it.
+func (m *Memory) deleteChirp(id uuid.UUID) {
+	delete(m.data.chirps, id)
+	delete(m.data.chirpFlags, id)
+	delete(m.data.chirpViews, id)
+
+	for key := range m.data.chirpLikes {
+		if key[1] == id {
+			delete(m.data.chirpLikes, key)
+		}
+	}
+	for key := range m.data.rechirps {
+		if key[1] == id {
+			delete(m.data.rechirps, key)
+		}
+	}
+
+	// Replies lose the reference, like ON DELETE SET NULL
+	for replyID, reply := range m.data.chirps {
+		changed := false
+		if reply.ParentID.Valid && reply.ParentID.UUID == id {
+			reply.ParentID = uuid.NullUUID{}
+			changed = true
+		}
+		if reply.RootID.Valid && reply.RootID.UUID == id {
+			reply.RootID = uuid.NullUUID{}
+			changed = true
+		}
+		if changed {
+			m.data.chirps[replyID] = reply
+		}
+	}
+}
+
+func (m *Memory) UpdateChirpBody(ctx context.Context, arg database.UpdateChirpBodyParams) (database.UpdateChirpBodyRow, error) {
+	defer m.lock()()
+
+	chirp, ok := m.data.chirps[arg.ID]
+	if !ok {
+		return database.UpdateChirpBodyRow{}, sql.ErrNoRows
+	}
+
+	chirp.Body = arg.Body
+	chirp.UpdatedAt = now()
+	m.data.chirps[chirp.ID] = chirp
+	return selectChirp[database.UpdateChirpBodyRow](chirp), nil
+}
+
+func (m *Memory) TombstoneChirp(ctx context.Context, id uuid.UUID) (database.TombstoneChirpRow, error) {
+	defer m.lock()()
+
+	chirp, ok := m.data.chirps[id]
+	if !ok {
+		return database.TombstoneChirpRow{}, sql.ErrNoRows
+	}
+
+	chirp.Body = ""
+	chirp.DeletedAt = nullNow()
+	chirp.UpdatedAt = now()
+	m.data.chirps[chirp.ID] = chirp
+	return selectChirp[database.TombstoneChirpRow](chirp), nil
+}
+
+func (m *Memory) countReplies(parentID uuid.NullUUID) int64 {
+	var count int64
+	for _, chirp := range m.data.chirps {
+		if parentID.Valid && chirp.ParentID.Valid && chirp.ParentID.UUID == parentID.UUID {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *Memory) GetThread(ctx context.Context, rootID uuid.UUID) ([]database.GetThreadRow, error) {
+	defer m.lock()()
+
+	return selectChirps[database.GetThreadRow](m.sortedChirps(false, func(chirp database.Chirp) bool {
+		return chirp.ID == rootID || (chirp.RootID.Valid && chirp.RootID.UUID == rootID)
+	})), nil
+}
+
+// chirpColumns are the columns chirp queries select, which is all of them
+// but search_vector. sqlc gives every query its own row type with them.
+type chirpColumns = struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	ParentID  uuid.NullUUID
+	RootID    uuid.NullUUID
+	DeletedAt sql.NullTime
+}
+
+// selectChirp is chirp as a query of row type T returns it.
+func selectChirp[T ~chirpColumns](chirp database.Chirp) T {
+	return T{
+		ID:        chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+		Body:      chirp.Body,
+		UserID:    chirp.UserID,
+		ParentID:  chirp.ParentID,
+		RootID:    chirp.RootID,
+		DeletedAt: chirp.DeletedAt,
+	}
+}
+
+func selectChirps[T ~chirpColumns](chirps []database.Chirp) []T {
+	rows := make([]T, 0, len(chirps))
+	for _, chirp := range chirps {
+		rows = append(rows, selectChirp[T](chirp))
+	}
+	return rows
+}
+
+// sortedChirps returns the chirps keep accepts by (created_at, id).
+func (m *Memory) sortedChirps(desc bool, keep func(database.Chirp) bool) []database.Chirp {
+	chirps := make([]database.Chirp, 0)
+	for _, chirp := range m.data.chirps {
+		if keep(chirp) {
+			chirps = append(chirps, chirp)
+		}
+	}
+
+	slices.SortFunc(chirps, func(a, b database.Chirp) int {
+		c := compareKeys(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+		if desc {
+			return -c
+		}
+		return c
+	})
+	return chirps
+}
+
+func (m *Memory) getChirps(cursorAt sql.NullTime, cursorID uuid.NullUUID, pageLimit int32, desc bool) []database.Chirp {
+	return limit(m.sortedChirps(desc, func(chirp database.Chirp) bool {
+		return !chirp.DeletedAt.Valid && afterCursor(chirp.CreatedAt, chirp.ID, cursorAt, cursorID, desc)
+	}), pageLimit)
+}
+
+func (m *Memory) GetChirpsAsc(ctx context.Context, arg database.GetChirpsAscParams) ([]database.GetChirpsAscRow, error) {
+	defer m.lock()()
+
+	return selectChirps[database.GetChirpsAscRow](m.getChirps(arg.CursorCreatedAt, arg.CursorID, arg.PageLimit, false)), nil
+}
+
+func (m *Memory) GetChirpsDesc(ctx context.Context, arg database.GetChirpsDescParams) ([]database.GetChirpsDescRow, error) {
+	defer m.lock()()
+
+	return selectChirps[database.GetChirpsDescRow](m.getChirps(arg.CursorCreatedAt, arg.CursorID, arg.PageLimit, true)), nil
+}
+
+// authorFeedRow is a row of an author's feed: their chirps and their
+// rechirps, ordered by when they appeared in it.
+type authorFeedRow struct {
+	chirp       database.Chirp
+	feedAt      time.Time
+	rechirpedBy uuid.NullUUID
+}
+
+func (m *Memory) getChirpsByAuthor(userID uuid.UUID, cursorAt sql.NullTime, cursorID uuid.NullUUID, pageLimit int32, desc bool) []authorFeedRow {
+	rows := make([]authorFeedRow, 0)
+	for _, chirp := range m.data.chirps {
+		if chirp.UserID == userID {
+			rows = append(rows, authorFeedRow{chirp: chirp, feedAt: chirp.CreatedAt})
+		}
+	}
+	for key, rechirp := range m.data.rechirps {
+		if key[0] == userID {
+			rows = append(rows, authorFeedRow{
+				chirp:       m.data.chirps[key[1]],
+				feedAt:      rechirp.CreatedAt,
+				rechirpedBy: uuid.NullUUID{UUID: userID, Valid: true},
+			})
+		}
+	}
+
+	rows = slices.DeleteFunc(rows, func(row authorFeedRow) bool {
+		return row.chirp.DeletedAt.Valid || !afterCursor(row.feedAt, row.chirp.ID, cursorAt, cursorID, desc)
+	})
+
+	slices.SortFunc(rows, func(a, b authorFeedRow) int {
+		c := compareKeys(a.feedAt, a.chirp.ID, b.feedAt, b.chirp.ID)
+		if desc {
+			return -c
+		}
+		return c
+	})
+	return limit(rows, pageLimit)
+}
+
+func (m *Memory) GetChirpsByAuthorAsc(ctx context.Context, arg database.GetChirpsByAuthorAscParams) ([]database.GetChirpsByAuthorAscRow, error) {
+	defer m.lock()()
+
+	items := make([]database.GetChirpsByAuthorAscRow, 0)
+	for _, row := range m.getChirpsByAuthor(arg.UserID, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit, false) {
+		items = append(items, database.GetChirpsByAuthorAscRow{
+			ID:          row.chirp.ID,
+			CreatedAt:   row.chirp.CreatedAt,
+			UpdatedAt:   row.chirp.UpdatedAt,
+			Body:        row.chirp.Body,
+			UserID:      row.chirp.UserID,
+			ParentID:    row.chirp.ParentID,
+			RootID:      row.chirp.RootID,
+			DeletedAt:   row.chirp.DeletedAt,
+			FeedAt:      row.feedAt,
+			RechirpedBy: row.rechirpedBy,
+		})
+	}
+	return items, nil
+}
+
+func (m *Memory) GetChirpsByAuthorDesc(ctx context.Context, arg database.GetChirpsByAuthorDescParams) ([]database.GetChirpsByAuthorDescRow, error) {
+	defer m.lock()()
+
+	items := make([]database.GetChirpsByAuthorDescRow, 0)
+	for _, row := range m.getChirpsByAuthor(arg.UserID, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit, true) {
+		items = append(items, database.GetChirpsByAuthorDescRow{
+			ID:          row.chirp.ID,
+			CreatedAt:   row.chirp.CreatedAt,
+			UpdatedAt:   row.chirp.UpdatedAt,
+			Body:        row.chirp.Body,
+			UserID:      row.chirp.UserID,
+			ParentID:    row.chirp.ParentID,
+			RootID:      row.chirp.RootID,
+			DeletedAt:   row.chirp.DeletedAt,
+			FeedAt:      row.feedAt,
+			RechirpedBy: row.rechirpedBy,
+		})
+	}
+	return items, nil
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func (m *Memory) CreateEmailVerificationToken(ctx context.Context, arg database.CreateEmailVerificationTokenParams) error {
+	defer m.lock()()
+
+	if _, ok := m.data.users[arg.UserID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := m.data.emailTokens[arg.TokenHash]; ok {
+		return ErrUniqueViolation
+	}
+
+	m.data.emailTokens[arg.TokenHash] = database.EmailVerificationToken{
+		TokenHash: arg.TokenHash,
+		UserID:    arg.UserID,
+		Email:     arg.Email,
+		CreatedAt: now(),
+		ExpiresAt: timestamp(arg.ExpiresAt),
+	}
+	return nil
+}
+
+func (m *Memory) UseEmailVerificationToken(ctx context.Context, tokenHash string) (database.UseEmailVerificationTokenRow, error) {
+	defer m.lock()()
+
+	token, ok := m.data.emailTokens[tokenHash]
+	if !ok || !token.ExpiresAt.After(now()) {
+		return database.UseEmailVerificationTokenRow{}, sql.ErrNoRows
+	}
+
+	delete(m.data.emailTokens, tokenHash)
+	return database.UseEmailVerificationTokenRow{UserID: token.UserID, Email: token.Email}, nil
+}
+
+func (m *Memory) DeleteEmailVerificationTokens(ctx context.Context, userID uuid.UUID) error {
+	defer m.lock()()
+
+	for hash, token := range m.data.emailTokens {
+		if token.UserID == userID {
+			delete(m.data.emailTokens, hash)
+		}
+	}
+	return nil
+}
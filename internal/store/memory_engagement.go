@@ -0,0 +1,121 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func (m *Memory) CreateChirpLike(ctx context.Context, arg database.CreateChirpLikeParams) error {
+	defer m.lock()()
+
+	if err := m.checkUserAndChirp(arg.UserID, arg.ChirpID); err != nil {
+		return err
+	}
+
+	key := pairKey{arg.UserID, arg.ChirpID}
+	if _, ok := m.data.chirpLikes[key]; !ok {
+		m.data.chirpLikes[key] = database.ChirpLike{UserID: arg.UserID, ChirpID: arg.ChirpID, CreatedAt: now()}
+	}
+	return nil
+}
+
+func (m *Memory) DeleteChirpLike(ctx context.Context, arg database.DeleteChirpLikeParams) error {
+	defer m.lock()()
+
+	delete(m.data.chirpLikes, pairKey{arg.UserID, arg.ChirpID})
+	return nil
+}
+
+func (m *Memory) CreateRechirp(ctx context.Context, arg database.CreateRechirpParams) error {
+	defer m.lock()()
+
+	if err := m.checkUserAndChirp(arg.UserID, arg.ChirpID); err != nil {
+		return err
+	}
+
+	key := pairKey{arg.UserID, arg.ChirpID}
+	if _, ok := m.data.rechirps[key]; !ok {
+		m.data.rechirps[key] = database.Rechirp{UserID: arg.UserID, ChirpID: arg.ChirpID, CreatedAt: now()}
+	}
+	return nil
+}
+
+func (m *Memory) DeleteRechirp(ctx context.Context, arg database.DeleteRechirpParams) error {
+	defer m.lock()()
+
+	delete(m.data.rechirps, pairKey{arg.UserID, arg.ChirpID})
+	return nil
+}
+
+func (m *Memory) checkUserAndChirp(userID, chirpID uuid.UUID) error {
+	if _, ok := m.data.users[userID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := m.data.chirps[chirpID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	return nil
+}
+
+func (m *Memory) countEngagement(chirpID uuid.UUID) (likes, rechirps int64) {
+	for key := range m.data.chirpLikes {
+		if key[1] == chirpID {
+			likes++
+		}
+	}
+	for key := range m.data.rechirps {
+		if key[1] == chirpID {
+			rechirps++
+		}
+	}
+	return likes, rechirps
+}
+
+func (m *Memory) GetChirpEngagement(ctx context.Context, arg database.GetChirpEngagementParams) ([]database.GetChirpEngagementRow, error) {
+	defer m.lock()()
+
+	items := make([]database.GetChirpEngagementRow, 0)
+	for _, id := range arg.ChirpIds {
+		if _, ok := m.data.chirps[id]; !ok {
+			continue
+		}
+
+		row := database.GetChirpEngagementRow{ID: id}
+		row.LikeCount, row.RechirpCount = m.countEngagement(id)
+		if arg.ViewerID.Valid {
+			_, row.LikedByMe = m.data.chirpLikes[pairKey{arg.ViewerID.UUID, id}]
+			_, row.RechirpedByMe = m.data.rechirps[pairKey{arg.ViewerID.UUID, id}]
+		}
+		items = append(items, row)
+	}
+	return items, nil
+}
+
+func (m *Memory) IncrementChirpViews(ctx context.Context, chirpID uuid.UUID) error {
+	defer m.lock()()
+
+	if _, ok := m.data.chirps[chirpID]; !ok {
+		return ErrForeignKeyViolation
+	}
+
+	m.data.chirpViews[chirpID]++
+	return nil
+}
+
+func (m *Memory) GetChirpAnalytics(ctx context.Context, id uuid.UUID) (database.GetChirpAnalyticsRow, error) {
+	defer m.lock()()
+
+	if _, ok := m.data.chirps[id]; !ok {
+		return database.GetChirpAnalyticsRow{}, sql.ErrNoRows
+	}
+
+	row := database.GetChirpAnalyticsRow{
+		Views:      m.data.chirpViews[id],
+		ReplyCount: m.countReplies(uuid.NullUUID{UUID: id, Valid: true}),
+	}
+	row.LikeCount, row.RechirpCount = m.countEngagement(id)
+	return row, nil
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func (m *Memory) CreateFollow(ctx context.Context, arg database.CreateFollowParams) (int64, error) {
+	defer m.lock()()
+
+	if arg.FollowerID == arg.FolloweeID {
+		return 0, ErrCheckViolation
+	}
+	for _, id := range []uuid.UUID{arg.FollowerID, arg.FolloweeID} {
+		if _, ok := m.data.users[id]; !ok {
+			return 0, ErrForeignKeyViolation
+		}
+	}
+
+	key := pairKey{arg.FollowerID, arg.FolloweeID}
+	if _, ok := m.data.follows[key]; ok {
+		return 0, nil
+	}
+
+	m.data.follows[key] = database.Follow{FollowerID: arg.FollowerID, FolloweeID: arg.FolloweeID, CreatedAt: now()}
+	return 1, nil
+}
+
+func (m *Memory) DeleteFollow(ctx context.Context, arg database.DeleteFollowParams) error {
+	defer m.lock()()
+
+	delete(m.data.follows, pairKey{arg.FollowerID, arg.FolloweeID})
+	return nil
+}
+
+// followedUsers lists a page of the users on the other side of userID's
+// follows, newest follow first. followers picks which side userID is on.
+func (m *Memory) followedUsers(userID uuid.UUID, followers bool, cursorAt sql.NullTime, cursorID uuid.NullUUID, pageLimit int32) []database.GetFollowersRow {
+	rows := make([]database.GetFollowersRow, 0)
+	for key, follow := range m.data.follows {
+		self, other := key[1], key[0]
+		if !followers {
+			self, other = key[0], key[1]
+		}
+		if self != userID || !afterCursor(follow.CreatedAt, other, cursorAt, cursorID, true) {
+			continue
+		}
+
+		rows = append(rows, database.GetFollowersRow{
+			ID:         other,
+			FollowedAt: follow.CreatedAt,
+		})
+	}
+
+	slices.SortFunc(rows, func(a, b database.GetFollowersRow) int {
+		return compareKeys(b.FollowedAt, b.ID, a.FollowedAt, a.ID)
+	})
+	return limit(rows, pageLimit)
+}
+
+func (m *Memory) GetFollowers(ctx context.Context, arg database.GetFollowersParams) ([]database.GetFollowersRow, error) {
+	defer m.lock()()
+
+	return m.followedUsers(arg.FolloweeID, true, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit), nil
+}
+
+func (m *Memory) GetFollowing(ctx context.Context, arg database.GetFollowingParams) ([]database.GetFollowingRow, error) {
+	defer m.lock()()
+
+	items := make([]database.GetFollowingRow, 0)
+	for _, row := range m.followedUsers(arg.FollowerID, false, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit) {
+		items = append(items, database.GetFollowingRow(row))
+	}
+	return items, nil
+}
+
+func (m *Memory) getTimeline(followerID uuid.UUID, cursorAt sql.NullTime, cursorID uuid.NullUUID, pageLimit int32, desc bool) []database.Chirp {
+	return limit(m.sortedChirps(desc, func(chirp database.Chirp) bool {
+		_, following := m.data.follows[pairKey{followerID, chirp.UserID}]
+		return following && !chirp.DeletedAt.Valid && afterCursor(chirp.CreatedAt, chirp.ID, cursorAt, cursorID, desc)
+	}), pageLimit)
+}
+
+func (m *Memory) GetTimelineAsc(ctx context.Context, arg database.GetTimelineAscParams) ([]database.GetTimelineAscRow, error) {
+	defer m.lock()()
+
+	return selectChirps[database.GetTimelineAscRow](m.getTimeline(arg.FollowerID, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit, false)), nil
+}
+
+func (m *Memory) GetTimelineDesc(ctx context.Context, arg database.GetTimelineDescParams) ([]database.GetTimelineDescRow, error) {
+	defer m.lock()()
+
+	return selectChirps[database.GetTimelineDescRow](m.getTimeline(arg.FollowerID, arg.CursorCreatedAt, arg.CursorID, arg.PageLimit, true)), nil
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func (m *Memory) GetBadWords(ctx context.Context) ([]string, error) {
+	defer m.lock()()
+
+	words := make([]string, 0, len(m.data.badWords))
+	for word := range m.data.badWords {
+		words = append(words, word)
+	}
+	slices.Sort(words)
+	return words, nil
+}
+
+func (m *Memory) CreateBadWord(ctx context.Context, word string) error {
+	defer m.lock()()
+
+	if _, ok := m.data.badWords[word]; !ok {
+		m.data.badWords[word] = database.BadWord{Word: word, CreatedAt: now()}
+	}
+	return nil
+}
+
+func (m *Memory) DeleteBadWord(ctx context.Context, word string) (int64, error) {
+	defer m.lock()()
+
+	if _, ok := m.data.badWords[word]; !ok {
+		return 0, nil
+	}
+	delete(m.data.badWords, word)
+	return 1, nil
+}
+
+func (m *Memory) CreateChirpFlag(ctx context.Context, arg database.CreateChirpFlagParams) error {
+	defer m.lock()()
+
+	if _, ok := m.data.chirps[arg.ChirpID]; !ok {
+		return ErrForeignKeyViolation
+	}
+
+	m.data.chirpFlags[arg.ChirpID] = database.ChirpFlag{
+		ChirpID:      arg.ChirpID,
+		MatchedWords: arg.MatchedWords,
+		CreatedAt:    now(),
+	}
+	return nil
+}
+
+func (m *Memory) GetFlaggedChirps(ctx context.Context) ([]database.GetFlaggedChirpsRow, error) {
+	defer m.lock()()
+
+	rows := make([]database.GetFlaggedChirpsRow, 0)
+	for _, flag := range m.data.chirpFlags {
+		chirp := m.data.chirps[flag.ChirpID]
+		rows = append(rows, database.GetFlaggedChirpsRow{
+			ID:           chirp.ID,
+			CreatedAt:    chirp.CreatedAt,
+			Body:         chirp.Body,
+			UserID:       chirp.UserID,
+			MatchedWords: flag.MatchedWords,
+			FlaggedAt:    flag.CreatedAt,
+		})
+	}
+
+	slices.SortFunc(rows, func(a, b database.GetFlaggedChirpsRow) int {
+		return compareKeys(a.FlaggedAt, a.ID, b.FlaggedAt, b.ID)
+	})
+	return rows, nil
+}
+
+func (m *Memory) DeleteChirpFlag(ctx context.Context, chirpID uuid.UUID) (int64, error) {
+	defer m.lock()()
+
+	if _, ok := m.data.chirpFlags[chirpID]; !ok {
+		return 0, nil
+	}
+	delete(m.data.chirpFlags, chirpID)
+	return 1, nil
+}
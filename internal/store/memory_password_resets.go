@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func (m *Memory) CreatePasswordResetToken(ctx context.Context, arg database.CreatePasswordResetTokenParams) error {
+	defer m.lock()()
+
+	if _, ok := m.data.users[arg.UserID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := m.data.passwordResets[arg.TokenHash]; ok {
+		return ErrUniqueViolation
+	}
+
+	m.data.passwordResets[arg.TokenHash] = database.PasswordResetToken{
+		TokenHash: arg.TokenHash,
+		UserID:    arg.UserID,
+		CreatedAt: now(),
+		ExpiresAt: timestamp(arg.ExpiresAt),
+	}
+	return nil
+}
+
+func (m *Memory) UsePasswordResetToken(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	defer m.lock()()
+
+	reset, ok := m.data.passwordResets[tokenHash]
+	if !ok || !reset.ExpiresAt.After(now()) {
+		return uuid.Nil, sql.ErrNoRows
+	}
+
+	delete(m.data.passwordResets, tokenHash)
+	return reset.UserID, nil
+}
+
+func (m *Memory) DeletePasswordResetTokens(ctx context.Context, userID uuid.UUID) error {
+	defer m.lock()()
+
+	for hash, reset := range m.data.passwordResets {
+		if reset.UserID == userID {
+			delete(m.data.passwordResets, hash)
+		}
+	}
+	return nil
+}
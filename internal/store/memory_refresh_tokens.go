@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func (m *Memory) CreateRefreshToken(ctx context.Context, arg database.CreateRefreshTokenParams) (database.RefreshToken, error) {
+	defer m.lock()()
+
+	if _, ok := m.data.users[arg.UserID]; !ok {
+		return database.RefreshToken{}, ErrForeignKeyViolation
+	}
+	if _, ok := m.data.refreshTokens[arg.Token]; ok {
+		return database.RefreshToken{}, ErrUniqueViolation
+	}
+
+	token := database.RefreshToken{
+		Token:      arg.Token,
+		CreatedAt:  now(),
+		UpdatedAt:  now(),
+		UserID:     arg.UserID,
+		ExpiresAt:  timestamp(arg.ExpiresAt),
+		RevokedAt:  nullTimestamp(arg.RevokedAt),
+		FamilyID:   arg.FamilyID,
+		UserAgent:  arg.UserAgent,
+		IpAddress:  arg.IpAddress,
+		LastUsedAt: now(),
+	}
+	m.data.refreshTokens[token.Token] = token
+	return token, nil
+}
+
+func (m *Memory) DropRefreshTokens(ctx context.Context) error {
+	defer m.lock()()
+
+	clear(m.data.refreshTokens)
+	return nil
+}
+
+func (m *Memory) GetRefreshToken(ctx context.Context, token string) (database.RefreshToken, error) {
+	defer m.lock()()
+
+	refreshToken, ok := m.data.refreshTokens[token]
+	if !ok {
+		return database.RefreshToken{}, sql.ErrNoRows
+	}
+	return refreshToken, nil
+}
+
+func (m *Memory) UpdateRefreshToken(ctx context.Context, arg database.UpdateRefreshTokenParams) (database.RefreshToken, error) {
+	defer m.lock()()
+
+	token, ok := m.data.refreshTokens[arg.Token]
+	if !ok {
+		return database.RefreshToken{}, sql.ErrNoRows
+	}
+
+	token.RevokedAt = nullTimestamp(arg.RevokedAt)
+	m.data.refreshTokens[token.Token] = token
+	return token, nil
+}
+
+// revokeRefreshTokens revokes every live token match accepts and reports
+// how many there were.
+func (m *Memory) revokeRefreshTokens(match func(database.RefreshToken) bool) int64 {
+	var revoked int64
+	for key, token := range m.data.refreshTokens {
+		if token.RevokedAt.Valid || !match(token) {
+			continue
+		}
+
+		token.RevokedAt = nullNow()
+		token.UpdatedAt = now()
+		m.data.refreshTokens[key] = token
+		revoked++
+	}
+	return revoked
+}
+
+func (m *Memory) RevokeRefreshToken(ctx context.Context, token string) (int64, error) {
+	defer m.lock()()
+
+	return m.revokeRefreshTokens(func(t database.RefreshToken) bool {
+		return t.Token == token
+	}), nil
+}
+
+func (m *Memory) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	defer m.lock()()
+
+	m.revokeRefreshTokens(func(t database.RefreshToken) bool {
+		return t.FamilyID == familyID
+	})
+	return nil
+}
+
+func (m *Memory) RevokeUserRefreshTokenFamily(ctx context.Context, arg database.RevokeUserRefreshTokenFamilyParams) (int64, error) {
+	defer m.lock()()
+
+	return m.revokeRefreshTokens(func(t database.RefreshToken) bool {
+		return t.FamilyID == arg.FamilyID && t.UserID == arg.UserID
+	}), nil
+}
+
+func (m *Memory) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	defer m.lock()()
+
+	m.revokeRefreshTokens(func(t database.RefreshToken) bool {
+		return t.UserID == userID
+	})
+	return nil
+}
+
+func (m *Memory) GetUserSessions(ctx context.Context, userID uuid.UUID) ([]database.GetUserSessionsRow, error) {
+	defer m.lock()()
+
+	signedInAt := map[uuid.UUID]time.Time{}
+	for _, token := range m.data.refreshTokens {
+		first, ok := signedInAt[token.FamilyID]
+		if !ok || token.CreatedAt.Before(first) {
+			signedInAt[token.FamilyID] = token.CreatedAt
+		}
+	}
+
+	current := now()
+	rows := make([]database.GetUserSessionsRow, 0)
+	for _, token := range m.data.refreshTokens {
+		if token.UserID != userID || token.RevokedAt.Valid || !token.ExpiresAt.After(current) {
+			continue
+		}
+
+		rows = append(rows, database.GetUserSessionsRow{
+			FamilyID:   token.FamilyID,
+			UserAgent:  token.UserAgent,
+			IpAddress:  token.IpAddress,
+			LastUsedAt: token.LastUsedAt,
+			ExpiresAt:  token.ExpiresAt,
+			SignedInAt: signedInAt[token.FamilyID],
+		})
+	}
+
+	slices.SortFunc(rows, func(a, b database.GetUserSessionsRow) int {
+		return b.LastUsedAt.Compare(a.LastUsedAt)
+	})
+	return rows, nil
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func (m *Memory) CreateScheduledChirp(ctx context.Context, arg database.CreateScheduledChirpParams) (database.ScheduledChirp, error) {
+	defer m.lock()()
+
+	if _, ok := m.data.users[arg.UserID]; !ok {
+		return database.ScheduledChirp{}, ErrForeignKeyViolation
+	}
+
+	scheduled := database.ScheduledChirp{
+		ID:           uuid.New(),
+		UserID:       arg.UserID,
+		Body:         arg.Body,
+		MatchedWords: arg.MatchedWords,
+		PublishAt:    timestamp(arg.PublishAt),
+		CreatedAt:    now(),
+	}
+	m.data.scheduledChirps[scheduled.ID] = scheduled
+	return scheduled, nil
+}
+
+func (m *Memory) GetScheduledChirps(ctx context.Context, userID uuid.UUID) ([]database.ScheduledChirp, error) {
+	defer m.lock()()
+
+	rows := make([]database.ScheduledChirp, 0)
+	for _, scheduled := range m.data.scheduledChirps {
+		if scheduled.UserID == userID {
+			rows = append(rows, scheduled)
+		}
+	}
+
+	slices.SortFunc(rows, func(a, b database.ScheduledChirp) int {
+		return compareKeys(a.PublishAt, a.ID, b.PublishAt, b.ID)
+	})
+	return rows, nil
+}
+
+func (m *Memory) DeleteScheduledChirp(ctx context.Context, arg database.DeleteScheduledChirpParams) (int64, error) {
+	defer m.lock()()
+
+	scheduled, ok := m.data.scheduledChirps[arg.ID]
+	if !ok || scheduled.UserID != arg.UserID {
+		return 0, nil
+	}
+	delete(m.data.scheduledChirps, arg.ID)
+	return 1, nil
+}
+
+func (m *Memory) ClaimDueScheduledChirps(ctx context.Context) ([]database.ScheduledChirp, error) {
+	defer m.lock()()
+
+	current := now()
+	rows := make([]database.ScheduledChirp, 0)
+	for id, scheduled := range m.data.scheduledChirps {
+		if scheduled.PublishAt.After(current) {
+			continue
+		}
+		rows = append(rows, scheduled)
+		delete(m.data.scheduledChirps, id)
+	}
+
+	slices.SortFunc(rows, func(a, b database.ScheduledChirp) int {
+		return compareKeys(a.PublishAt, a.ID, b.PublishAt, b.ID)
+	})
+	return rows, nil
+}
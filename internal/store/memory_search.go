@@ -0,0 +1,62 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func (m *Memory) SearchChirpsByRecency(ctx context.Context, arg database.SearchChirpsByRecencyParams) ([]database.SearchChirpsByRecencyRow, error) {
+	defer m.lock()()
+
+	include, exclude := searchTerms(arg.Query)
+	return selectChirps[database.SearchChirpsByRecencyRow](limit(m.sortedChirps(true, func(chirp database.Chirp) bool {
+		_, match := searchRank(chirp.Body, include, exclude)
+		return match &&
+			!chirp.DeletedAt.Valid &&
+			(!arg.AuthorID.Valid || chirp.UserID == arg.AuthorID.UUID) &&
+			afterCursor(chirp.CreatedAt, chirp.ID, arg.CursorCreatedAt, arg.CursorID, true)
+	}), arg.PageLimit)), nil
+}
+
+func (m *Memory) SearchChirpsByRelevance(ctx context.Context, arg database.SearchChirpsByRelevanceParams) ([]database.SearchChirpsByRelevanceRow, error) {
+	defer m.lock()()
+
+	include, exclude := searchTerms(arg.Query)
+	rows := make([]database.SearchChirpsByRelevanceRow, 0)
+	for _, chirp := range m.data.chirps {
+		rank, match := searchRank(chirp.Body, include, exclude)
+		if !match || chirp.DeletedAt.Valid || (arg.AuthorID.Valid && chirp.UserID != arg.AuthorID.UUID) {
+			continue
+		}
+
+		if arg.CursorRank.Valid {
+			cursorRank := float32(arg.CursorRank.Float64)
+			if rank > cursorRank || (rank == cursorRank && compareIDs(chirp.ID, arg.CursorID.UUID) >= 0) {
+				continue
+			}
+		}
+
+		rows = append(rows, database.SearchChirpsByRelevanceRow{
+			ID:        chirp.ID,
+			CreatedAt: chirp.CreatedAt,
+			UpdatedAt: chirp.UpdatedAt,
+			Body:      chirp.Body,
+			UserID:    chirp.UserID,
+			ParentID:  chirp.ParentID,
+			RootID:    chirp.RootID,
+			DeletedAt: chirp.DeletedAt,
+			Rank:      rank,
+		})
+	}
+
+	slices.SortFunc(rows, func(a, b database.SearchChirpsByRelevanceRow) int {
+		if c := cmp.Compare(b.Rank, a.Rank); c != 0 {
+			return c
+		}
+		return compareIDs(b.ID, a.ID)
+	})
+	return limit(rows, arg.PageLimit), nil
+}
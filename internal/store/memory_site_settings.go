@@ -0,0 +1,20 @@
+package store
+
+import (
+	"context"
+
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func (m *Memory) GetSiteSettings(ctx context.Context) (database.SiteSetting, error) {
+	defer m.lock()()
+
+	return m.data.siteSettings, nil
+}
+
+func (m *Memory) UpdateSiteSettings(ctx context.Context, allowUnverifiedChirps bool) (database.SiteSetting, error) {
+	defer m.lock()()
+
+	m.data.siteSettings.AllowUnverifiedChirps = allowUnverifiedChirps
+	return m.data.siteSettings, nil
+}
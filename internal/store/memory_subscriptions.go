@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func (m *Memory) GetSubscription(ctx context.Context, userID uuid.UUID) (database.Subscription, error) {
+	defer m.lock()()
+
+	sub, ok := m.data.subscriptions[userID]
+	if !ok {
+		return database.Subscription{}, sql.ErrNoRows
+	}
+	return sub, nil
+}
+
+func (m *Memory) UpsertSubscription(ctx context.Context, arg database.UpsertSubscriptionParams) error {
+	defer m.lock()()
+
+	if _, ok := m.data.users[arg.UserID]; !ok {
+		return ErrForeignKeyViolation
+	}
+
+	sub, ok := m.data.subscriptions[arg.UserID]
+	if !ok {
+		sub = database.Subscription{UserID: arg.UserID, CreatedAt: now()}
+	}
+
+	sub.Plan = arg.Plan
+	sub.Status = "active"
+	sub.CurrentPeriodEnd = timestamp(arg.CurrentPeriodEnd)
+	sub.GracePeriodEnd = sql.NullTime{}
+	sub.CanceledAt = sql.NullTime{}
+	sub.UpdatedAt = now()
+	m.data.subscriptions[arg.UserID] = sub
+	return nil
+}
+
+// updateSubscription applies update to the user's subscription, if any.
+func (m *Memory) updateSubscription(userID uuid.UUID, update func(*database.Subscription)) {
+	sub, ok := m.data.subscriptions[userID]
+	if !ok {
+		return
+	}
+
+	update(&sub)
+	sub.UpdatedAt = now()
+	m.data.subscriptions[userID] = sub
+}
+
+func (m *Memory) RenewSubscription(ctx context.Context, arg database.RenewSubscriptionParams) error {
+	defer m.lock()()
+
+	m.updateSubscription(arg.UserID, func(sub *database.Subscription) {
+		sub.Status = "active"
+		sub.CurrentPeriodEnd = timestamp(arg.CurrentPeriodEnd)
+		sub.GracePeriodEnd = sql.NullTime{}
+	})
+	return nil
+}
+
+func (m *Memory) MarkSubscriptionPastDue(ctx context.Context, arg database.MarkSubscriptionPastDueParams) error {
+	defer m.lock()()
+
+	m.updateSubscription(arg.UserID, func(sub *database.Subscription) {
+		sub.Status = "past_due"
+		sub.GracePeriodEnd = nullTimestamp(arg.GracePeriodEnd)
+	})
+	return nil
+}
+
+func (m *Memory) CancelSubscription(ctx context.Context, userID uuid.UUID) error {
+	defer m.lock()()
+
+	m.updateSubscription(userID, func(sub *database.Subscription) {
+		sub.Status = "canceled"
+		sub.CanceledAt = nullNow()
+	})
+	return nil
+}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"maps"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func (m *Memory) UpsertUserTOTP(ctx context.Context, arg database.UpsertUserTOTPParams) error {
+	defer m.lock()()
+
+	if _, ok := m.data.users[arg.UserID]; !ok {
+		return ErrForeignKeyViolation
+	}
+
+	m.data.userTOTP[arg.UserID] = database.UserTotp{
+		UserID:    arg.UserID,
+		Secret:    arg.Secret,
+		CreatedAt: now(),
+	}
+	return nil
+}
+
+func (m *Memory) GetUserTOTP(ctx context.Context, userID uuid.UUID) (database.UserTotp, error) {
+	defer m.lock()()
+
+	totp, ok := m.data.userTOTP[userID]
+	if !ok {
+		return database.UserTotp{}, sql.ErrNoRows
+	}
+	return totp, nil
+}
+
+func (m *Memory) EnableUserTOTP(ctx context.Context, userID uuid.UUID) error {
+	defer m.lock()()
+
+	totp, ok := m.data.userTOTP[userID]
+	if ok {
+		totp.EnabledAt = nullNow()
+		m.data.userTOTP[userID] = totp
+	}
+	return nil
+}
+
+func (m *Memory) UpdateUserTOTPLastStep(ctx context.Context, arg database.UpdateUserTOTPLastStepParams) (int64, error) {
+	defer m.lock()()
+
+	totp, ok := m.data.userTOTP[arg.UserID]
+	if !ok || totp.LastStep >= arg.LastStep {
+		return 0, nil
+	}
+
+	totp.LastStep = arg.LastStep
+	m.data.userTOTP[arg.UserID] = totp
+	return 1, nil
+}
+
+func (m *Memory) DeleteUserTOTP(ctx context.Context, userID uuid.UUID) error {
+	defer m.lock()()
+
+	delete(m.data.userTOTP, userID)
+	return nil
+}
+
+func (m *Memory) CreateRecoveryCode(ctx context.Context, arg database.CreateRecoveryCodeParams) error {
+	defer m.lock()()
+
+	if _, ok := m.data.users[arg.UserID]; !ok {
+		return ErrForeignKeyViolation
+	}
+
+	code := database.RecoveryCode{
+		ID:        uuid.New(),
+		UserID:    arg.UserID,
+		CodeHash:  arg.CodeHash,
+		CreatedAt: now(),
+	}
+	m.data.recoveryCodes[code.ID] = code
+	return nil
+}
+
+func (m *Memory) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error {
+	defer m.lock()()
+
+	maps.DeleteFunc(m.data.recoveryCodes, func(_ uuid.UUID, code database.RecoveryCode) bool {
+		return code.UserID == userID
+	})
+	return nil
+}
+
+func (m *Memory) UseRecoveryCode(ctx context.Context, arg database.UseRecoveryCodeParams) (int64, error) {
+	defer m.lock()()
+
+	var used int64
+	for id, code := range m.data.recoveryCodes {
+		if code.UserID != arg.UserID || code.CodeHash != arg.CodeHash || code.UsedAt.Valid {
+			continue
+		}
+
+		code.UsedAt = nullNow()
+		m.data.recoveryCodes[id] = code
+		used++
+	}
+	return used, nil
+}
+
+func (m *Memory) CreateTwoFactorChallenge(ctx context.Context, arg database.CreateTwoFactorChallengeParams) error {
+	defer m.lock()()
+
+	if _, ok := m.data.users[arg.UserID]; !ok {
+		return ErrForeignKeyViolation
+	}
+	if _, ok := m.data.challenges[arg.ID]; ok {
+		return ErrUniqueViolation
+	}
+
+	m.data.challenges[arg.ID] = database.TwoFactorChallenge{
+		ID:        arg.ID,
+		UserID:    arg.UserID,
+		CreatedAt: now(),
+		ExpiresAt: timestamp(arg.ExpiresAt),
+	}
+	return nil
+}
+
+func (m *Memory) CountTwoFactorAttempt(ctx context.Context, arg database.CountTwoFactorAttemptParams) (int64, error) {
+	defer m.lock()()
+
+	challenge, ok := m.data.challenges[arg.ID]
+	if !ok || challenge.UserID != arg.UserID || !challenge.ExpiresAt.After(now()) || challenge.Attempts >= arg.MaxAttempts {
+		return 0, nil
+	}
+
+	challenge.Attempts++
+	m.data.challenges[arg.ID] = challenge
+	return 1, nil
+}
+
+func (m *Memory) DeleteTwoFactorChallenge(ctx context.Context, id uuid.UUID) (int64, error) {
+	defer m.lock()()
+
+	if _, ok := m.data.challenges[id]; !ok {
+		return 0, nil
+	}
+
+	delete(m.data.challenges, id)
+	return 1, nil
+}
+
+func (m *Memory) DeleteExpiredTwoFactorChallenges(ctx context.Context, userID uuid.UUID) error {
+	defer m.lock()()
+
+	maps.DeleteFunc(m.data.challenges, func(_ uuid.UUID, challenge database.TwoFactorChallenge) bool {
+		return challenge.UserID == userID && !challenge.ExpiresAt.After(now())
+	})
+	return nil
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func (m *Memory) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	defer m.lock()()
+
+	for _, user := range m.data.users {
+		if user.Email == arg.Email {
+			return database.User{}, ErrUniqueViolation
+		}
+	}
+
+	user := database.User{
+		ID:             uuid.New(),
+		CreatedAt:      now(),
+		UpdatedAt:      now(),
+		Email:          arg.Email,
+		HashedPassword: arg.HashedPassword,
+	}
+	m.data.users[user.ID] = user
+	return user, nil
+}
+
+func (m *Memory) DropUsers(ctx context.Context) error {
+	defer m.lock()()
+
+	// Everything but the bad words, incoming webhooks and site settings
+	// hangs off a user, except admin webhook endpoints
+	endpoints := m.data.webhookEndpoints
+	deliveries := m.data.webhookDeliveries
+	badWords := m.data.badWords
+	webhookEvents := m.data.webhookEvents
+	siteSettings := m.data.siteSettings
+
+	*m.data = *newTables()
+	m.data.badWords = badWords
+	m.data.webhookEvents = webhookEvents
+	m.data.siteSettings = siteSettings
+
+	for id, endpoint := range endpoints {
+		if !endpoint.UserID.Valid {
+			m.data.webhookEndpoints[id] = endpoint
+		}
+	}
+	for id, delivery := range deliveries {
+		if _, ok := m.data.webhookEndpoints[delivery.EndpointID]; ok {
+			m.data.webhookDeliveries[id] = delivery
+		}
+	}
+	return nil
+}
+
+func (m *Memory) GetUser(ctx context.Context, id uuid.UUID) (database.User, error) {
+	defer m.lock()()
+
+	user, ok := m.data.users[id]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (m *Memory) GetUserByEmail(ctx context.Context, email string) (database.User, error) {
+	defer m.lock()()
+
+	for _, user := range m.data.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (m *Memory) UpdateUser(ctx context.Context, arg database.UpdateUserParams) (database.User, error) {
+	defer m.lock()()
+
+	user, ok := m.data.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+
+	for _, other := range m.data.users {
+		if other.ID != arg.ID && other.Email == arg.Email {
+			return database.User{}, ErrUniqueViolation
+		}
+	}
+
+	user.Email = arg.Email
+	user.HashedPassword = arg.HashedPassword
+	m.data.users[user.ID] = user
+	return user, nil
+}
+
+func (m *Memory) UpdateUserPassword(ctx context.Context, arg database.UpdateUserPasswordParams) error {
+	defer m.lock()()
+
+	user, ok := m.data.users[arg.ID]
+	if ok {
+		user.HashedPassword = arg.HashedPassword
+		user.UpdatedAt = now()
+		m.data.users[user.ID] = user
+	}
+	return nil
+}
+
+func (m *Memory) VerifyUserEmail(ctx context.Context, arg database.VerifyUserEmailParams) (database.User, error) {
+	defer m.lock()()
+
+	user, ok := m.data.users[arg.ID]
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+
+	for _, other := range m.data.users {
+		if other.ID != arg.ID && other.Email == arg.Email {
+			return database.User{}, ErrUniqueViolation
+		}
+	}
+
+	user.Email = arg.Email
+	user.EmailVerifiedAt = nullNow()
+	user.UpdatedAt = now()
+	m.data.users[user.ID] = user
+	return user, nil
+}
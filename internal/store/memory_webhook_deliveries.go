@@ -0,0 +1,169 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func (m *Memory) CreateWebhookEndpoint(ctx context.Context, arg database.CreateWebhookEndpointParams) (database.WebhookEndpoint, error) {
+	defer m.lock()()
+
+	if _, ok := m.data.users[arg.UserID.UUID]; arg.UserID.Valid && !ok {
+		return database.WebhookEndpoint{}, ErrForeignKeyViolation
+	}
+
+	endpoint := database.WebhookEndpoint{
+		ID:        uuid.New(),
+		UserID:    arg.UserID,
+		Url:       arg.Url,
+		Secret:    arg.Secret,
+		Events:    arg.Events,
+		CreatedAt: now(),
+	}
+	m.data.webhookEndpoints[endpoint.ID] = endpoint
+	return endpoint, nil
+}
+
+func (m *Memory) GetWebhookEndpoint(ctx context.Context, id uuid.UUID) (database.WebhookEndpoint, error) {
+	defer m.lock()()
+
+	endpoint, ok := m.data.webhookEndpoints[id]
+	if !ok {
+		return database.WebhookEndpoint{}, sql.ErrNoRows
+	}
+	return endpoint, nil
+}
+
+func (m *Memory) GetWebhookEndpoints(ctx context.Context, userID uuid.NullUUID) ([]database.WebhookEndpoint, error) {
+	defer m.lock()()
+
+	rows := make([]database.WebhookEndpoint, 0)
+	for _, endpoint := range m.data.webhookEndpoints {
+		if endpoint.UserID == userID {
+			rows = append(rows, endpoint)
+		}
+	}
+
+	slices.SortFunc(rows, func(a, b database.WebhookEndpoint) int {
+		return compareKeys(a.CreatedAt, a.ID, b.CreatedAt, b.ID)
+	})
+	return rows, nil
+}
+
+func (m *Memory) DeleteWebhookEndpoint(ctx context.Context, id uuid.UUID) error {
+	defer m.lock()()
+
+	delete(m.data.webhookEndpoints, id)
+	maps.DeleteFunc(m.data.webhookDeliveries, func(_ uuid.UUID, delivery database.WebhookDelivery) bool {
+		return delivery.EndpointID == id
+	})
+	return nil
+}
+
+func (m *Memory) EnqueueWebhookDeliveries(ctx context.Context, arg database.EnqueueWebhookDeliveriesParams) (int64, error) {
+	defer m.lock()()
+
+	var enqueued int64
+	for _, endpoint := range m.data.webhookEndpoints {
+		if !slices.Contains(strings.Split(endpoint.Events, ","), arg.EventType) {
+			continue
+		}
+		if endpoint.UserID.Valid && !slices.Contains(arg.UserIds, endpoint.UserID.UUID) {
+			continue
+		}
+
+		delivery := database.WebhookDelivery{
+			ID:            uuid.New(),
+			EndpointID:    endpoint.ID,
+			EventType:     arg.EventType,
+			Payload:       arg.Payload,
+			Status:        "pending",
+			NextAttemptAt: now(),
+			CreatedAt:     now(),
+		}
+		m.data.webhookDeliveries[delivery.ID] = delivery
+		enqueued++
+	}
+	return enqueued, nil
+}
+
+func (m *Memory) ClaimDueWebhookDeliveries(ctx context.Context, arg database.ClaimDueWebhookDeliveriesParams) ([]database.ClaimDueWebhookDeliveriesRow, error) {
+	defer m.lock()()
+
+	current := now()
+	due := make([]database.WebhookDelivery, 0)
+	for _, delivery := range m.data.webhookDeliveries {
+		if delivery.Status == "pending" && !delivery.NextAttemptAt.After(current) {
+			due = append(due, delivery)
+		}
+	}
+
+	slices.SortFunc(due, func(a, b database.WebhookDelivery) int {
+		return compareKeys(a.NextAttemptAt, a.ID, b.NextAttemptAt, b.ID)
+	})
+	due = limit(due, arg.BatchSize)
+
+	rows := make([]database.ClaimDueWebhookDeliveriesRow, 0, len(due))
+	for _, delivery := range due {
+		delivery.NextAttemptAt = timestamp(arg.LeaseUntil)
+		m.data.webhookDeliveries[delivery.ID] = delivery
+
+		endpoint := m.data.webhookEndpoints[delivery.EndpointID]
+		rows = append(rows, database.ClaimDueWebhookDeliveriesRow{
+			ID:             delivery.ID,
+			EndpointID:     delivery.EndpointID,
+			EventType:      delivery.EventType,
+			Payload:        delivery.Payload,
+			Status:         delivery.Status,
+			Attempts:       delivery.Attempts,
+			NextAttemptAt:  delivery.NextAttemptAt,
+			LastStatusCode: delivery.LastStatusCode,
+			LastError:      delivery.LastError,
+			CreatedAt:      delivery.CreatedAt,
+			DeliveredAt:    delivery.DeliveredAt,
+			Url:            endpoint.Url,
+			Secret:         endpoint.Secret,
+		})
+	}
+	return rows, nil
+}
+
+func (m *Memory) RecordWebhookDeliveryAttempt(ctx context.Context, arg database.RecordWebhookDeliveryAttemptParams) error {
+	defer m.lock()()
+
+	delivery, ok := m.data.webhookDeliveries[arg.ID]
+	if !ok {
+		return nil
+	}
+
+	delivery.Status = arg.Status
+	delivery.Attempts++
+	delivery.NextAttemptAt = timestamp(arg.NextAttemptAt)
+	delivery.LastStatusCode = arg.LastStatusCode
+	delivery.LastError = arg.LastError
+	delivery.DeliveredAt = nullTimestamp(arg.DeliveredAt)
+	m.data.webhookDeliveries[arg.ID] = delivery
+	return nil
+}
+
+func (m *Memory) GetWebhookDeliveries(ctx context.Context, endpointID uuid.UUID) ([]database.WebhookDelivery, error) {
+	defer m.lock()()
+
+	rows := make([]database.WebhookDelivery, 0)
+	for _, delivery := range m.data.webhookDeliveries {
+		if delivery.EndpointID == endpointID {
+			rows = append(rows, delivery)
+		}
+	}
+
+	slices.SortFunc(rows, func(a, b database.WebhookDelivery) int {
+		return compareKeys(b.CreatedAt, b.ID, a.CreatedAt, a.ID)
+	})
+	return limit(rows, 100), nil
+}
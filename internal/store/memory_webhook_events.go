@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func (m *Memory) CreateWebhookEvent(ctx context.Context, arg database.CreateWebhookEventParams) (int64, error) {
+	defer m.lock()()
+
+	if _, ok := m.data.webhookEvents[arg.ID]; ok {
+		return 0, nil
+	}
+
+	m.data.webhookEvents[arg.ID] = database.WebhookEvent{
+		ID:         arg.ID,
+		EventType:  arg.EventType,
+		Payload:    arg.Payload,
+		Status:     "received",
+		ReceivedAt: now(),
+	}
+	return 1, nil
+}
+
+func (m *Memory) GetWebhookEvent(ctx context.Context, id string) (database.WebhookEvent, error) {
+	defer m.lock()()
+
+	event, ok := m.data.webhookEvents[id]
+	if !ok {
+		return database.WebhookEvent{}, sql.ErrNoRows
+	}
+	return event, nil
+}
+
+func (m *Memory) GetWebhookEventsByStatus(ctx context.Context, status string) ([]database.WebhookEvent, error) {
+	defer m.lock()()
+
+	rows := make([]database.WebhookEvent, 0)
+	for _, event := range m.data.webhookEvents {
+		if event.Status == status {
+			rows = append(rows, event)
+		}
+	}
+
+	slices.SortFunc(rows, func(a, b database.WebhookEvent) int {
+		if c := a.ReceivedAt.Compare(b.ReceivedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return rows, nil
+}
+
+func (m *Memory) UpdateWebhookEventStatus(ctx context.Context, arg database.UpdateWebhookEventStatusParams) error {
+	defer m.lock()()
+
+	event, ok := m.data.webhookEvents[arg.ID]
+	if !ok {
+		return nil
+	}
+
+	event.Status = arg.Status
+	event.LastError = arg.LastError
+	event.Attempts++
+	event.ProcessedAt = nullNow()
+	m.data.webhookEvents[arg.ID] = event
+	return nil
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Backend is the kind of database a Store runs on.
+type Backend string
+
+const (
+	BackendPostgres Backend = "postgres"
+	BackendSQLite   Backend = "sqlite"
+)
+
+// Open connects to the database dbURL names, picking the backend by its
+// scheme: postgres:// or postgresql:// for Postgres, sqlite: for a SQLite
+// file, as in sqlite:chirpy.db or sqlite:///var/lib/chirpy/chirpy.db. A
+// Postgres connection string without a scheme goes to Postgres too.
+func Open(dbURL string) (Backend, *sql.DB, error) {
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		// Key/value connection strings don't parse as URLs
+		u = &url.URL{}
+	}
+
+	switch u.Scheme {
+	case "", "postgres", "postgresql":
+		db, err := sql.Open("postgres", dbURL)
+		return BackendPostgres, db, err
+	case "sqlite", "sqlite3":
+		path := u.Opaque
+		if path == "" {
+			path = u.Host + u.Path
+		}
+		if path == "" {
+			return "", nil, fmt.Errorf("DB_URL %q doesn't name a SQLite file", dbURL)
+		}
+
+		db, err := sql.Open(sqliteDriver, sqliteDSN(path, u.Query()))
+		return BackendSQLite, db, err
+	default:
+		return "", nil, fmt.Errorf("unsupported DB_URL scheme %q, use postgres or sqlite", u.Scheme)
+	}
+}
+
+// sqliteDSN adds the connection settings the queries rely on to the ones
+// given in DB_URL.
+func sqliteDSN(path string, params url.Values) string {
+	defaults := map[string]string{
+		// Cascades and SET NULL depend on foreign keys, which SQLite
+		// leaves off unless asked
+		"_foreign_keys": "on",
+		// Transactions take the write lock up front, so one that started
+		// out reading can't fail on upgrading it later
+		"_txlock":       "immediate",
+		"_busy_timeout": "5000",
+		"_journal_mode": "WAL",
+	}
+	for key, value := range defaults {
+		if !params.Has(key) {
+			params.Set(key, value)
+		}
+	}
+
+	return "file:" + strings.TrimPrefix(path, "file:") + "?" + params.Encode()
+}
+
+// New returns the Store for a connection Open made.
+func New(backend Backend, db *sql.DB) Store {
+	if backend == BackendSQLite {
+		return NewSQLite(db)
+	}
+	return NewPostgres(db)
+}
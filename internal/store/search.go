@@ -0,0 +1,72 @@
+package store
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// searchTerms approximates websearch_to_tsquery for the stores without
+// Postgres full text search: words must all appear, words prefixed with "-"
+// must not. Words are matched on a crude stem, not on Postgres' English
+// dictionary.
+func searchTerms(query string) (include, exclude []string) {
+	for _, field := range strings.Fields(strings.ToLower(query)) {
+		negated := strings.HasPrefix(field, "-")
+		for _, word := range searchWords(field) {
+			if negated {
+				exclude = append(exclude, word)
+			} else {
+				include = append(include, word)
+			}
+		}
+	}
+	return include, exclude
+}
+
+func searchWords(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = stem(word)
+	}
+	return words
+}
+
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if trimmed, ok := strings.CutSuffix(word, suffix); ok && len(trimmed) >= 3 {
+			return trimmed
+		}
+	}
+	return word
+}
+
+// searchRank reports whether body matches the query and how well, as the
+// share of its words that are search terms.
+func searchRank(body string, include, exclude []string) (float32, bool) {
+	if len(include) == 0 {
+		return 0, false
+	}
+
+	words := searchWords(body)
+	for _, word := range exclude {
+		if slices.Contains(words, word) {
+			return 0, false
+		}
+	}
+
+	hits := 0
+	for _, word := range include {
+		if !slices.Contains(words, word) {
+			return 0, false
+		}
+	}
+	for _, word := range words {
+		if slices.Contains(include, word) {
+			hits++
+		}
+	}
+	return float32(hits) / float32(len(words)), true
+}
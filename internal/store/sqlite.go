@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+// sqliteDriver is SQLite with the functions the query variants call.
+const sqliteDriver = "chirpy-sqlite3"
+
+// sqliteTimeFormat is how timestamps are stored: UTC with a fixed number of
+// digits, so comparing the text compares the times.
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000+00:00"
+
+//go:embed sqlite/*.sql
+var sqliteQueryFiles embed.FS
+
+// sqliteQueries holds the SQLite variant of every sqlc query, by name.
+var sqliteQueries = loadSQLiteQueries()
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("chirpy_search_rank", sqliteSearchRank, true)
+		},
+	})
+}
+
+func loadSQLiteQueries() map[string]string {
+	files, err := sqliteQueryFiles.ReadDir("sqlite")
+	if err != nil {
+		panic(err)
+	}
+
+	queries := map[string]string{}
+	for _, file := range files {
+		src, err := sqliteQueryFiles.ReadFile("sqlite/" + file.Name())
+		if err != nil {
+			panic(err)
+		}
+
+		for _, query := range strings.Split(string(src), "-- name: ")[1:] {
+			queries[strings.Fields(query)[0]] = "-- name: " + strings.TrimSpace(query)
+		}
+	}
+	return queries
+}
+
+// sqliteSearchRank is chirpy_search_rank(body, query), the rank of a chirp
+// matching the search or NULL when it doesn't match.
+func sqliteSearchRank(body, query string) interface{} {
+	include, exclude := searchTerms(query)
+	rank, match := searchRank(body, include, exclude)
+	if !match {
+		return nil
+	}
+	return float64(rank)
+}
+
+// SQLite is a Store on a SQLite file. It runs the code sqlc generated for
+// Postgres, with every query swapped for its variant from sqlite/.
+type SQLite struct {
+	*database.Queries
+	db *sql.DB
+}
+
+func NewSQLite(db *sql.DB) *SQLite {
+	return &SQLite{
+		Queries: database.New(sqliteConn{db}),
+		db:      db,
+	}
+}
+
+func (s *SQLite) BeginTx(ctx context.Context) (Tx, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlTx{
+		Queries: database.New(sqliteConn{tx}),
+		tx:      tx,
+	}, nil
+}
+
+func (s *SQLite) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+// sqliteConn hands the generated code's queries to SQLite, translated.
+type sqliteConn struct {
+	db database.DBTX
+}
+
+func (c sqliteConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query, args = sqliteStatement(query, args)
+	return c.db.ExecContext(ctx, query, args...)
+}
+
+// PrepareContext can't supply NOW(), the queries that use it have to run
+// through the other methods.
+func (c sqliteConn) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.db.PrepareContext(ctx, sqliteQuery(query))
+}
+
+func (c sqliteConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	query, args = sqliteStatement(query, args)
+	return c.db.QueryContext(ctx, query, args...)
+}
+
+func (c sqliteConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	query, args = sqliteStatement(query, args)
+	return c.db.QueryRowContext(ctx, query, args...)
+}
+
+// sqliteStatement translates a generated query and its arguments. NOW() in
+// a variant becomes one more parameter with the current time, as SQLite's
+// clock only has milliseconds and Postgres' NOW() doesn't change during a
+// statement.
+func sqliteStatement(query string, args []interface{}) (string, []interface{}) {
+	query = sqliteQuery(query)
+	args = sqliteArgs(args)
+
+	if strings.Contains(query, "NOW()") {
+		args = append(args, time.Now().UTC().Format(sqliteTimeFormat))
+		query = strings.ReplaceAll(query, "NOW()", "?"+strconv.Itoa(len(args)))
+	}
+	return query, args
+}
+
+// sqliteQuery looks up the variant of a generated query by the name in its
+// first line. A query without one is passed on as it is, and most likely
+// fails on the Postgres syntax.
+func sqliteQuery(query string) string {
+	header, _, _ := strings.Cut(query, "\n")
+	if fields := strings.Fields(strings.TrimPrefix(header, "-- name: ")); len(fields) > 0 {
+		if variant, ok := sqliteQueries[fields[0]]; ok {
+			return variant
+		}
+	}
+	return query
+}
+
+func sqliteArgs(args []interface{}) []interface{} {
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			arg = v.UTC().Format(sqliteTimeFormat)
+		case sql.NullTime:
+			arg = nil
+			if v.Valid {
+				arg = v.Time.UTC().Format(sqliteTimeFormat)
+			}
+		case pq.GenericArray:
+			// Arrays go in as JSON, which the queries unpack with json_each
+			if encoded, err := json.Marshal(v.A); err == nil {
+				arg = string(encoded)
+			}
+		}
+		converted[i] = arg
+	}
+	return converted
+}
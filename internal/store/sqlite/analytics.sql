@@ -0,0 +1,16 @@
+-- name: IncrementChirpViews :exec
+INSERT INTO chirp_views (chirp_id, views)
+VALUES (
+    ?1,
+    1
+)
+ON CONFLICT (chirp_id) DO UPDATE SET views = chirp_views.views + 1;
+
+-- name: GetChirpAnalytics :one
+SELECT
+    COALESCE((SELECT views FROM chirp_views WHERE chirp_views.chirp_id = chirps.id), 0) AS views,
+    (SELECT count(*) FROM chirp_likes WHERE chirp_likes.chirp_id = chirps.id) AS like_count,
+    (SELECT count(*) FROM rechirps WHERE rechirps.chirp_id = chirps.id) AS rechirp_count,
+    (SELECT count(*) FROM chirps AS replies WHERE replies.parent_id = chirps.id) AS reply_count
+FROM chirps
+WHERE chirps.id = ?1;
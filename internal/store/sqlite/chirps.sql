@@ -0,0 +1,80 @@
+-- name: CreateChirp :one
+INSERT INTO chirps (created_at, updated_at, body, user_id, parent_id, root_id)
+VALUES (
+    NOW(),
+    NOW(),
+    ?1,
+    ?2,
+    ?3,
+    ?4
+)
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at;
+
+-- name: DropChirps :exec
+DELETE FROM chirps;
+
+-- name: GetChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
+WHERE deleted_at IS NULL
+    AND (?1 IS NULL OR (created_at, id) > (?1, ?2))
+ORDER BY created_at ASC, id ASC
+LIMIT ?3;
+
+-- name: GetChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
+WHERE deleted_at IS NULL
+    AND (?1 IS NULL OR (created_at, id) < (?1, ?2))
+ORDER BY created_at DESC, id DESC
+LIMIT ?3;
+
+-- name: GetChirpsByAuthorAsc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, feed_at, rechirped_by FROM (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, chirps.created_at AS feed_at, NULL AS rechirped_by FROM chirps
+    WHERE chirps.user_id = ?1
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, rechirps.created_at AS feed_at, rechirps.user_id AS rechirped_by FROM rechirps
+    JOIN chirps ON chirps.id = rechirps.chirp_id
+    WHERE rechirps.user_id = ?1
+) AS feed
+WHERE deleted_at IS NULL
+    AND (?2 IS NULL OR (feed_at, id) > (?2, ?3))
+ORDER BY feed_at ASC, id ASC
+LIMIT ?4;
+
+-- name: GetChirpsByAuthorDesc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, feed_at, rechirped_by FROM (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, chirps.created_at AS feed_at, NULL AS rechirped_by FROM chirps
+    WHERE chirps.user_id = ?1
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, rechirps.created_at AS feed_at, rechirps.user_id AS rechirped_by FROM rechirps
+    JOIN chirps ON chirps.id = rechirps.chirp_id
+    WHERE rechirps.user_id = ?1
+) AS feed
+WHERE deleted_at IS NULL
+    AND (?2 IS NULL OR (feed_at, id) < (?2, ?3))
+ORDER BY feed_at DESC, id DESC
+LIMIT ?4;
+
+-- name: GetChirp :one
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
+WHERE id = ?1;
+
+-- name: DeleteChirp :exec
+DELETE FROM chirps WHERE id = ?1;
+
+-- name: DeleteChirpWithoutReplies :execrows
+DELETE FROM chirps WHERE id = ?1
+    AND NOT EXISTS (SELECT 1 FROM chirps AS replies WHERE replies.parent_id = ?1);
+
+-- name: UpdateChirpBody :one
+UPDATE chirps SET body = ?2, updated_at = NOW() WHERE id = ?1
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at;
+
+-- name: TombstoneChirp :one
+UPDATE chirps SET body = '', deleted_at = NOW(), updated_at = NOW() WHERE id = ?1
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at;
+
+-- name: GetThread :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
+WHERE id = ?1 OR root_id = ?1
+ORDER BY created_at ASC, id ASC;
@@ -0,0 +1,21 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens (token_hash, user_id, email, created_at, expires_at)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    NOW(),
+    ?4
+);
+
+-- name: UseEmailVerificationToken :one
+DELETE FROM email_verification_tokens
+WHERE token_hash = ?1 AND expires_at > NOW()
+RETURNING user_id, email;
+
+-- name: DeleteEmailVerificationTokens :exec
+DELETE FROM email_verification_tokens WHERE user_id = ?1;
+
+-- name: VerifyUserEmail :one
+UPDATE users SET email = ?2, email_verified_at = NOW(), updated_at = NOW() WHERE id = ?1
+RETURNING *;
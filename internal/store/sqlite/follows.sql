@@ -0,0 +1,41 @@
+-- name: CreateFollow :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+    ?1,
+    ?2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteFollow :exec
+DELETE FROM follows WHERE follower_id = ?1 AND followee_id = ?2;
+
+-- name: GetFollowers :many
+SELECT follower_id AS id, created_at AS followed_at FROM follows
+WHERE followee_id = ?1
+    AND (?2 IS NULL OR (created_at, follower_id) < (?2, ?3))
+ORDER BY created_at DESC, follower_id DESC
+LIMIT ?4;
+
+-- name: GetFollowing :many
+SELECT followee_id AS id, created_at AS followed_at FROM follows
+WHERE follower_id = ?1
+    AND (?2 IS NULL OR (created_at, followee_id) < (?2, ?3))
+ORDER BY created_at DESC, followee_id DESC
+LIMIT ?4;
+
+-- name: GetTimelineAsc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
+WHERE user_id IN (SELECT followee_id FROM follows WHERE follower_id = ?1)
+    AND deleted_at IS NULL
+    AND (?2 IS NULL OR (created_at, id) > (?2, ?3))
+ORDER BY created_at ASC, id ASC
+LIMIT ?4;
+
+-- name: GetTimelineDesc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
+WHERE user_id IN (SELECT followee_id FROM follows WHERE follower_id = ?1)
+    AND deleted_at IS NULL
+    AND (?2 IS NULL OR (created_at, id) < (?2, ?3))
+ORDER BY created_at DESC, id DESC
+LIMIT ?4;
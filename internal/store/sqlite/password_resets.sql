@@ -0,0 +1,16 @@
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (token_hash, user_id, created_at, expires_at)
+VALUES (
+    ?1,
+    ?2,
+    NOW(),
+    ?3
+);
+
+-- name: UsePasswordResetToken :one
+DELETE FROM password_reset_tokens
+WHERE token_hash = ?1 AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: DeletePasswordResetTokens :exec
+DELETE FROM password_reset_tokens WHERE user_id = ?1;
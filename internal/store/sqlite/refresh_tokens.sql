@@ -0,0 +1,62 @@
+-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, user_agent, ip_address, last_used_at)
+VALUES (
+    ?1,
+    NOW(),
+    NOW(),
+    ?2,
+    ?3,
+    ?4,
+    ?5,
+    ?6,
+    ?7,
+    NOW()
+)
+RETURNING *;
+
+-- name: DropRefreshTokens :exec
+DELETE FROM refresh_tokens;
+
+-- name: GetRefreshToken :one
+SELECT * FROM refresh_tokens
+WHERE token = ?1;
+
+-- name: UpdateRefreshToken :one
+UPDATE refresh_tokens SET revoked_at = ?1 WHERE token = ?2
+RETURNING *;
+
+-- name: RevokeRefreshToken :execrows
+UPDATE refresh_tokens SET revoked_at = NOW(), updated_at = NOW()
+WHERE token = ?1 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens SET revoked_at = NOW(), updated_at = NOW()
+WHERE family_id = ?1 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokenFamily :execrows
+UPDATE refresh_tokens SET revoked_at = NOW(), updated_at = NOW()
+WHERE family_id = ?1 AND user_id = ?2 AND revoked_at IS NULL;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = ?1 AND revoked_at IS NULL;
+
+-- name: GetUserSessions :many
+-- signed_in_at is a bare column next to min(), which SQLite takes from the
+-- row with the minimum. Unlike min() itself it keeps the timestamp type.
+SELECT
+    refresh_tokens.family_id,
+    refresh_tokens.user_agent,
+    refresh_tokens.ip_address,
+    refresh_tokens.last_used_at,
+    refresh_tokens.expires_at,
+    family.signed_in_at
+FROM refresh_tokens
+JOIN (
+    SELECT family_id, min(created_at), created_at AS signed_in_at FROM refresh_tokens
+    GROUP BY family_id
+) AS family ON family.family_id = refresh_tokens.family_id
+WHERE refresh_tokens.user_id = ?1
+    AND refresh_tokens.revoked_at IS NULL
+    AND refresh_tokens.expires_at > NOW()
+ORDER BY refresh_tokens.last_used_at DESC;
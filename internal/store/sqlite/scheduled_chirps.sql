@@ -0,0 +1,22 @@
+-- name: CreateScheduledChirp :one
+INSERT INTO scheduled_chirps (user_id, body, matched_words, publish_at, created_at)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetScheduledChirps :many
+SELECT * FROM scheduled_chirps
+WHERE user_id = ?1
+ORDER BY publish_at ASC, id ASC;
+
+-- name: DeleteScheduledChirp :execrows
+DELETE FROM scheduled_chirps WHERE id = ?1 AND user_id = ?2;
+
+-- name: ClaimDueScheduledChirps :many
+DELETE FROM scheduled_chirps WHERE publish_at <= NOW()
+RETURNING *;
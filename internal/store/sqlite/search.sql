@@ -0,0 +1,20 @@
+-- name: SearchChirpsByRecency :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
+WHERE chirpy_search_rank(body, ?1) IS NOT NULL
+    AND deleted_at IS NULL
+    AND (?2 IS NULL OR user_id = ?2)
+    AND (?3 IS NULL OR (created_at, id) < (?3, ?4))
+ORDER BY created_at DESC, id DESC
+LIMIT ?5;
+
+-- name: SearchChirpsByRelevance :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, rank FROM (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, chirpy_search_rank(chirps.body, ?1) AS rank
+    FROM chirps
+    WHERE chirps.deleted_at IS NULL
+        AND (?2 IS NULL OR chirps.user_id = ?2)
+) AS results
+WHERE rank IS NOT NULL
+    AND (?3 IS NULL OR (rank, id) < (?3, ?4))
+ORDER BY rank DESC, id DESC
+LIMIT ?5;
@@ -0,0 +1,6 @@
+-- name: GetSiteSettings :one
+SELECT * FROM site_settings;
+
+-- name: UpdateSiteSettings :one
+UPDATE site_settings SET allow_unverified_chirps = ?1
+RETURNING *;
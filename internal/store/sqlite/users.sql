@@ -0,0 +1,27 @@
+-- name: CreateUser :one
+INSERT INTO users (created_at, updated_at, email, hashed_password)
+VALUES (
+    NOW(),
+    NOW(),
+    ?1,
+    ?2
+)
+RETURNING *;
+
+-- name: DropUsers :exec
+DELETE FROM users;
+
+-- name: GetUser :one
+SELECT * FROM users
+WHERE id = ?1;
+
+-- name: GetUserByEmail :one
+SELECT id, created_at, updated_at, email, hashed_password, email_verified_at FROM users
+WHERE email = ?1;
+
+-- name: UpdateUser :one
+UPDATE users set email = ?1, hashed_password = ?2 WHERE id = ?3
+RETURNING *;
+
+-- name: UpdateUserPassword :exec
+UPDATE users SET hashed_password = ?2, updated_at = NOW() WHERE id = ?1;
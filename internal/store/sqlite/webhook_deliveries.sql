@@ -0,0 +1,53 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (user_id, url, secret, events, created_at)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    ?4,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetWebhookEndpoint :one
+SELECT * FROM webhook_endpoints
+WHERE id = ?1;
+
+-- name: GetWebhookEndpoints :many
+SELECT * FROM webhook_endpoints
+WHERE user_id IS ?1
+ORDER BY created_at ASC;
+
+-- name: DeleteWebhookEndpoint :exec
+DELETE FROM webhook_endpoints WHERE id = ?1;
+
+-- name: EnqueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (endpoint_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at)
+SELECT webhook_endpoints.id, ?1, ?2, 'pending', 0, NOW(), 0, '', NOW(), NULL
+FROM webhook_endpoints
+WHERE instr(',' || webhook_endpoints.events || ',', ',' || ?1 || ',') > 0
+    AND (webhook_endpoints.user_id IS NULL OR webhook_endpoints.user_id IN (SELECT value FROM json_each(?3)));
+
+-- name: ClaimDueWebhookDeliveries :many
+-- Writers take turns in SQLite, so there's no need to skip locked rows.
+-- RETURNING can't see the joined endpoint, hence the subqueries.
+UPDATE webhook_deliveries SET next_attempt_at = ?1
+WHERE id IN (
+    SELECT id FROM webhook_deliveries AS due
+    WHERE due.status = 'pending' AND due.next_attempt_at <= NOW()
+    ORDER BY due.next_attempt_at
+    LIMIT ?2
+)
+RETURNING *,
+    (SELECT url FROM webhook_endpoints WHERE webhook_endpoints.id = webhook_deliveries.endpoint_id) AS url,
+    (SELECT secret FROM webhook_endpoints WHERE webhook_endpoints.id = webhook_deliveries.endpoint_id) AS secret;
+
+-- name: RecordWebhookDeliveryAttempt :exec
+UPDATE webhook_deliveries SET status = ?2, attempts = attempts + 1, next_attempt_at = ?3, last_status_code = ?4, last_error = ?5, delivered_at = ?6
+WHERE id = ?1;
+
+-- name: GetWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id = ?1
+ORDER BY created_at DESC, id DESC
+LIMIT 100;
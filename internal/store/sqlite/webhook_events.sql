@@ -0,0 +1,26 @@
+-- name: CreateWebhookEvent :execrows
+INSERT INTO webhook_events (id, event_type, payload, status, last_error, attempts, received_at, processed_at)
+VALUES (
+    ?1,
+    ?2,
+    ?3,
+    'received',
+    '',
+    0,
+    NOW(),
+    NULL
+)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: GetWebhookEvent :one
+SELECT * FROM webhook_events
+WHERE id = ?1;
+
+-- name: GetWebhookEventsByStatus :many
+SELECT * FROM webhook_events
+WHERE status = ?1
+ORDER BY received_at;
+
+-- name: UpdateWebhookEventStatus :exec
+UPDATE webhook_events SET status = ?2, last_error = ?3, attempts = attempts + 1, processed_at = NOW()
+WHERE id = ?1;
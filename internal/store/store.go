@@ -0,0 +1,100 @@
+// Package store is the storage the API runs on. Handlers only see the Store
+// interface, so they run the same against Postgres, SQLite and the in-memory
+// store the tests use.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+// Store is every query the API runs, plus transactions.
+type Store interface {
+	database.Querier
+
+	// BeginTx starts a transaction. Rolling back after a commit is a no-op,
+	// so callers can always defer Rollback.
+	BeginTx(ctx context.Context) (Tx, error)
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// Tx runs queries inside a transaction.
+type Tx interface {
+	database.Querier
+
+	Commit() error
+	Rollback() error
+}
+
+// IsUniqueViolation reports whether err is a write that a unique constraint
+// rejected, whichever backend it came from.
+func IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	var sqliteErr sqlite3.Error
+	switch {
+	case errors.Is(err, ErrUniqueViolation):
+		return true
+	case errors.As(err, &pqErr):
+		return pqErr.Code == "23505"
+	case errors.As(err, &sqliteErr):
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	return false
+}
+
+// Postgres is a Store backed by the sqlc queries.
+type Postgres struct {
+	*database.Queries
+	db *sql.DB
+}
+
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{
+		Queries: database.New(db),
+		db:      db,
+	}
+}
+
+func (p *Postgres) BeginTx(ctx context.Context) (Tx, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlTx{
+		Queries: p.Queries.WithTx(tx),
+		tx:      tx,
+	}, nil
+}
+
+func (p *Postgres) Ping(ctx context.Context) error {
+	return p.db.PingContext(ctx)
+}
+
+func (p *Postgres) Close() error {
+	return p.db.Close()
+}
+
+// sqlTx is a transaction on either SQL backend.
+type sqlTx struct {
+	*database.Queries
+	tx *sql.Tx
+}
+
+func (t *sqlTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *sqlTx) Rollback() error {
+	err := t.tx.Rollback()
+	if err == sql.ErrTxDone {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,593 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pressly/goose/v3"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+// eachStore runs the test against every backend: the in-memory store, a new
+// SQLite database, and Postgres when CHIRPY_TEST_POSTGRES_URL points at a
+// database the test may wipe.
+func eachStore(t *testing.T, test func(t *testing.T, db Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemory())
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, openTestStore(t, "sqlite:"+filepath.Join(t.TempDir(), "chirpy.db"), goose.DialectSQLite3, "../../sql/sqlite/schema"))
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		dbURL := os.Getenv("CHIRPY_TEST_POSTGRES_URL")
+		if dbURL == "" {
+			t.Skip("CHIRPY_TEST_POSTGRES_URL isn't set")
+		}
+		test(t, openTestStore(t, dbURL, goose.DialectPostgres, "../../sql/schema"))
+	})
+}
+
+// openTestStore opens the database and migrates it from scratch.
+func openTestStore(t *testing.T, dbURL string, dialect goose.Dialect, schema string) Store {
+	t.Helper()
+
+	backend, db, err := Open(dbURL)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := goose.NewProvider(dialect, db, os.DirFS(schema))
+	if err != nil {
+		t.Fatalf("goose.NewProvider() error = %v", err)
+	}
+	ctx := context.Background()
+	if _, err := migrations.DownTo(ctx, 0); err != nil {
+		t.Fatalf("migrating down: %v", err)
+	}
+	if _, err := migrations.Up(ctx); err != nil {
+		t.Fatalf("migrating up: %v", err)
+	}
+
+	return New(backend, db)
+}
+
+func createUser(t *testing.T, db database.Querier, email string) database.User {
+	t.Helper()
+
+	user, err := db.CreateUser(context.Background(), database.CreateUserParams{Email: email, HashedPassword: "hash"})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	return user
+}
+
+func createChirp(t *testing.T, db database.Querier, arg database.CreateChirpParams) database.CreateChirpRow {
+	t.Helper()
+
+	chirp, err := db.CreateChirp(context.Background(), arg)
+	if err != nil {
+		t.Fatalf("CreateChirp() error = %v", err)
+	}
+	return chirp
+}
+
+func TestSQLiteQueryVariants(t *testing.T) {
+	querier := reflect.TypeOf((*database.Querier)(nil)).Elem()
+	for i := 0; i < querier.NumMethod(); i++ {
+		if name := querier.Method(i).Name; sqliteQueries[name] == "" {
+			t.Errorf("query %s has no SQLite variant", name)
+		}
+	}
+}
+
+func TestMemoryTimestamps(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemory()
+	user := createUser(t, db, "walt@example.com")
+
+	// Like a Postgres timestamp column, the offset is dropped and the wall
+	// clock read back as UTC
+	expiresAt := time.Date(2024, 3, 1, 9, 0, 0, 0, time.FixedZone("UTC-7", -7*60*60))
+	err := db.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{TokenHash: "hash", UserID: user.ID, ExpiresAt: expiresAt})
+	if err != nil {
+		t.Fatalf("CreatePasswordResetToken() error = %v", err)
+	}
+
+	want := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	if got := db.data.passwordResets["hash"].ExpiresAt; !got.Equal(want) {
+		t.Errorf("stored expires_at = %v, want %v", got, want)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		dbURL   string
+		want    Backend
+		wantErr bool
+	}{
+		{"postgres://chirpy@localhost:5432/chirpy?sslmode=disable", BackendPostgres, false},
+		{"postgresql://localhost/chirpy", BackendPostgres, false},
+		{"host=localhost dbname=chirpy", BackendPostgres, false},
+		{"sqlite:chirpy.db", BackendSQLite, false},
+		{"sqlite:///var/lib/chirpy/chirpy.db", BackendSQLite, false},
+		{"sqlite:", "", true},
+		{"mysql://localhost/chirpy", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dbURL, func(t *testing.T) {
+			backend, db, err := Open(tt.dbURL)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if db != nil {
+				db.Close()
+			}
+			if backend != tt.want {
+				t.Errorf("Open() backend = %q, want %q", backend, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	eachStore(t, func(t *testing.T, db Store) {
+		ctx := context.Background()
+
+		tests := []struct {
+			name   string
+			commit bool
+			want   error
+		}{
+			{"commit keeps the changes", true, nil},
+			{"rollback discards them", false, sql.ErrNoRows},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tx, err := db.BeginTx(ctx)
+				if err != nil {
+					t.Fatalf("BeginTx() error = %v", err)
+				}
+
+				user := createUser(t, tx, uuid.NewString()+"@example.com")
+
+				if tt.commit {
+					err = tx.Commit()
+				} else {
+					err = tx.Rollback()
+				}
+				if err != nil {
+					t.Fatalf("finishing the transaction: %v", err)
+				}
+
+				// Rolling back after the transaction is done is harmless
+				if err := tx.Rollback(); err != nil {
+					t.Errorf("Rollback() after finishing error = %v", err)
+				}
+
+				if _, err := db.GetUserByEmail(ctx, user.Email); !errors.Is(err, tt.want) {
+					t.Errorf("GetUserByEmail() error = %v, want %v", err, tt.want)
+				}
+			})
+		}
+	})
+}
+
+func TestConstraints(t *testing.T) {
+	eachStore(t, func(t *testing.T, db Store) {
+		ctx := context.Background()
+		user := createUser(t, db, "walt@example.com")
+
+		if _, err := db.CreateUser(ctx, database.CreateUserParams{Email: "walt@example.com", HashedPassword: "hash"}); !IsUniqueViolation(err) {
+			t.Errorf("CreateUser() with a taken email error = %v, want a unique violation", err)
+		}
+
+		if _, err := db.CreateChirp(ctx, database.CreateChirpParams{Body: "Hi", UserID: uuid.New()}); err == nil {
+			t.Error("CreateChirp() for an unknown user succeeded")
+		}
+
+		parent := createChirp(t, db, database.CreateChirpParams{Body: "Parent", UserID: user.ID})
+		parentID := uuid.NullUUID{UUID: parent.ID, Valid: true}
+		reply := createChirp(t, db, database.CreateChirpParams{Body: "Reply", UserID: user.ID, ParentID: parentID, RootID: parentID})
+
+		if err := db.CreateChirpLike(ctx, database.CreateChirpLikeParams{UserID: user.ID, ChirpID: parent.ID}); err != nil {
+			t.Fatalf("CreateChirpLike() error = %v", err)
+		}
+
+		if err := db.DeleteChirp(ctx, parent.ID); err != nil {
+			t.Fatalf("DeleteChirp() error = %v", err)
+		}
+
+		// Replies outlive their parent, detached from it
+		got, err := db.GetChirp(ctx, reply.ID)
+		if err != nil {
+			t.Fatalf("GetChirp() error = %v", err)
+		}
+		if got.ParentID.Valid || got.RootID.Valid {
+			t.Errorf("reply still points at %v / %v", got.ParentID, got.RootID)
+		}
+
+		// Everything else goes with the user
+		if err := db.DropUsers(ctx); err != nil {
+			t.Fatalf("DropUsers() error = %v", err)
+		}
+		if _, err := db.GetChirp(ctx, reply.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetChirp() after dropping users error = %v, want %v", err, sql.ErrNoRows)
+		}
+	})
+}
+
+func TestDeleteChirpWithoutReplies(t *testing.T) {
+	eachStore(t, func(t *testing.T, db Store) {
+		ctx := context.Background()
+		user := createUser(t, db, "walt@example.com")
+
+		parent := createChirp(t, db, database.CreateChirpParams{Body: "Parent", UserID: user.ID})
+		parentID := uuid.NullUUID{UUID: parent.ID, Valid: true}
+		reply := createChirp(t, db, database.CreateChirpParams{Body: "Reply", UserID: user.ID, ParentID: parentID, RootID: parentID})
+
+		if n, err := db.DeleteChirpWithoutReplies(ctx, parent.ID); err != nil || n != 0 {
+			t.Fatalf("DeleteChirpWithoutReplies() for a chirp with replies = %d, %v, want 0", n, err)
+		}
+		if _, err := db.GetChirp(ctx, parent.ID); err != nil {
+			t.Errorf("GetChirp() after a skipped delete error = %v", err)
+		}
+
+		if n, err := db.DeleteChirpWithoutReplies(ctx, reply.ID); err != nil || n != 1 {
+			t.Fatalf("DeleteChirpWithoutReplies() for a reply = %d, %v, want 1", n, err)
+		}
+		if _, err := db.GetChirp(ctx, reply.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetChirp() after deleting error = %v, want %v", err, sql.ErrNoRows)
+		}
+	})
+}
+
+func TestChirpPagination(t *testing.T) {
+	eachStore(t, func(t *testing.T, db Store) {
+		ctx := context.Background()
+		user := createUser(t, db, "walt@example.com")
+
+		created := make([]database.CreateChirpRow, 0)
+		for _, body := range []string{"one", "two", "three"} {
+			created = append(created, createChirp(t, db, database.CreateChirpParams{Body: body, UserID: user.ID}))
+		}
+
+		first, err := db.GetChirpsAsc(ctx, database.GetChirpsAscParams{PageLimit: 2})
+		if err != nil {
+			t.Fatalf("GetChirpsAsc() error = %v", err)
+		}
+		if len(first) != 2 || first[0].ID != created[0].ID || first[1].ID != created[1].ID {
+			t.Fatalf("first page = %v", first)
+		}
+		if !first[0].CreatedAt.Equal(created[0].CreatedAt) {
+			t.Errorf("CreatedAt = %v, want %v", first[0].CreatedAt, created[0].CreatedAt)
+		}
+
+		last := first[len(first)-1]
+		second, err := db.GetChirpsAsc(ctx, database.GetChirpsAscParams{
+			CursorCreatedAt: sql.NullTime{Time: last.CreatedAt, Valid: true},
+			CursorID:        uuid.NullUUID{UUID: last.ID, Valid: true},
+			PageLimit:       2,
+		})
+		if err != nil {
+			t.Fatalf("GetChirpsAsc() error = %v", err)
+		}
+		if len(second) != 1 || second[0].ID != created[2].ID {
+			t.Errorf("second page = %v", second)
+		}
+
+		desc, err := db.GetChirpsDesc(ctx, database.GetChirpsDescParams{PageLimit: 10})
+		if err != nil {
+			t.Fatalf("GetChirpsDesc() error = %v", err)
+		}
+		if len(desc) != 3 || desc[0].ID != created[2].ID {
+			t.Errorf("newest first = %v", desc)
+		}
+	})
+}
+
+func TestChirpEngagement(t *testing.T) {
+	eachStore(t, func(t *testing.T, db Store) {
+		ctx := context.Background()
+		walt := createUser(t, db, "walt@example.com")
+		jesse := createUser(t, db, "jesse@example.com")
+		liked := createChirp(t, db, database.CreateChirpParams{Body: "Liked", UserID: walt.ID})
+		ignored := createChirp(t, db, database.CreateChirpParams{Body: "Ignored", UserID: walt.ID})
+
+		for i := 0; i < 2; i++ {
+			if err := db.CreateChirpLike(ctx, database.CreateChirpLikeParams{UserID: jesse.ID, ChirpID: liked.ID}); err != nil {
+				t.Fatalf("CreateChirpLike() error = %v", err)
+			}
+		}
+
+		rows, err := db.GetChirpEngagement(ctx, database.GetChirpEngagementParams{
+			ViewerID: uuid.NullUUID{UUID: jesse.ID, Valid: true},
+			ChirpIds: []uuid.UUID{liked.ID, ignored.ID},
+		})
+		if err != nil {
+			t.Fatalf("GetChirpEngagement() error = %v", err)
+		}
+
+		got := map[uuid.UUID]database.GetChirpEngagementRow{}
+		for _, row := range rows {
+			got[row.ID] = row
+		}
+		if row := got[liked.ID]; row.LikeCount != 1 || !row.LikedByMe {
+			t.Errorf("liked chirp = %+v", row)
+		}
+		if row := got[ignored.ID]; len(got) != 2 || row.LikeCount != 0 || row.LikedByMe {
+			t.Errorf("ignored chirp = %+v", row)
+		}
+	})
+}
+
+func TestSearchChirps(t *testing.T) {
+	eachStore(t, func(t *testing.T, db Store) {
+		ctx := context.Background()
+		user := createUser(t, db, "walt@example.com")
+		cooking := createChirp(t, db, database.CreateChirpParams{Body: "Cooking chemistry", UserID: user.ID})
+		createChirp(t, db, database.CreateChirpParams{Body: "Teaching chemistry to teenagers", UserID: user.ID})
+		createChirp(t, db, database.CreateChirpParams{Body: "Car wash", UserID: user.ID})
+
+		rows, err := db.SearchChirpsByRelevance(ctx, database.SearchChirpsByRelevanceParams{Query: "chemistry", PageLimit: 10})
+		if err != nil {
+			t.Fatalf("SearchChirpsByRelevance() error = %v", err)
+		}
+		if len(rows) != 2 || rows[0].ID != cooking.ID || rows[0].Rank <= rows[1].Rank {
+			t.Errorf("SearchChirpsByRelevance() = %+v", rows)
+		}
+
+		chirps, err := db.SearchChirpsByRecency(ctx, database.SearchChirpsByRecencyParams{Query: "chemistry -teaching", PageLimit: 10})
+		if err != nil {
+			t.Fatalf("SearchChirpsByRecency() error = %v", err)
+		}
+		if len(chirps) != 1 || chirps[0].ID != cooking.ID {
+			t.Errorf("SearchChirpsByRecency() = %+v", chirps)
+		}
+	})
+}
+
+func TestUserSessions(t *testing.T) {
+	eachStore(t, func(t *testing.T, db Store) {
+		ctx := context.Background()
+		user := createUser(t, db, "walt@example.com")
+		familyID := uuid.New()
+
+		for _, token := range []string{"first", "rotated"} {
+			_, err := db.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+				Token:     token,
+				UserID:    user.ID,
+				ExpiresAt: time.Now().UTC().Add(time.Hour),
+				FamilyID:  familyID,
+			})
+			if err != nil {
+				t.Fatalf("CreateRefreshToken() error = %v", err)
+			}
+		}
+		first, err := db.GetRefreshToken(ctx, "first")
+		if err != nil {
+			t.Fatalf("GetRefreshToken() error = %v", err)
+		}
+		if _, err := db.RevokeRefreshToken(ctx, "first"); err != nil {
+			t.Fatalf("RevokeRefreshToken() error = %v", err)
+		}
+
+		sessions, err := db.GetUserSessions(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetUserSessions() error = %v", err)
+		}
+		if len(sessions) != 1 || sessions[0].FamilyID != familyID || !sessions[0].SignedInAt.Equal(first.CreatedAt) {
+			t.Errorf("GetUserSessions() = %+v, want the family signed in at %v", sessions, first.CreatedAt)
+		}
+	})
+}
+
+func TestWebhookDeliveryQueue(t *testing.T) {
+	eachStore(t, func(t *testing.T, db Store) {
+		ctx := context.Background()
+		walt := createUser(t, db, "walt@example.com")
+		jesse := createUser(t, db, "jesse@example.com")
+
+		endpoints := []database.CreateWebhookEndpointParams{
+			{UserID: uuid.NullUUID{UUID: walt.ID, Valid: true}, Url: "https://walt.example.com", Secret: "s", Events: "chirp.created,chirp.deleted"},
+			{UserID: uuid.NullUUID{UUID: jesse.ID, Valid: true}, Url: "https://jesse.example.com", Secret: "s", Events: "chirp.created"},
+			{Url: "https://admin.example.com", Secret: "s", Events: "chirp.deleted"},
+		}
+		for _, endpoint := range endpoints {
+			if _, err := db.CreateWebhookEndpoint(ctx, endpoint); err != nil {
+				t.Fatalf("CreateWebhookEndpoint() error = %v", err)
+			}
+		}
+
+		admin, err := db.GetWebhookEndpoints(ctx, uuid.NullUUID{})
+		if err != nil {
+			t.Fatalf("GetWebhookEndpoints() error = %v", err)
+		}
+		if len(admin) != 1 || admin[0].Url != "https://admin.example.com" {
+			t.Errorf("admin endpoints = %+v", admin)
+		}
+
+		queued, err := db.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+			EventType: "chirp.deleted",
+			Payload:   "{}",
+			UserIds:   []uuid.UUID{walt.ID},
+		})
+		if err != nil {
+			t.Fatalf("EnqueueWebhookDeliveries() error = %v", err)
+		}
+		if queued != 2 {
+			t.Errorf("EnqueueWebhookDeliveries() = %d, want 2", queued)
+		}
+
+		lease := database.ClaimDueWebhookDeliveriesParams{LeaseUntil: time.Now().UTC().Add(time.Minute), BatchSize: 10}
+		claimed, err := db.ClaimDueWebhookDeliveries(ctx, lease)
+		if err != nil {
+			t.Fatalf("ClaimDueWebhookDeliveries() error = %v", err)
+		}
+		if len(claimed) != 2 || claimed[0].Url == "" || claimed[0].Secret != "s" {
+			t.Fatalf("ClaimDueWebhookDeliveries() = %+v", claimed)
+		}
+
+		// Claimed deliveries are leased until they're recorded
+		if again, err := db.ClaimDueWebhookDeliveries(ctx, lease); err != nil || len(again) != 0 {
+			t.Errorf("ClaimDueWebhookDeliveries() again = %v, %v, want nothing", again, err)
+		}
+	})
+}
+
+func TestPasswordResetTokens(t *testing.T) {
+	eachStore(t, func(t *testing.T, db Store) {
+		ctx := context.Background()
+		user := createUser(t, db, "walt@example.com")
+
+		tokens := []database.CreatePasswordResetTokenParams{
+			{TokenHash: "current", UserID: user.ID, ExpiresAt: time.Now().UTC().Add(time.Hour)},
+			{TokenHash: "expired", UserID: user.ID, ExpiresAt: time.Now().UTC().Add(-time.Minute)},
+			{TokenHash: "older", UserID: user.ID, ExpiresAt: time.Now().UTC().Add(time.Hour)},
+		}
+		for _, token := range tokens {
+			if err := db.CreatePasswordResetToken(ctx, token); err != nil {
+				t.Fatalf("CreatePasswordResetToken() error = %v", err)
+			}
+		}
+
+		if _, err := db.UsePasswordResetToken(ctx, "expired"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("UsePasswordResetToken(expired) error = %v, want sql.ErrNoRows", err)
+		}
+
+		userID, err := db.UsePasswordResetToken(ctx, "current")
+		if err != nil || userID != user.ID {
+			t.Fatalf("UsePasswordResetToken() = %v, %v, want %v", userID, err, user.ID)
+		}
+		if _, err := db.UsePasswordResetToken(ctx, "current"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("second UsePasswordResetToken() error = %v, want sql.ErrNoRows", err)
+		}
+
+		if err := db.DeletePasswordResetTokens(ctx, user.ID); err != nil {
+			t.Fatalf("DeletePasswordResetTokens() error = %v", err)
+		}
+		if _, err := db.UsePasswordResetToken(ctx, "older"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("UsePasswordResetToken(older) error = %v, want sql.ErrNoRows", err)
+		}
+
+		if err := db.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{ID: user.ID, HashedPassword: "new-hash"}); err != nil {
+			t.Fatalf("UpdateUserPassword() error = %v", err)
+		}
+		updated, err := db.GetUser(ctx, user.ID)
+		if err != nil || updated.HashedPassword != "new-hash" || updated.UpdatedAt.Before(user.UpdatedAt) {
+			t.Errorf("GetUser() = %+v, %v, want the new password hash", updated, err)
+		}
+	})
+}
+
+func TestEmailVerification(t *testing.T) {
+	eachStore(t, func(t *testing.T, db Store) {
+		ctx := context.Background()
+		walt := createUser(t, db, "walt@example.com")
+		createUser(t, db, "jesse@example.com")
+
+		if walt.EmailVerifiedAt.Valid {
+			t.Fatalf("new user is verified: %+v", walt)
+		}
+
+		err := db.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+			TokenHash: "change",
+			UserID:    walt.ID,
+			Email:     "heisenberg@example.com",
+			ExpiresAt: time.Now().UTC().Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("CreateEmailVerificationToken() error = %v", err)
+		}
+
+		token, err := db.UseEmailVerificationToken(ctx, "change")
+		if err != nil || token.UserID != walt.ID || token.Email != "heisenberg@example.com" {
+			t.Fatalf("UseEmailVerificationToken() = %+v, %v", token, err)
+		}
+		if _, err := db.UseEmailVerificationToken(ctx, "change"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("second UseEmailVerificationToken() error = %v, want sql.ErrNoRows", err)
+		}
+
+		verified, err := db.VerifyUserEmail(ctx, database.VerifyUserEmailParams{ID: walt.ID, Email: token.Email})
+		if err != nil || verified.Email != "heisenberg@example.com" || !verified.EmailVerifiedAt.Valid {
+			t.Errorf("VerifyUserEmail() = %+v, %v", verified, err)
+		}
+		if _, err := db.VerifyUserEmail(ctx, database.VerifyUserEmailParams{ID: walt.ID, Email: "jesse@example.com"}); err == nil {
+			t.Error("VerifyUserEmail() to a taken address error = nil, want a unique violation")
+		}
+
+		settings, err := db.GetSiteSettings(ctx)
+		if err != nil || !settings.AllowUnverifiedChirps {
+			t.Errorf("GetSiteSettings() = %+v, %v, want unverified chirps allowed", settings, err)
+		}
+		if _, err := db.UpdateSiteSettings(ctx, false); err != nil {
+			t.Fatalf("UpdateSiteSettings() error = %v", err)
+		}
+		if err := db.DropUsers(ctx); err != nil {
+			t.Fatalf("DropUsers() error = %v", err)
+		}
+		settings, err = db.GetSiteSettings(ctx)
+		if err != nil || settings.AllowUnverifiedChirps {
+			t.Errorf("GetSiteSettings() after DropUsers() = %+v, %v, want the update kept", settings, err)
+		}
+	})
+}
+
+func TestTwoFactorChallenges(t *testing.T) {
+	eachStore(t, func(t *testing.T, db Store) {
+		ctx := context.Background()
+		user := createUser(t, db, "walt@example.com")
+		other := createUser(t, db, "jesse@example.com")
+
+		current := database.CreateTwoFactorChallengeParams{ID: uuid.New(), UserID: user.ID, ExpiresAt: time.Now().UTC().Add(time.Hour)}
+		expired := database.CreateTwoFactorChallengeParams{ID: uuid.New(), UserID: user.ID, ExpiresAt: time.Now().UTC().Add(-time.Minute)}
+		for _, challenge := range []database.CreateTwoFactorChallengeParams{current, expired} {
+			if err := db.CreateTwoFactorChallenge(ctx, challenge); err != nil {
+				t.Fatalf("CreateTwoFactorChallenge() error = %v", err)
+			}
+		}
+
+		attempt := func(id, userID uuid.UUID) int64 {
+			t.Helper()
+			counted, err := db.CountTwoFactorAttempt(ctx, database.CountTwoFactorAttemptParams{ID: id, UserID: userID, MaxAttempts: 2})
+			if err != nil {
+				t.Fatalf("CountTwoFactorAttempt() error = %v", err)
+			}
+			return counted
+		}
+
+		if attempt(expired.ID, user.ID) != 0 {
+			t.Errorf("an attempt was counted on an expired challenge")
+		}
+		if attempt(current.ID, other.ID) != 0 {
+			t.Errorf("an attempt was counted on someone else's challenge")
+		}
+		if attempt(current.ID, user.ID) != 1 || attempt(current.ID, user.ID) != 1 {
+			t.Fatalf("attempts within the limit weren't counted")
+		}
+		if attempt(current.ID, user.ID) != 0 {
+			t.Errorf("an attempt past the limit was counted")
+		}
+
+		if err := db.DeleteExpiredTwoFactorChallenges(ctx, user.ID); err != nil {
+			t.Fatalf("DeleteExpiredTwoFactorChallenges() error = %v", err)
+		}
+		if deleted, err := db.DeleteTwoFactorChallenge(ctx, expired.ID); err != nil || deleted != 0 {
+			t.Errorf("DeleteTwoFactorChallenge(expired) = %d, %v, want it already gone", deleted, err)
+		}
+		if deleted, err := db.DeleteTwoFactorChallenge(ctx, current.ID); err != nil || deleted != 1 {
+			t.Errorf("DeleteTwoFactorChallenge(current) = %d, %v, want 1", deleted, err)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.215.14", true},
+		{"2606:4700::6810:85e5", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		if got := PublicAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("PublicAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestNewClientRefusesPrivateAddresses(t *testing.T) {
+	reached := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer receiver.Close()
+
+	gotStatus, err := NewSender(NewClient(time.Second, false)).Send(context.Background(), Delivery{URL: receiver.URL, Payload: []byte("{}")})
+	if !errors.Is(err, ErrPrivateAddress) || gotStatus != 0 {
+		t.Errorf("Send() = %v, %v, want 0 and ErrPrivateAddress", gotStatus, err)
+	}
+	if reached {
+		t.Errorf("the request reached a loopback address")
+	}
+}
+
+func TestNewClientDoesntFollowRedirects(t *testing.T) {
+	redirected := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			redirected = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusTemporaryRedirect)
+	}))
+	defer receiver.Close()
+
+	gotStatus, err := NewSender(NewClient(time.Second, true)).Send(context.Background(), Delivery{URL: receiver.URL + "/hook", Payload: []byte("{}")})
+	if err == nil || gotStatus != http.StatusTemporaryRedirect {
+		t.Errorf("Send() = %v, %v, want %d and an error", gotStatus, err, http.StatusTemporaryRedirect)
+	}
+	if redirected {
+		t.Errorf("the redirect was followed")
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	for _, host := range []string{"127.0.0.1", "169.254.169.254", "::1"} {
+		if err := CheckHost(context.Background(), host); !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("CheckHost(%q) error = %v, want ErrPrivateAddress", host, err)
+		}
+	}
+
+	if err := CheckHost(context.Background(), "93.184.215.14"); err != nil {
+		t.Errorf("CheckHost() error = %v for a public address", err)
+	}
+}
@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Headers sent with every outgoing delivery
+const (
+	SignatureHeader = "X-Chirpy-Signature"
+	EventHeader     = "X-Chirpy-Event"
+	DeliveryHeader  = "X-Chirpy-Delivery"
+)
+
+const (
+	// MaxAttempts is how often a delivery is tried before it's given up on.
+	MaxAttempts = 8
+
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 6 * time.Hour
+)
+
+// Delivery is one event on its way to one endpoint.
+type Delivery struct {
+	ID      string
+	Event   string
+	URL     string
+	Secret  string
+	Payload []byte
+}
+
+// Sender posts signed deliveries to subscriber endpoints.
+type Sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewSender(client *http.Client) *Sender {
+	return &Sender{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Send posts the delivery once. It returns the response status code, or 0
+// when no response came back, and an error unless the endpoint answered
+// with a 2xx status.
+func (s *Sender) Send(ctx context.Context, d Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set(EventHeader, d.Event)
+	req.Header.Set(DeliveryHeader, d.ID)
+	req.Header.Set(SignatureHeader, Sign(d.Secret, s.now(), d.Payload))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("endpoint responded with %s", res.Status)
+	}
+
+	return res.StatusCode, nil
+}
+
+// Backoff is how long to wait before the given retry, counting from 1. It
+// doubles every time, up to a cap.
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	backoff := baseBackoff
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if backoff >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return backoff
+}
+
+// MakeSecret returns a random signing secret for a new endpoint.
+func MakeSecret() string {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+
+	return "whsec_" + hex.EncodeToString(secret)
+}
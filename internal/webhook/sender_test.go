@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSenderSend(t *testing.T) {
+	payload := []byte(`{"event":"chirp.created","data":{"body":"hello"}}`)
+	secret := MakeSecret()
+
+	tests := []struct {
+		name       string
+		status     int
+		wantStatus int
+		wantErr    bool
+	}{
+		{name: "Accepted", status: http.StatusOK, wantStatus: http.StatusOK},
+		{name: "No content", status: http.StatusNoContent, wantStatus: http.StatusNoContent},
+		{name: "Server error", status: http.StatusInternalServerError, wantStatus: http.StatusInternalServerError, wantErr: true},
+		{name: "Redirect isn't success", status: http.StatusNotModified, wantStatus: http.StatusNotModified, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received *http.Request
+			var body []byte
+			receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tt.status)
+			}))
+			defer receiver.Close()
+
+			sender := NewSender(receiver.Client())
+			gotStatus, err := sender.Send(context.Background(), Delivery{
+				ID:      "delivery-1",
+				Event:   "chirp.created",
+				URL:     receiver.URL,
+				Secret:  secret,
+				Payload: payload,
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotStatus != tt.wantStatus {
+				t.Errorf("Send() status = %v, want %v", gotStatus, tt.wantStatus)
+			}
+
+			if received == nil {
+				t.Fatalf("receiver got no request")
+			}
+			if received.Header.Get(EventHeader) != "chirp.created" || received.Header.Get(DeliveryHeader) != "delivery-1" {
+				t.Errorf("receiver got headers %v", received.Header)
+			}
+			if err := Verify(secret, received.Header.Get(SignatureHeader), body, time.Minute, time.Now()); err != nil {
+				t.Errorf("receiver couldn't verify the signature: %v", err)
+			}
+		})
+	}
+}
+
+func TestSenderSendUnreachable(t *testing.T) {
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := receiver.URL
+	client := receiver.Client()
+	receiver.Close()
+
+	gotStatus, err := NewSender(client).Send(context.Background(), Delivery{URL: url, Payload: []byte("{}")})
+	if err == nil || gotStatus != 0 {
+		t.Errorf("Send() = %v, %v, want 0 and an error", gotStatus, err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 30 * time.Second},
+		{attempt: 2, want: time.Minute},
+		{attempt: 5, want: 8 * time.Minute},
+		{attempt: 20, want: 6 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	payload := []byte(`{"event":"user.upgraded","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`)
+	now := time.Now()
+	valid := Sign("secret", now, payload)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		payload []byte
+		wantErr error
+	}{
+		{
+			name:    "Valid signature",
+			secret:  "secret",
+			header:  valid,
+			payload: payload,
+		},
+		{
+			name:    "Rotated secret",
+			secret:  "secret",
+			header:  Sign("old", now, payload) + ",v1=" + valid[len(valid)-64:],
+			payload: payload,
+		},
+		{
+			name:    "Wrong secret",
+			secret:  "wrong_secret",
+			header:  valid,
+			payload: payload,
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "Tampered payload",
+			secret:  "secret",
+			header:  valid,
+			payload: []byte(`{"event":"user.upgraded","data":{"user_id":"someone-else"}}`),
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "Too old",
+			secret:  "secret",
+			header:  Sign("secret", now.Add(-10*time.Minute), payload),
+			payload: payload,
+			wantErr: ErrStaleSignature,
+		},
+		{
+			name:    "Empty secret",
+			secret:  "",
+			header:  Sign("", now, payload),
+			payload: payload,
+			wantErr: ErrNoSecret,
+		},
+		{
+			name:    "Missing header",
+			secret:  "secret",
+			header:  "",
+			payload: payload,
+			wantErr: ErrMissingSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.payload, 5*time.Minute, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := Verify("secret", "t=abc,v1=00", payload, 5*time.Minute, now); err == nil {
+		t.Errorf("Verify() accepted a malformed header")
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	signedAt := time.Unix(1700000000, 0)
+
+	got, err := Timestamp(Sign("secret", signedAt, []byte("{}")))
+	if err != nil || !got.Equal(signedAt) {
+		t.Errorf("Timestamp() = %v, %v, want %v", got, err, signedAt)
+	}
+
+	for _, header := range []string{"", "v1=abcd", "t=soon,v1=abcd", "t=1700000000"} {
+		if _, err := Timestamp(header); err == nil {
+			t.Errorf("Timestamp(%q) succeeded", header)
+		}
+	}
+}
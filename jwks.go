@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/tracevt/chirpy/internal/auth"
+)
+
+// loadKeyring builds the JWT keyring. privateKeyFiles is a comma separated
+// list of PEM files: the first one signs new tokens, the others only validate
+// tokens they signed before a rotation. A secret adds an HS256 key, which
+// signs when there are no private keys.
+func loadKeyring(secret, privateKeyFiles string) (*auth.Keyring, error) {
+	keyring := auth.NewKeyring()
+
+	if secret != "" {
+		keyring.Add(auth.NewHMACKey(secret), false)
+	}
+
+	current := true
+	for _, path := range strings.Split(privateKeyFiles, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		dat, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		signer, err := auth.ParsePrivateKeyPEM(dat)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		key, err := auth.NewKey(signer)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		keyring.Add(key, current)
+		current = false
+	}
+
+	if secret == "" && current {
+		return nil, errors.New("provide a SECRET or JWT_PRIVATE_KEYS to sign JWTs with")
+	}
+
+	return keyring, nil
+}
+
+func (cfg *apiConfig) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, http.StatusOK, cfg.keyring.JWKS())
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/auth"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	// maxRequestIDLength bounds the IDs accepted from clients and proxies,
+	// which end up in every log line of the request.
+	maxRequestIDLength = 128
+)
+
+type requestIDKey struct{}
+
+// newLogger logs JSON lines, or plain text on the dev platform.
+func newLogger(out io.Writer, platform string) *slog.Logger {
+	var handler slog.Handler = slog.NewJSONHandler(out, nil)
+	if platform == "dev" {
+		handler = slog.NewTextHandler(out, nil)
+	}
+	return slog.New(requestIDHandler{handler})
+}
+
+// requestIDHandler adds the request ID to everything logged with the
+// request's context.
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h requestIDHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := requestID(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{h.Handler.WithGroup(name)}
+}
+
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID accepts the IDs other services are likely to send, and
+// nothing that could garble a log line.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// middlewareLogging gives every request an ID, keeping the one the client or
+// a proxy sent, and logs one line per request once it's done. It has to
+// wrap the ServeMux to see the route the mux matched.
+func (cfg *apiConfig) middlewareLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r)
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("route", routeLabel(r)),
+			slog.Int("status", recorder.status),
+			slog.Duration("latency", time.Since(start)),
+		}
+		if userID, ok := cfg.requestUserID(r); ok {
+			attrs = append(attrs, slog.String("user_id", userID.String()))
+		}
+		slog.LogAttrs(r.Context(), slog.LevelInfo, "request", attrs...)
+	})
+}
+
+// requestUserID reports who sent the request, for requests with a valid
+// access token.
+func (cfg *apiConfig) requestUserID(r *http.Request) (uuid.UUID, bool) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRequestIDs(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("saul@example.com")
+
+	tests := []struct {
+		name   string
+		sent   string
+		wantID bool // whether the sent ID is kept
+	}{
+		{name: "kept", sent: "req-42.abc:1_x", wantID: true},
+		{name: "missing"},
+		{name: "garbled", sent: "bad id\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/chirps/not-a-uuid", nil)
+			req.Header.Set("Authorization", bearer(user.Token))
+			if tt.sent != "" {
+				req.Header.Set(requestIDHeader, tt.sent)
+			}
+			rec := httptest.NewRecorder()
+			ts.handler.ServeHTTP(rec, req)
+			expectStatus(t, rec, http.StatusBadRequest)
+
+			id := rec.Header().Get(requestIDHeader)
+			if tt.wantID && id != tt.sent {
+				t.Errorf("%s = %q, want %q", requestIDHeader, id, tt.sent)
+			}
+			if !tt.wantID {
+				if _, err := uuid.Parse(id); err != nil {
+					t.Errorf("%s = %q, want a generated UUID", requestIDHeader, id)
+				}
+			}
+
+			if problem := decodeBody[Problem](t, rec); problem.RequestID != id {
+				t.Errorf("problem request_id = %q, want %q", problem.RequestID, id)
+			}
+
+			log := testLogs.accessLog(id)
+			if log == nil {
+				t.Fatalf("no access log for request %q", id)
+			}
+			if log["route"] != "/api/chirps/{chirpID}" || log["status"] != "400" || log["user_id"] != user.ID.String() {
+				t.Errorf("access log = %v", log)
+			}
+		})
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"0b6a3c2e-8f1d-4d5e-9a7b-1c2d3e4f5a6b", true},
+		{"trace:abc.DEF_1", true},
+		{"", false},
+		{"has space", false},
+		{"line\nbreak", false},
+		{"quote\"", false},
+		{string(make([]byte, maxRequestIDLength+1)), false},
+	}
+
+	for _, tt := range tests {
+		if got := validRequestID(tt.id); got != tt.want {
+			t.Errorf("validRequestID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
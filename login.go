@@ -2,86 +2,134 @@ package main
 
 import (
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/tracevt/chirpy/internal/auth"
 	"github.com/tracevt/chirpy/internal/database"
 )
 
+const (
+	accessTokenExpiration        = time.Minute * 60
+	refreshTokenExpiration       = time.Minute * 86400 // 60 days after the fact
+	twoFactorChallengeExpiration = time.Minute * 5
+	// twoFactorMaxAttempts is how many codes can be tried with one challenge
+	// before the password has to be entered again
+	twoFactorMaxAttempts = 5
+)
+
+// createRefreshToken issues a new refresh token in the given token family.
+// Every login starts a new family and every refresh continues it, so a
+// family is what users see as a session.
+func createRefreshToken(r *http.Request, db database.Querier, userID, familyID uuid.UUID) (database.RefreshToken, error) {
+	return db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		Token:     auth.MakeRefreshToken(),
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(refreshTokenExpiration),
+		FamilyID:  familyID,
+		UserAgent: r.UserAgent(),
+		IpAddress: clientIP(r),
+	})
+}
+
 func (cfg *apiConfig) login(w http.ResponseWriter, r *http.Request) {
 	type loginCredentials struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
 
-	decoder := json.NewDecoder(r.Body)
 	params := loginCredentials{}
-	err := decoder.Decode(&params)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
-		return
-	}
-
-	if params.Email == "" {
-		respondWithError(w, http.StatusBadRequest, "Email is required", fmt.Errorf("email is required"))
+	if !decodeJSON(w, r, &params) {
 		return
 	}
 
-	if params.Password == "" {
-		respondWithError(w, http.StatusBadRequest, "Password is required", fmt.Errorf("password is required"))
+	var v validation
+	v.required("email", normalizeEmail(params.Email), "Email")
+	v.required("password", params.Password, "Password")
+	if v.failed(w, r) {
 		return
 	}
 
-	user, err := cfg.db.GetUserByEmail(r.Context(), params.Email)
+	user, err := cfg.userByEmail(r.Context(), params.Email)
 
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "incorrect email or password", err)
+		cfg.metrics.logins.WithLabelValues("failed").Inc()
+		respondWithError(w, r, problemBadCredentials, "incorrect email or password", err)
 		return
 	}
 
 	noMatch := auth.CheckPasswordHash(params.Password, user.HashedPassword)
 
 	if noMatch != nil {
-		respondWithError(w, http.StatusUnauthorized, "incorrect email or password", noMatch)
+		cfg.metrics.logins.WithLabelValues("failed").Inc()
+		respondWithError(w, r, problemBadCredentials, "incorrect email or password", noMatch)
+		return
+	}
+
+	// With 2FA on, the password only buys a challenge token
+	totp, err := cfg.db.GetUserTOTP(r.Context(), user.ID)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, problemInternal, "Couldn't check two-factor authentication", err)
 		return
 	}
 
-	expiration := time.Minute * 60
+	if err == nil && totp.EnabledAt.Valid {
+		challengeToken, err := cfg.startTwoFactorChallenge(r.Context(), user.ID)
+
+		if err != nil {
+			respondWithError(w, r, problemInternal, "JWT couldn't be generated", err)
+			return
+		}
 
-	token, err := auth.MakeJWT(user.ID, cfg.secret, expiration)
+		respondWithJSON(w, http.StatusOK, TwoFactorChallenge{
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+		})
+		return
+	}
+
+	cfg.respondWithTokens(w, r, user)
+}
+
+// respondWithTokens finishes a login by starting a new session for the user.
+func (cfg *apiConfig) respondWithTokens(w http.ResponseWriter, r *http.Request, user database.User) {
+	token, err := cfg.keyring.MakeJWT(user.ID, accessTokenExpiration)
 
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "JWT couldn't be generated", err)
+		respondWithError(w, r, problemInternal, "JWT couldn't be generated", err)
 		return
 	}
 
 	// Create Refresh Token on the DB
-	refreshToken := auth.MakeRefreshToken()
-	refreshTokenExpiration := time.Minute * 86400 // 60 days after the fact
-	refreshTokenExpirationDate := time.Now().Add(refreshTokenExpiration)
-
-	refreshTokenDB, err := cfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token:     refreshToken,
-		UserID:    user.ID,
-		ExpiresAt: refreshTokenExpirationDate,
-	})
+	refreshTokenDB, err := createRefreshToken(r, cfg.db, user.ID, uuid.New())
 
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Refresh Token couldn't be generated", err)
+		respondWithError(w, r, problemInternal, "Refresh Token couldn't be generated", err)
 		return
 	}
 
+	isChirpyRed, err := cfg.isChirpyRed(r.Context(), user.ID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve subscription", err)
+		return
+	}
+
+	cfg.metrics.logins.WithLabelValues("succeeded").Inc()
+
 	jsonUser := &UserWithToken{
-		ID:           user.ID,
-		CreatedAt:    user.CreatedAt,
-		UpdatedAt:    user.UpdatedAt,
-		Email:        user.Email,
-		Token:        token,
-		RefreshToken: refreshTokenDB.Token,
-		IsChirpyRed:  user.IsChirpyRed,
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		Token:         token,
+		RefreshToken:  refreshTokenDB.Token,
+		IsChirpyRed:   isChirpyRed,
 	}
 
 	respondWithJSON(w, http.StatusOK, jsonUser)
@@ -89,60 +137,114 @@ func (cfg *apiConfig) login(w http.ResponseWriter, r *http.Request) {
 
 func (cfg *apiConfig) refresh(w http.ResponseWriter, r *http.Request) {
 	type TokenType struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 
 	// Check for the token in the headers
 	refreshToken, err := auth.GetBearerToken(r.Header)
 
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Please provide an auth token", err)
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
 		return
 	}
 
 	refreshTokenDB, err := cfg.db.GetRefreshToken(r.Context(), refreshToken)
 
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "", err)
+		respondWithError(w, r, problemInvalidToken, "Refresh token is invalid", err)
 		return
 	}
 
-	// Check if the token has been revoked
+	// A revoked token coming back means it was copied by someone else: it has
+	// already been rotated or the user logged out. Log out the whole family.
 	if refreshTokenDB.RevokedAt.Valid {
-		respondWithError(w, http.StatusUnauthorized, "", err)
+		cfg.revokeTokenFamily(w, r, refreshTokenDB)
 		return
 	}
 
-	// Start creating a new token for the authorized user
-	expiration := time.Minute * 60
+	if time.Now().After(refreshTokenDB.ExpiresAt) {
+		respondWithError(w, r, problemTokenExpired, "Refresh token has expired", fmt.Errorf("refresh token expired at %s", refreshTokenDB.ExpiresAt))
+		return
+	}
+
+	// Rotate the token: revoke the one we got and issue its replacement
+	tx, err := cfg.db.BeginTx(r.Context())
 
-	token, err := auth.MakeJWT(refreshTokenDB.UserID, cfg.secret, expiration)
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Refresh token couldn't be rotated", err)
+		return
+	}
+	defer tx.Rollback()
+
+	revoked, err := tx.RevokeRefreshToken(r.Context(), refreshTokenDB.Token)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Refresh token couldn't be rotated", err)
+		return
+	}
+
+	// Someone else rotated it between our read and our update
+	if revoked == 0 {
+		tx.Rollback()
+		cfg.revokeTokenFamily(w, r, refreshTokenDB)
+		return
+	}
+
+	newRefreshToken, err := createRefreshToken(r, tx, refreshTokenDB.UserID, refreshTokenDB.FamilyID)
 
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "JWT couldn't be generated", err)
+		respondWithError(w, r, problemInternal, "Refresh Token couldn't be generated", err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Refresh token couldn't be rotated", err)
+		return
+	}
+
+	// Start creating a new token for the authorized user
+	token, err := cfg.keyring.MakeJWT(refreshTokenDB.UserID, accessTokenExpiration)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "JWT couldn't be generated", err)
 		return
 	}
 
 	tokenResponse := &TokenType{
-		Token: token,
+		Token:        token,
+		RefreshToken: newRefreshToken.Token,
 	}
 
 	respondWithJSON(w, http.StatusOK, tokenResponse)
 }
 
+func (cfg *apiConfig) revokeTokenFamily(w http.ResponseWriter, r *http.Request, reused database.RefreshToken) {
+	err := cfg.db.RevokeRefreshTokenFamily(r.Context(), reused.FamilyID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Refresh tokens couldn't be revoked", err)
+		return
+	}
+
+	respondWithError(w, r, problemTokenRevoked, "Refresh token has been revoked", fmt.Errorf("reuse of revoked refresh token detected, revoked family %s", reused.FamilyID))
+}
+
 func (cfg *apiConfig) revoke(w http.ResponseWriter, r *http.Request) {
 	// Check for the token in the headers
 	refreshToken, err := auth.GetBearerToken(r.Header)
 
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Please provide an auth token", err)
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
 		return
 	}
 
 	refreshTokenDB, err := cfg.db.GetRefreshToken(r.Context(), refreshToken)
 
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "", err)
+		respondWithError(w, r, problemInvalidToken, "Refresh token is invalid", err)
 		return
 	}
 
@@ -159,7 +261,7 @@ func (cfg *apiConfig) revoke(w http.ResponseWriter, r *http.Request) {
 	_, err = cfg.db.UpdateRefreshToken(r.Context(), *refreshParams)
 
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Refresh token couldn't be revoked", err)
+		respondWithError(w, r, problemInternal, "Refresh token couldn't be revoked", err)
 		return
 	}
 
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+
+	"github.com/tracevt/chirpy/internal/mail"
+)
+
+// loadMailer sends through SMTP_ADDR when it's set. Otherwise emails are
+// written to files in MAIL_DIR, which is enough for local development.
+func loadMailer() (mail.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		return mail.NewSMTP(addr, from, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD")), nil
+	}
+
+	dir := os.Getenv("MAIL_DIR")
+	if dir == "" {
+		dir = "mail"
+	}
+	return mail.NewFile(dir, from)
+}
@@ -1,69 +1,270 @@
 package main
 
 import (
-	"database/sql"
-	"log"
+	"context"
+	"flag"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
-	"github.com/tracevt/chirpy/internal/database"
+	"github.com/pressly/goose/v3"
+	"github.com/tracevt/chirpy/internal/auth"
+	"github.com/tracevt/chirpy/internal/mail"
+	"github.com/tracevt/chirpy/internal/moderation"
+	"github.com/tracevt/chirpy/internal/store"
+	"github.com/tracevt/chirpy/internal/webhook"
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32
-	db             *database.Queries
+	db             store.Store
 	platform       string
-	secret         string
+	keyring        *auth.Keyring
 	polka          string
+	adminKey       string
+	moderation     *moderation.Filter
+	webhookSender  *webhook.Sender
+	metrics        *serverMetrics
+	draining       atomic.Bool
+	healthChecks   []HealthCheck
+	migrations     *goose.Provider
+	mailer         mail.Mailer
+	publicURL      string // Where users reach the server, for links in emails
 }
 
 func main() {
+	autoMigrate := flag.Bool("auto-migrate", false, "apply pending migrations before serving")
+	flag.Parse()
+
 	godotenv.Load()
 	dbURL := os.Getenv("DB_URL")
 	platform := os.Getenv("PLATFORM")
 	secret := os.Getenv("SECRET")
 	polka := os.Getenv("POLKA_KEY")
-	db, err := sql.Open("postgres", dbURL)
+	adminKey := os.Getenv("ADMIN_KEY")
+	publicURL := strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/")
+	if publicURL == "" {
+		publicURL = "http://localhost:8080"
+	}
+
+	slog.SetDefault(newLogger(os.Stdout, platform))
+
+	if polka == "" {
+		slog.Warn("POLKA_KEY isn't set, Polka webhooks will be rejected")
+	}
+
+	serverCfg, err := loadServerConfig()
+	if err != nil {
+		fatal("Invalid server configuration", err)
+	}
+
+	backend, db, err := store.Open(dbURL)
+
+	if err != nil {
+		fatal("Error opening a database connection", err)
+	}
+
+	db.SetMaxOpenConns(serverCfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(serverCfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(serverCfg.DBConnMaxLifetime)
+	db.SetConnMaxIdleTime(serverCfg.DBConnMaxIdleTime)
+
+	migrations, err := newMigrationProvider(backend, db)
+	if err != nil {
+		fatal("Couldn't load the embedded migrations", err)
+	}
+
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrateCommand(context.Background(), migrations, flag.Args()[1:]); err != nil {
+			fatal("Migration failed", err)
+		}
+		return
+	}
+
+	if *autoMigrate {
+		results, err := migrations.Up(context.Background())
+		if err != nil {
+			fatal("Couldn't apply migrations", err)
+		}
+		for _, result := range results {
+			slog.Info("Applied migration", "path", result.Source.Path)
+		}
+	}
+
+	if err := verifySchemaVersion(context.Background(), migrations); err != nil {
+		fatal("Refusing to start", err)
+	}
 
+	keyring, err := loadKeyring(secret, os.Getenv("JWT_PRIVATE_KEYS"))
 	if err != nil {
-		log.Fatal("Error opening a database connection")
+		fatal("Couldn't load JWT signing keys", err)
 	}
 
-	if secret == "" {
-		log.Fatal("Provide a secret to encode JWTs in")
+	moderationAction, err := moderation.ParseAction(os.Getenv("MODERATION_ACTION"))
+	if err != nil {
+		fatal("Invalid moderation configuration", err)
+	}
+	moderationNormalize := os.Getenv("MODERATION_NORMALIZE") == "true"
+
+	mailer, err := loadMailer()
+	if err != nil {
+		fatal("Couldn't set up email", err)
 	}
 
 	apiCfg := &apiConfig{
 		fileserverHits: atomic.Int32{},
-		db:             database.New(db),
+		db:             store.New(backend, db),
 		platform:       platform,
-		secret:         secret,
+		keyring:        keyring,
 		polka:          polka,
+		adminKey:       adminKey,
+		moderation:     moderation.NewFilter(moderationAction, moderationNormalize),
+		webhookSender:  webhook.NewSender(webhook.NewClient(webhookSendTimeout, platform == "dev")),
+		metrics:        newServerMetrics(db),
+		migrations:     migrations,
+		mailer:         mailer,
+		publicURL:      publicURL,
 	}
 
-	mux := http.NewServeMux()
-	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(".")))))
-	mux.HandleFunc("GET /api/healthz", HealthEndpoint)
-	mux.HandleFunc("GET /admin/metrics", apiCfg.metricsHandler)
-	mux.HandleFunc("GET /api/chirps", apiCfg.getChirps)
-	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.getChirp)
-	mux.HandleFunc("POST /admin/reset", apiCfg.ResetMetricsHandler)
-	mux.HandleFunc("POST /api/chirps", apiCfg.handleChirps)
-	mux.HandleFunc("POST /api/users", apiCfg.createUser)
-	mux.HandleFunc("POST /api/login", apiCfg.login)
-	mux.HandleFunc("POST /api/refresh", apiCfg.refresh)
-	mux.HandleFunc("POST /api/revoke", apiCfg.revoke)
-	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.parseEvent)
-	mux.HandleFunc("PUT /api/users", apiCfg.updateUser)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.deleteChirp)
+	apiCfg.registerHealthCheck("database", apiCfg.pingDatabase)
+	apiCfg.registerHealthCheck("schema_version", apiCfg.checkSchemaVersion)
+
+	if err := apiCfg.reloadBadWords(context.Background()); err != nil {
+		fatal("Couldn't load the bad words list", err)
+	}
+
+	// Cancelled by SIGINT or SIGTERM, which starts the shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var workers sync.WaitGroup
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		apiCfg.runScheduledChirpPublisher(ctx, scheduledChirpInterval)
+	}()
+	go func() {
+		defer workers.Done()
+		apiCfg.runWebhookDispatcher(ctx, webhookDispatchInterval)
+	}()
 
 	s := &http.Server{
-		Addr:    ":8080",
-		Handler: mux,
+		Addr:              serverCfg.ListenAddr,
+		Handler:           apiCfg.routes(),
+		ReadTimeout:       serverCfg.ReadTimeout,
+		ReadHeaderTimeout: serverCfg.ReadHeaderTimeout,
+		WriteTimeout:      serverCfg.WriteTimeout,
+		IdleTimeout:       serverCfg.IdleTimeout,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Serving", "addr", serverCfg.ListenAddr)
+		serverErr <- s.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		fatal("Server failed", err)
+	case <-ctx.Done():
+	}
+	// A second signal kills the process without waiting
+	stop()
+
+	slog.Info("Shutting down, draining connections")
+	apiCfg.draining.Store(true)
+	time.Sleep(serverCfg.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serverCfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		slog.Warn("Couldn't drain all connections", "error", err)
 	}
 
-	log.Fatal(s.ListenAndServe())
+	workers.Wait()
+	db.Close()
+	slog.Info("Server stopped")
+}
+
+// fatal logs why the server can't go on and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// routes registers every endpoint, behind the logging and metrics middleware.
+func (cfg *apiConfig) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/app/", cfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(".")))))
+	mux.HandleFunc("GET /api/healthz", cfg.HealthEndpoint)
+	mux.HandleFunc("GET /api/livez", cfg.livenessHandler)
+	mux.HandleFunc("GET /api/readyz", cfg.readinessHandler)
+	mux.HandleFunc("GET /.well-known/jwks.json", cfg.jwksHandler)
+	mux.HandleFunc("GET /admin/metrics", cfg.metricsHandler)
+	mux.Handle("GET /metrics", cfg.metrics.handler())
+	mux.HandleFunc("GET /api/chirps", cfg.getChirps)
+	mux.HandleFunc("GET /api/chirps/search", cfg.searchChirps)
+	mux.HandleFunc("GET /api/chirps/{chirpID}", cfg.getChirp)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", cfg.getThread)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/analytics", cfg.getChirpAnalytics)
+	mux.HandleFunc("GET /api/scheduled_chirps", cfg.getScheduledChirps)
+	mux.HandleFunc("DELETE /api/scheduled_chirps/{scheduledID}", cfg.deleteScheduledChirp)
+	mux.HandleFunc("POST /admin/reset", cfg.ResetMetricsHandler)
+	mux.HandleFunc("GET /admin/badwords", cfg.getBadWords)
+	mux.HandleFunc("POST /admin/badwords", cfg.addBadWord)
+	mux.HandleFunc("DELETE /admin/badwords/{word}", cfg.deleteBadWord)
+	mux.HandleFunc("GET /admin/flagged", cfg.getFlaggedChirps)
+	mux.HandleFunc("DELETE /admin/flagged/{chirpID}", cfg.dismissChirpFlag)
+	mux.HandleFunc("POST /api/chirps", cfg.handleChirps)
+	mux.HandleFunc("POST /api/users", cfg.createUser)
+	mux.HandleFunc("POST /api/login", cfg.login)
+	mux.HandleFunc("POST /api/login/2fa", cfg.loginTwoFactor)
+	mux.HandleFunc("POST /api/2fa/enroll", cfg.enrollTwoFactor)
+	mux.HandleFunc("POST /api/2fa/confirm", cfg.confirmTwoFactor)
+	mux.HandleFunc("POST /api/2fa/disable", cfg.disableTwoFactor)
+	mux.HandleFunc("POST /api/refresh", cfg.refresh)
+	mux.HandleFunc("POST /api/revoke", cfg.revoke)
+	mux.HandleFunc("GET /api/sessions", cfg.getSessions)
+	mux.HandleFunc("DELETE /api/sessions", cfg.revokeAllSessions)
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", cfg.revokeSession)
+	mux.HandleFunc("POST /api/polka/webhooks", cfg.parseEvent)
+	mux.HandleFunc("GET /admin/webhooks/failed", cfg.getFailedWebhookEvents)
+	mux.HandleFunc("POST /admin/webhooks/{eventID}/replay", cfg.replayWebhookEvent)
+	mux.HandleFunc("GET /admin/webhooks/endpoints", cfg.getAdminWebhookEndpoints)
+	mux.HandleFunc("POST /admin/webhooks/endpoints", cfg.createAdminWebhookEndpoint)
+	mux.HandleFunc("DELETE /admin/webhooks/endpoints/{endpointID}", cfg.deleteAdminWebhookEndpoint)
+	mux.HandleFunc("GET /admin/webhooks/endpoints/{endpointID}/deliveries", cfg.getAdminWebhookDeliveries)
+	mux.HandleFunc("GET /api/webhooks", cfg.getUserWebhookEndpoints)
+	mux.HandleFunc("POST /api/webhooks", cfg.createUserWebhookEndpoint)
+	mux.HandleFunc("DELETE /api/webhooks/{endpointID}", cfg.deleteUserWebhookEndpoint)
+	mux.HandleFunc("GET /api/webhooks/{endpointID}/deliveries", cfg.getUserWebhookDeliveries)
+	mux.HandleFunc("PUT /api/users", cfg.updateUser)
+	mux.HandleFunc("POST /api/password/forgot", cfg.forgotPassword)
+	mux.HandleFunc("POST /api/password/reset", cfg.resetPassword)
+	mux.HandleFunc("GET /api/email/verify", cfg.verifyEmail)
+	mux.HandleFunc("POST /api/email/verify", cfg.resendEmailVerification)
+	mux.HandleFunc("GET /admin/settings", cfg.getSiteSettings)
+	mux.HandleFunc("PUT /admin/settings", cfg.updateSiteSettings)
+	mux.HandleFunc("GET /api/subscription", cfg.getSubscription)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", cfg.editChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.deleteChirp)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}/like", cfg.likeChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", cfg.unlikeChirp)
+	mux.HandleFunc("PUT /api/chirps/{chirpID}/rechirp", cfg.rechirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", cfg.undoRechirp)
+	mux.HandleFunc("POST /api/users/{userID}/follow", cfg.followUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", cfg.unfollowUser)
+	mux.HandleFunc("GET /api/users/{userID}/followers", cfg.getFollowers)
+	mux.HandleFunc("GET /api/users/{userID}/following", cfg.getFollowing)
+	mux.HandleFunc("GET /api/timeline", cfg.getTimeline)
+
+	return cfg.middlewareLogging(cfg.metrics.middleware(mux))
 }
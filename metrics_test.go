@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	m := newServerMetrics(nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/chirps/{chirpID}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	handler := m.middleware(mux)
+
+	for _, path := range []string{"/api/chirps/1", "/api/chirps/2", "/nowhere"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	m.handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	// Paths are labeled by the pattern they matched, and the first status
+	// written is the one recorded
+	for _, want := range []string{
+		`chirpy_http_requests_total{method="GET",route="/api/chirps/{chirpID}",status="404"} 2`,
+		`chirpy_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`chirpy_http_requests_in_flight 0`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("metrics don't include %s", want)
+		}
+	}
+}
+
+func TestRouteLabel(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{pattern: "GET /api/chirps/{chirpID}", want: "/api/chirps/{chirpID}"},
+		{pattern: "/app/", want: "/app/"},
+		{pattern: "", want: "unmatched"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Pattern = tt.pattern
+		if got := routeLabel(r); got != tt.want {
+			t.Errorf("routeLabel(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pressly/goose/v3"
+	"github.com/tracevt/chirpy/internal/store"
+)
+
+// The schema ships inside the binary, so it always matches the queries it
+// was built with.
+//
+//go:embed sql/schema/*.sql sql/sqlite/schema/*.sql
+var embeddedMigrations embed.FS
+
+// newMigrationProvider loads the migrations for the backend: sql/schema for
+// Postgres, sql/sqlite/schema for SQLite.
+func newMigrationProvider(backend store.Backend, db *sql.DB) (*goose.Provider, error) {
+	dir, dialect := "sql/schema", goose.DialectPostgres
+	if backend == store.BackendSQLite {
+		dir, dialect = "sql/sqlite/schema", goose.DialectSQLite3
+	}
+
+	fsys, err := fs.Sub(embeddedMigrations, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return goose.NewProvider(dialect, db, fsys)
+}
+
+// runMigrateCommand implements "chirpy migrate up|down|status".
+func runMigrateCommand(ctx context.Context, migrations *goose.Provider, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: chirpy migrate up|down|status")
+	}
+
+	switch args[0] {
+	case "up":
+		results, err := migrations.Up(ctx)
+		for _, result := range results {
+			fmt.Printf("Applied %s in %s\n", result.Source.Path, result.Duration)
+		}
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Println("Nothing to apply, the schema is up to date")
+		}
+	case "down":
+		result, err := migrations.Down(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %s in %s\n", result.Source.Path, result.Duration)
+	case "status":
+		statuses, err := migrations.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tSTATE\tAPPLIED AT\tMIGRATION")
+		for _, status := range statuses {
+			appliedAt := ""
+			if !status.AppliedAt.IsZero() {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", status.Source.Version, status.State, appliedAt, status.Source.Path)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q, use up, down or status", args[0])
+	}
+
+	return nil
+}
+
+// verifySchemaVersion fails unless the database is at exactly the version of
+// the newest embedded migration. Behind means queries hit missing columns,
+// ahead means a newer build migrated and this one doesn't know the schema.
+func verifySchemaVersion(ctx context.Context, migrations *goose.Provider) error {
+	current, target, err := migrations.GetVersions(ctx)
+	if err != nil {
+		return err
+	}
+
+	if current < target {
+		return fmt.Errorf("schema is at version %d, behind the expected %d, run \"chirpy migrate up\"", current, target)
+	}
+	if current > target {
+		return fmt.Errorf("schema is at version %d, ahead of the expected %d", current, target)
+	}
+	return nil
+}
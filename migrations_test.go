@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/store"
+)
+
+func TestVerifySchemaVersion(t *testing.T) {
+	ctx := context.Background()
+
+	backend, db, err := store.Open("sqlite:" + filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatalf("store.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	migrations, err := newMigrationProvider(backend, db)
+	if err != nil {
+		t.Fatalf("newMigrationProvider() error = %v", err)
+	}
+
+	if err := verifySchemaVersion(ctx, migrations); err == nil {
+		t.Error("verifySchemaVersion() on an empty database succeeded")
+	}
+
+	if _, err := migrations.Up(ctx); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	if err := verifySchemaVersion(ctx, migrations); err != nil {
+		t.Errorf("verifySchemaVersion() after migrating error = %v", err)
+	}
+
+	if _, err := migrations.Down(ctx); err != nil {
+		t.Fatalf("rolling back: %v", err)
+	}
+	if err := verifySchemaVersion(ctx, migrations); err == nil {
+		t.Error("verifySchemaVersion() a migration behind succeeded")
+	}
+
+	for _, args := range [][]string{nil, {"sideways"}, {"up", "down"}} {
+		if err := runMigrateCommand(ctx, migrations, args); err == nil {
+			t.Errorf("runMigrateCommand(%q) succeeded", args)
+		}
+	}
+}
+
+func TestLowercaseEmailsMigration(t *testing.T) {
+	ctx := context.Background()
+
+	backend, db, err := store.Open("sqlite:" + filepath.Join(t.TempDir(), "chirpy.db"))
+	if err != nil {
+		t.Fatalf("store.Open() error = %v", err)
+	}
+	defer db.Close()
+
+	migrations, err := newMigrationProvider(backend, db)
+	if err != nil {
+		t.Fatalf("newMigrationProvider() error = %v", err)
+	}
+
+	// sql/sqlite/schema/006_lowercase_emails.sql
+	const version = 6
+	if _, err := migrations.UpTo(ctx, version-1); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+
+	users := []struct {
+		email string
+		want  string
+	}{
+		{"Jesse@Example.com", "jesse@example.com"},
+		// The oldest of the accounts that only differ in case gets the address
+		{" Walt@Example.com", "walt@example.com"},
+		{"WALT@example.com", "WALT@example.com"},
+		// Unless one of them already has it
+		{"Skyler@Example.com", "Skyler@Example.com"},
+		{"skyler@example.com", "skyler@example.com"},
+	}
+
+	ids := make([]uuid.UUID, len(users))
+	createdAt := time.Now().UTC()
+	for i, user := range users {
+		ids[i] = uuid.New()
+		at := createdAt.Add(time.Duration(i) * time.Second).Format("2006-01-02 15:04:05.000000+00:00")
+		_, err := db.ExecContext(ctx, "INSERT INTO users (id, created_at, updated_at, email, hashed_password) VALUES (?, ?, ?, ?, 'unset')", ids[i].String(), at, at, user.email)
+		if err != nil {
+			t.Fatalf("inserting %q: %v", user.email, err)
+		}
+	}
+
+	if _, err := migrations.UpTo(ctx, version); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+
+	for i, user := range users {
+		var email string
+		if err := db.QueryRowContext(ctx, "SELECT email FROM users WHERE id = ?", ids[i].String()).Scan(&email); err != nil {
+			t.Fatalf("reading %q: %v", user.email, err)
+		}
+		if email != user.want {
+			t.Errorf("%q became %q, want %q", user.email, email, user.want)
+		}
+	}
+}
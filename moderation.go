@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type FlaggedChirp struct {
+	ID           uuid.UUID `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Body         string    `json:"body"`
+	UserID       uuid.UUID `json:"user_id"`
+	MatchedWords []string  `json:"matched_words"`
+	FlaggedAt    time.Time `json:"flagged_at"`
+}
+
+// reloadBadWords loads the word list from the database into the filter.
+func (cfg *apiConfig) reloadBadWords(ctx context.Context) error {
+	words, err := cfg.db.GetBadWords(ctx)
+	if err != nil {
+		return err
+	}
+
+	cfg.moderation.SetWords(words)
+	return nil
+}
+
+func (cfg *apiConfig) getBadWords(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		respondWithError(w, r, problemAdminRequired, "Admin access required", nil)
+		return
+	}
+
+	words, err := cfg.db.GetBadWords(r.Context())
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve bad words", err)
+		return
+	}
+
+	if words == nil {
+		words = make([]string, 0)
+	}
+
+	respondWithJSON(w, http.StatusOK, words)
+}
+
+func (cfg *apiConfig) addBadWord(w http.ResponseWriter, r *http.Request) {
+	type wordData struct {
+		Word string `json:"word"`
+	}
+
+	if !cfg.isAdmin(r) {
+		respondWithError(w, r, problemAdminRequired, "Admin access required", nil)
+		return
+	}
+
+	params := wordData{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	word := strings.ToLower(strings.TrimSpace(params.Word))
+
+	if !cfg.moderation.IsWord(word) {
+		respondWithFieldErrors(w, r, problemValidation, FieldError{Field: "word", Code: "invalid", Detail: "Please provide a single word of letters and digits"})
+		return
+	}
+
+	err := cfg.db.CreateBadWord(r.Context(), word)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't add bad word", err)
+		return
+	}
+
+	err = cfg.reloadBadWords(r.Context())
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't reload bad words", err)
+		return
+	}
+
+	respondWithNoContent(w)
+}
+
+func (cfg *apiConfig) deleteBadWord(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		respondWithError(w, r, problemAdminRequired, "Admin access required", nil)
+		return
+	}
+
+	deleted, err := cfg.db.DeleteBadWord(r.Context(), strings.ToLower(r.PathValue("word")))
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't delete bad word", err)
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, r, problemNotFound, "Bad word not found", nil)
+		return
+	}
+
+	err = cfg.reloadBadWords(r.Context())
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't reload bad words", err)
+		return
+	}
+
+	respondWithNoContent(w)
+}
+
+func (cfg *apiConfig) getFlaggedChirps(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		respondWithError(w, r, problemAdminRequired, "Admin access required", nil)
+		return
+	}
+
+	flagged, err := cfg.db.GetFlaggedChirps(r.Context())
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve flagged chirps", err)
+		return
+	}
+
+	jsonChirps := make([]FlaggedChirp, 0)
+	for _, chirp := range flagged {
+		jsonChirps = append(jsonChirps, FlaggedChirp{
+			ID:           chirp.ID,
+			CreatedAt:    chirp.CreatedAt,
+			Body:         chirp.Body,
+			UserID:       chirp.UserID,
+			MatchedWords: strings.Split(chirp.MatchedWords, ","),
+			FlaggedAt:    chirp.FlaggedAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, jsonChirps)
+}
+
+func (cfg *apiConfig) dismissChirpFlag(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		respondWithError(w, r, problemAdminRequired, "Admin access required", nil)
+		return
+	}
+
+	chirpUUID, err := uuid.Parse(r.PathValue("chirpID"))
+
+	if err != nil {
+		respondWithError(w, r, problemInvalidID, "Chirp UUID is not in the correct format", err)
+		return
+	}
+
+	deleted, err := cfg.db.DeleteChirpFlag(r.Context(), chirpUUID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't dismiss the flag", err)
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, r, problemNotFound, "Flag not found", nil)
+		return
+	}
+
+	respondWithNoContent(w)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/moderation"
+)
+
+func TestBadWords(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	expectStatus(t, ts.do("GET", "/admin/badwords", nil, ""), http.StatusForbidden)
+	for _, word := range []string{"two words", "f'ing", "x-word", ""} {
+		rec := ts.do("POST", "/admin/badwords", map[string]string{"word": word}, adminKey())
+		expectProblem(t, rec, problemValidation)
+	}
+	expectStatus(t, ts.do("POST", "/admin/badwords", map[string]string{"word": " Heisenberg "}, adminKey()), http.StatusNoContent)
+
+	rec := ts.do("GET", "/admin/badwords", nil, adminKey())
+	expectStatus(t, rec, http.StatusOK)
+	if words := decodeBody[[]string](t, rec); len(words) != 4 {
+		t.Errorf("bad words = %s, want 4 words", rec.Body.String())
+	}
+
+	if chirp := ts.createChirp(user.Token, "I am Heisenberg"); chirp.Body != "I am ****" {
+		t.Errorf("chirp body = %q, want %q", chirp.Body, "I am ****")
+	}
+
+	expectStatus(t, ts.do("DELETE", "/admin/badwords/heisenberg", nil, adminKey()), http.StatusNoContent)
+	expectStatus(t, ts.do("DELETE", "/admin/badwords/heisenberg", nil, adminKey()), http.StatusNotFound)
+
+	if chirp := ts.createChirp(user.Token, "I am Heisenberg"); chirp.Body != "I am Heisenberg" {
+		t.Errorf("chirp body = %q, want it unmasked", chirp.Body)
+	}
+}
+
+func TestFlaggedChirps(t *testing.T) {
+	ts := newTestServer(t)
+	ts.cfg.moderation = moderation.NewFilter(moderation.ActionFlag, false)
+	if err := ts.cfg.reloadBadWords(context.Background()); err != nil {
+		t.Fatalf("reloadBadWords() error = %v", err)
+	}
+
+	user := ts.signUp("walt@example.com")
+	flagged := ts.createChirp(user.Token, "What a kerfuffle")
+	ts.createChirp(user.Token, "Nothing to see")
+
+	expectStatus(t, ts.do("GET", "/admin/flagged", nil, bearer(user.Token)), http.StatusForbidden)
+
+	rec := ts.do("GET", "/admin/flagged", nil, adminKey())
+	expectStatus(t, rec, http.StatusOK)
+	list := decodeBody[[]FlaggedChirp](t, rec)
+	if len(list) != 1 || list[0].ID != flagged.ID || len(list[0].MatchedWords) != 1 || list[0].MatchedWords[0] != "kerfuffle" {
+		t.Errorf("flagged chirps = %s", rec.Body.String())
+	}
+
+	expectStatus(t, ts.do("DELETE", "/admin/flagged/nope", nil, adminKey()), http.StatusBadRequest)
+	expectStatus(t, ts.do("DELETE", "/admin/flagged/"+uuid.NewString(), nil, adminKey()), http.StatusNotFound)
+	expectStatus(t, ts.do("DELETE", "/admin/flagged/"+flagged.ID.String(), nil, adminKey()), http.StatusNoContent)
+
+	rec = ts.do("GET", "/admin/flagged", nil, adminKey())
+	expectStatus(t, rec, http.StatusOK)
+	if list := decodeBody[[]FlaggedChirp](t, rec); len(list) != 0 {
+		t.Errorf("flagged chirps after dismissing = %s", rec.Body.String())
+	}
+}
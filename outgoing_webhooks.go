@@ -0,0 +1,431 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/auth"
+	"github.com/tracevt/chirpy/internal/database"
+	"github.com/tracevt/chirpy/internal/webhook"
+)
+
+// Events subscribers can register for
+const (
+	eventChirpCreated = "chirp.created"
+	eventChirpDeleted = "chirp.deleted"
+	eventUserFollowed = "user.followed"
+	eventUserUpgraded = "user.upgraded"
+)
+
+var outgoingEventTypes = map[string]bool{
+	eventChirpCreated: true,
+	eventChirpDeleted: true,
+	eventUserFollowed: true,
+	eventUserUpgraded: true,
+}
+
+const (
+	webhookDispatchInterval = 10 * time.Second
+	webhookDispatchBatch    = 20
+	// webhookDeliveryLease keeps a claimed delivery from being picked up
+	// again while it's in flight. It has to outlast the sender's timeout.
+	webhookDeliveryLease = time.Minute
+	webhookSendTimeout   = 10 * time.Second
+)
+
+const (
+	deliveryStatusPending   = "pending"
+	deliveryStatusSucceeded = "succeeded"
+	deliveryStatusFailed    = "failed"
+)
+
+type WebhookEndpoint struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    *uuid.UUID `json:"user_id"`
+	URL       string     `json:"url"`
+	Events    []string   `json:"events"`
+	Secret    string     `json:"secret,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	EndpointID     uuid.UUID       `json:"endpoint_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode int32           `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
+
+// OutgoingEvent is the body of every delivery.
+type OutgoingEvent struct {
+	ID        uuid.UUID   `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+func webhookEndpointFromDB(endpoint database.WebhookEndpoint) WebhookEndpoint {
+	jsonEndpoint := WebhookEndpoint{
+		ID:        endpoint.ID,
+		URL:       endpoint.Url,
+		Events:    strings.Split(endpoint.Events, ","),
+		CreatedAt: endpoint.CreatedAt,
+	}
+	if endpoint.UserID.Valid {
+		jsonEndpoint.UserID = &endpoint.UserID.UUID
+	}
+	return jsonEndpoint
+}
+
+func webhookDeliveryFromDB(delivery database.WebhookDelivery) WebhookDelivery {
+	jsonDelivery := WebhookDelivery{
+		ID:             delivery.ID,
+		EndpointID:     delivery.EndpointID,
+		Event:          delivery.EventType,
+		Payload:        json.RawMessage(delivery.Payload),
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == deliveryStatusPending {
+		jsonDelivery.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if delivery.DeliveredAt.Valid {
+		jsonDelivery.DeliveredAt = &delivery.DeliveredAt.Time
+	}
+	return jsonDelivery
+}
+
+// publishEvent queues a delivery of the event for every endpoint subscribed
+// to it: the endpoints of the users involved and the admin ones. Failing to
+// queue doesn't fail the request that caused the event.
+func (cfg *apiConfig) publishEvent(ctx context.Context, event string, userIDs []uuid.UUID, data interface{}) {
+	payload, err := json.Marshal(OutgoingEvent{
+		ID:        uuid.New(),
+		Event:     event,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Couldn't encode event", "event", event, "error", err)
+		return
+	}
+
+	_, err = cfg.db.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+		EventType: event,
+		Payload:   string(payload),
+		UserIds:   userIDs,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Couldn't queue webhooks", "event", event, "error", err)
+	}
+}
+
+// deliverWebhooks sends the deliveries that are due and records how each
+// attempt went. Failed attempts are retried with exponential backoff until
+// webhook.MaxAttempts is reached.
+func (cfg *apiConfig) deliverWebhooks(ctx context.Context) (int, error) {
+	due, err := cfg.db.ClaimDueWebhookDeliveries(ctx, database.ClaimDueWebhookDeliveriesParams{
+		LeaseUntil: time.Now().UTC().Add(webhookDeliveryLease),
+		BatchSize:  webhookDispatchBatch,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, delivery := range due {
+		statusCode, sendErr := cfg.webhookSender.Send(ctx, webhook.Delivery{
+			ID:      delivery.ID.String(),
+			Event:   delivery.EventType,
+			URL:     delivery.Url,
+			Secret:  delivery.Secret,
+			Payload: []byte(delivery.Payload),
+		})
+
+		now := time.Now().UTC()
+		attempt := database.RecordWebhookDeliveryAttemptParams{
+			ID:             delivery.ID,
+			Status:         deliveryStatusSucceeded,
+			NextAttemptAt:  now,
+			LastStatusCode: int32(statusCode),
+			DeliveredAt:    sql.NullTime{Time: now, Valid: true},
+		}
+
+		if sendErr != nil {
+			attempts := int(delivery.Attempts) + 1
+			attempt.Status = deliveryStatusPending
+			attempt.NextAttemptAt = now.Add(webhook.Backoff(attempts))
+			attempt.LastError = sendErr.Error()
+			attempt.DeliveredAt = sql.NullTime{}
+
+			if attempts >= webhook.MaxAttempts {
+				attempt.Status = deliveryStatusFailed
+			}
+		}
+
+		err = cfg.db.RecordWebhookDeliveryAttempt(ctx, attempt)
+		if err != nil {
+			return 0, err
+		}
+		cfg.metrics.webhookDelivered.WithLabelValues(attempt.Status).Inc()
+	}
+
+	return len(due), nil
+}
+
+// runWebhookDispatcher works through the delivery queue every interval
+// until ctx is done.
+func (cfg *apiConfig) runWebhookDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := cfg.deliverWebhooks(ctx)
+			if err != nil {
+				slog.Error("Couldn't deliver webhooks", "error", err)
+			}
+		}
+	}
+}
+
+// createWebhookEndpoint registers an endpoint for owner, or an admin
+// endpoint when owner is null. The secret is only ever shown here.
+func (cfg *apiConfig) createWebhookEndpoint(w http.ResponseWriter, r *http.Request, owner uuid.NullUUID) {
+	type endpointData struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+
+	params := endpointData{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	endpointURL, err := url.Parse(params.URL)
+
+	// Plain HTTP is only good enough for local development
+	if err != nil || endpointURL.Host == "" || !(endpointURL.Scheme == "https" || (endpointURL.Scheme == "http" && cfg.platform == "dev")) {
+		respondWithFieldErrors(w, r, problemValidation, FieldError{Field: "url", Code: "invalid", Detail: "Please provide an HTTPS URL"})
+		return
+	}
+
+	// Local development is the only place subscribers may be on the same
+	// network as the server
+	if cfg.platform != "dev" {
+		err = webhook.CheckHost(r.Context(), endpointURL.Hostname())
+
+		if errors.Is(err, webhook.ErrPrivateAddress) {
+			respondWithFieldErrors(w, r, problemValidation, FieldError{Field: "url", Code: "private", Detail: "Webhooks can only be sent to public addresses"})
+			return
+		}
+
+		if err != nil {
+			respondWithFieldErrors(w, r, problemValidation, FieldError{Field: "url", Code: "unresolvable", Detail: "Couldn't resolve the URL's host"})
+			return
+		}
+	}
+
+	if len(params.Events) == 0 {
+		respondWithFieldErrors(w, r, problemValidation, FieldError{Field: "events", Code: "required", Detail: "Please subscribe to at least one event"})
+		return
+	}
+
+	for _, event := range params.Events {
+		if !outgoingEventTypes[event] {
+			respondWithFieldErrors(w, r, problemValidation, FieldError{Field: "events", Code: "invalid", Detail: "Unknown event " + event})
+			return
+		}
+	}
+
+	secret := webhook.MakeSecret()
+	endpoint, err := cfg.db.CreateWebhookEndpoint(r.Context(), database.CreateWebhookEndpointParams{
+		UserID: owner,
+		Url:    endpointURL.String(),
+		Secret: secret,
+		Events: strings.Join(params.Events, ","),
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't create webhook endpoint", err)
+		return
+	}
+
+	jsonEndpoint := webhookEndpointFromDB(endpoint)
+	jsonEndpoint.Secret = secret
+
+	respondWithJSON(w, http.StatusCreated, jsonEndpoint)
+}
+
+func (cfg *apiConfig) listWebhookEndpoints(w http.ResponseWriter, r *http.Request, owner uuid.NullUUID) {
+	endpoints, err := cfg.db.GetWebhookEndpoints(r.Context(), owner)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve webhook endpoints", err)
+		return
+	}
+
+	jsonEndpoints := make([]WebhookEndpoint, 0)
+	for _, endpoint := range endpoints {
+		jsonEndpoints = append(jsonEndpoints, webhookEndpointFromDB(endpoint))
+	}
+
+	respondWithJSON(w, http.StatusOK, jsonEndpoints)
+}
+
+// webhookEndpoint loads the endpoint named in the path. Users only see their
+// own endpoints, admins (a null owner) see all of them.
+func (cfg *apiConfig) webhookEndpoint(w http.ResponseWriter, r *http.Request, owner uuid.NullUUID) (database.WebhookEndpoint, bool) {
+	endpointUUID, err := uuid.Parse(r.PathValue("endpointID"))
+
+	if err != nil {
+		respondWithError(w, r, problemInvalidID, "Endpoint UUID is not in the correct format", err)
+		return database.WebhookEndpoint{}, false
+	}
+
+	endpoint, err := cfg.db.GetWebhookEndpoint(r.Context(), endpointUUID)
+
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && owner.Valid && endpoint.UserID != owner) {
+		respondWithError(w, r, problemNotFound, "Webhook endpoint not found", err)
+		return database.WebhookEndpoint{}, false
+	}
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve webhook endpoint", err)
+		return database.WebhookEndpoint{}, false
+	}
+
+	return endpoint, true
+}
+
+func (cfg *apiConfig) deleteWebhookEndpoint(w http.ResponseWriter, r *http.Request, owner uuid.NullUUID) {
+	endpoint, ok := cfg.webhookEndpoint(w, r, owner)
+	if !ok {
+		return
+	}
+
+	err := cfg.db.DeleteWebhookEndpoint(r.Context(), endpoint.ID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't delete webhook endpoint", err)
+		return
+	}
+
+	respondWithNoContent(w)
+}
+
+func (cfg *apiConfig) listWebhookDeliveries(w http.ResponseWriter, r *http.Request, owner uuid.NullUUID) {
+	endpoint, ok := cfg.webhookEndpoint(w, r, owner)
+	if !ok {
+		return
+	}
+
+	deliveries, err := cfg.db.GetWebhookDeliveries(r.Context(), endpoint.ID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve webhook deliveries", err)
+		return
+	}
+
+	jsonDeliveries := make([]WebhookDelivery, 0)
+	for _, delivery := range deliveries {
+		jsonDeliveries = append(jsonDeliveries, webhookDeliveryFromDB(delivery))
+	}
+
+	respondWithJSON(w, http.StatusOK, jsonDeliveries)
+}
+
+// webhookOwner authenticates the user managing their own endpoints.
+func (cfg *apiConfig) webhookOwner(w http.ResponseWriter, r *http.Request) (uuid.NullUUID, bool) {
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return uuid.NullUUID{}, false
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return uuid.NullUUID{}, false
+	}
+
+	return uuid.NullUUID{UUID: userID, Valid: true}, true
+}
+
+func (cfg *apiConfig) createUserWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	if owner, ok := cfg.webhookOwner(w, r); ok {
+		cfg.createWebhookEndpoint(w, r, owner)
+	}
+}
+
+func (cfg *apiConfig) getUserWebhookEndpoints(w http.ResponseWriter, r *http.Request) {
+	if owner, ok := cfg.webhookOwner(w, r); ok {
+		cfg.listWebhookEndpoints(w, r, owner)
+	}
+}
+
+func (cfg *apiConfig) deleteUserWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	if owner, ok := cfg.webhookOwner(w, r); ok {
+		cfg.deleteWebhookEndpoint(w, r, owner)
+	}
+}
+
+func (cfg *apiConfig) getUserWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if owner, ok := cfg.webhookOwner(w, r); ok {
+		cfg.listWebhookDeliveries(w, r, owner)
+	}
+}
+
+func (cfg *apiConfig) createAdminWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		respondWithError(w, r, problemAdminRequired, "Admin access required", nil)
+		return
+	}
+	cfg.createWebhookEndpoint(w, r, uuid.NullUUID{})
+}
+
+func (cfg *apiConfig) getAdminWebhookEndpoints(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		respondWithError(w, r, problemAdminRequired, "Admin access required", nil)
+		return
+	}
+	cfg.listWebhookEndpoints(w, r, uuid.NullUUID{})
+}
+
+func (cfg *apiConfig) deleteAdminWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		respondWithError(w, r, problemAdminRequired, "Admin access required", nil)
+		return
+	}
+	cfg.deleteWebhookEndpoint(w, r, uuid.NullUUID{})
+}
+
+func (cfg *apiConfig) getAdminWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		respondWithError(w, r, problemAdminRequired, "Admin access required", nil)
+		return
+	}
+	cfg.listWebhookDeliveries(w, r, uuid.NullUUID{})
+}
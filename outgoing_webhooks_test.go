@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/webhook"
+)
+
+func TestUserWebhookEndpoints(t *testing.T) {
+	ts := newTestServer(t)
+	walt := ts.signUp("walt@example.com")
+	jesse := ts.signUp("jesse@example.com")
+
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer subscriber.Close()
+
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		want   int
+	}{
+		{"no events", map[string]interface{}{"url": subscriber.URL}, http.StatusBadRequest},
+		{"unknown event", map[string]interface{}{"url": subscriber.URL, "events": []string{"chirp.liked"}}, http.StatusBadRequest},
+		{"not a URL", map[string]interface{}{"url": "chirpy", "events": []string{eventChirpCreated}}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, ts.do("POST", "/api/webhooks", tt.params, bearer(walt.Token)), tt.want)
+		})
+	}
+
+	params := map[string]interface{}{"url": subscriber.URL, "events": []string{eventChirpCreated}}
+	expectStatus(t, ts.do("POST", "/api/webhooks", params, ""), http.StatusUnauthorized)
+
+	rec := ts.do("POST", "/api/webhooks", params, bearer(walt.Token))
+	expectStatus(t, rec, http.StatusCreated)
+	endpoint := decodeBody[WebhookEndpoint](t, rec)
+	if endpoint.Secret == "" || endpoint.UserID == nil || *endpoint.UserID != walt.ID {
+		t.Fatalf("created endpoint = %s", rec.Body.String())
+	}
+	path := "/api/webhooks/" + endpoint.ID.String()
+
+	rec = ts.do("GET", "/api/webhooks", nil, bearer(walt.Token))
+	expectStatus(t, rec, http.StatusOK)
+	if list := decodeBody[[]WebhookEndpoint](t, rec); len(list) != 1 || list[0].ID != endpoint.ID || list[0].Secret != "" {
+		t.Errorf("endpoints = %s", rec.Body.String())
+	}
+
+	// Only the owner's chirps are delivered
+	ts.createChirp(jesse.Token, "Yeah, science!")
+	chirp := ts.createChirp(walt.Token, "Say my name")
+
+	sent, err := ts.cfg.deliverWebhooks(context.Background())
+	if err != nil {
+		t.Fatalf("deliverWebhooks() error = %v", err)
+	}
+	if sent != 1 {
+		t.Fatalf("deliverWebhooks() = %d, want 1", sent)
+	}
+
+	req := <-received
+	body := <-bodies
+	if req.Header.Get(webhook.EventHeader) != eventChirpCreated {
+		t.Errorf("event header = %q, want %q", req.Header.Get(webhook.EventHeader), eventChirpCreated)
+	}
+	if err := webhook.Verify(endpoint.Secret, req.Header.Get(webhook.SignatureHeader), body, time.Minute, time.Now()); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	rec = ts.do("GET", path+"/deliveries", nil, bearer(walt.Token))
+	expectStatus(t, rec, http.StatusOK)
+	deliveries := decodeBody[[]WebhookDelivery](t, rec)
+	if len(deliveries) != 1 || deliveries[0].Status != deliveryStatusSucceeded || deliveries[0].LastStatusCode != http.StatusOK {
+		t.Fatalf("deliveries = %s", rec.Body.String())
+	}
+	payload := struct {
+		Event string `json:"event"`
+		Data  Chirp  `json:"data"`
+	}{}
+	if err := json.Unmarshal(deliveries[0].Payload, &payload); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if payload.Event != eventChirpCreated || payload.Data.ID != chirp.ID {
+		t.Errorf("delivered payload = %s", deliveries[0].Payload)
+	}
+
+	// Other users can't see or remove the endpoint
+	expectStatus(t, ts.do("GET", path+"/deliveries", nil, bearer(jesse.Token)), http.StatusNotFound)
+	expectStatus(t, ts.do("DELETE", path, nil, bearer(jesse.Token)), http.StatusNotFound)
+	expectStatus(t, ts.do("DELETE", "/api/webhooks/nope", nil, bearer(walt.Token)), http.StatusBadRequest)
+	expectStatus(t, ts.do("DELETE", path, nil, bearer(walt.Token)), http.StatusNoContent)
+	expectStatus(t, ts.do("DELETE", path, nil, bearer(walt.Token)), http.StatusNotFound)
+}
+
+func TestWebhookEndpointsOnPrivateAddresses(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+	ts.cfg.platform = "prod"
+
+	for _, endpointURL := range []string{
+		"https://127.0.0.1/hook",
+		"https://localhost:8443/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://10.0.0.7/hook",
+		"https://[::1]/hook",
+		"https://0.0.0.0/hook",
+	} {
+		t.Run(endpointURL, func(t *testing.T) {
+			params := map[string]interface{}{"url": endpointURL, "events": []string{eventChirpCreated}}
+			problem := expectProblem(t, ts.do("POST", "/api/webhooks", params, bearer(user.Token)), problemValidation)
+			if len(problem.Errors) != 1 || problem.Errors[0].Code != "private" {
+				t.Errorf("field errors = %+v, want url private", problem.Errors)
+			}
+		})
+	}
+}
+
+func TestAdminWebhookEndpoints(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer subscriber.Close()
+
+	params := map[string]interface{}{"url": subscriber.URL, "events": []string{eventChirpCreated, eventChirpDeleted}}
+	expectStatus(t, ts.do("POST", "/admin/webhooks/endpoints", params, bearer(user.Token)), http.StatusForbidden)
+
+	rec := ts.do("POST", "/admin/webhooks/endpoints", params, adminKey())
+	expectStatus(t, rec, http.StatusCreated)
+	endpoint := decodeBody[WebhookEndpoint](t, rec)
+	if endpoint.UserID != nil {
+		t.Errorf("admin endpoint belongs to %v", *endpoint.UserID)
+	}
+	path := "/admin/webhooks/endpoints/" + endpoint.ID.String()
+
+	rec = ts.do("GET", "/admin/webhooks/endpoints", nil, adminKey())
+	expectStatus(t, rec, http.StatusOK)
+	if list := decodeBody[[]WebhookEndpoint](t, rec); len(list) != 1 {
+		t.Errorf("admin endpoints = %s", rec.Body.String())
+	}
+
+	// Admin endpoints hear about every user's chirps
+	ts.createChirp(user.Token, "Say my name")
+
+	if _, err := ts.cfg.deliverWebhooks(context.Background()); err != nil {
+		t.Fatalf("deliverWebhooks() error = %v", err)
+	}
+
+	// A failed attempt is rescheduled rather than retried right away
+	sent, err := ts.cfg.deliverWebhooks(context.Background())
+	if err != nil {
+		t.Fatalf("deliverWebhooks() error = %v", err)
+	}
+	if sent != 0 {
+		t.Errorf("deliverWebhooks() = %d, want 0", sent)
+	}
+
+	rec = ts.do("GET", path+"/deliveries", nil, adminKey())
+	expectStatus(t, rec, http.StatusOK)
+	deliveries := decodeBody[[]WebhookDelivery](t, rec)
+	if len(deliveries) != 1 || deliveries[0].Status != deliveryStatusPending || deliveries[0].Attempts != 1 || deliveries[0].LastStatusCode != http.StatusServiceUnavailable {
+		t.Errorf("deliveries = %s", rec.Body.String())
+	}
+
+	expectStatus(t, ts.do("DELETE", "/admin/webhooks/endpoints/"+uuid.NewString(), nil, adminKey()), http.StatusNotFound)
+	expectStatus(t, ts.do("DELETE", path, nil, adminKey()), http.StatusNoContent)
+}
+
+func TestWebhookDeliveryWestOfUTC(t *testing.T) {
+	westOfUTC(t)
+
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	// The first attempt runs the dispatcher again while it's in flight
+	inFlight := false
+	reclaimed := 0
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !inFlight {
+			inFlight = true
+			reclaimed, _ = ts.cfg.deliverWebhooks(context.Background())
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer subscriber.Close()
+
+	params := map[string]interface{}{"url": subscriber.URL, "events": []string{eventChirpCreated}}
+	expectStatus(t, ts.do("POST", "/admin/webhooks/endpoints", params, adminKey()), http.StatusCreated)
+	ts.createChirp(user.Token, "Say my name")
+
+	sent, err := ts.cfg.deliverWebhooks(context.Background())
+	if err != nil || sent != 1 {
+		t.Fatalf("deliverWebhooks() = %d, %v, want 1", sent, err)
+	}
+	if reclaimed != 0 {
+		t.Errorf("a delivery in flight was claimed again")
+	}
+
+	sent, err = ts.cfg.deliverWebhooks(context.Background())
+	if err != nil || sent != 0 {
+		t.Errorf("deliverWebhooks() = %d, %v, want the retry to wait for its backoff", sent, err)
+	}
+}
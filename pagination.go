@@ -0,0 +1,175 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// Feeds are paged by time unless they're search results ordered by
+// relevance, whose cursors carry a rank instead.
+const (
+	orderByTime = ""
+	orderByRank = "rank"
+)
+
+// chirpCursor marks the last chirp of a page. It is handed to clients as an
+// opaque string and sent back to fetch the next page. Order records how the
+// page was ordered, since a cursor only makes sense for that ordering.
+type chirpCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        uuid.UUID `json:"id"`
+	Rank      float32   `json:"rank,omitempty"`
+	Order     string    `json:"order,omitempty"`
+}
+
+type pageParams struct {
+	Limit         int32
+	Cursor        *chirpCursor
+	SortDirection string
+	Order         string
+}
+
+type ChirpsPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+func encodeCursor(c chirpCursor) string {
+	dat, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(dat)
+}
+
+func decodeCursor(s string) (chirpCursor, error) {
+	c := chirpCursor{}
+
+	dat, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, fmt.Errorf("malformed cursor: %w", err)
+	}
+
+	err = json.Unmarshal(dat, &c)
+	if err != nil {
+		return c, fmt.Errorf("malformed cursor: %w", err)
+	}
+
+	if c.ID == uuid.Nil || c.CreatedAt.IsZero() {
+		return c, fmt.Errorf("malformed cursor")
+	}
+
+	return c, nil
+}
+
+// parsePageParams reads the page size, sort direction and cursor of a page
+// ordered by order, rejecting cursors that were handed out for another one.
+func parsePageParams(r *http.Request, order string) (pageParams, error) {
+	page := pageParams{
+		Limit:         defaultPageLimit,
+		SortDirection: "asc",
+		Order:         order,
+	}
+
+	if r.URL.Query().Get("sort") == "desc" {
+		page.SortDirection = "desc"
+	}
+
+	limitParam := r.URL.Query().Get("limit")
+	if limitParam != "" {
+		limit, err := strconv.Atoi(limitParam)
+		if err != nil || limit < 1 {
+			return page, fmt.Errorf("limit must be a positive integer")
+		}
+		page.Limit = int32(min(limit, maxPageLimit))
+	}
+
+	cursorParam := r.URL.Query().Get("cursor")
+	if cursorParam != "" {
+		cursor, err := decodeCursor(cursorParam)
+		if err != nil {
+			return page, err
+		}
+		if cursor.Order != order {
+			return page, fmt.Errorf("cursor is for a different order")
+		}
+		page.Cursor = &cursor
+	}
+
+	return page, nil
+}
+
+// queryLimit asks for one row more than the page size, so we know whether
+// there is a next page without a separate count query.
+func (p pageParams) queryLimit() int32 {
+	return p.Limit + 1
+}
+
+func (p pageParams) cursorCreatedAt() sql.NullTime {
+	if p.Cursor == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: p.Cursor.CreatedAt, Valid: true}
+}
+
+func (p pageParams) cursorID() uuid.NullUUID {
+	if p.Cursor == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: p.Cursor.ID, Valid: true}
+}
+
+func (p pageParams) cursorRank() sql.NullFloat64 {
+	if p.Cursor == nil || p.Cursor.Order != orderByRank {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: float64(p.Cursor.Rank), Valid: true}
+}
+
+// feedItem is one entry of a paginated feed. FeedAt is the time the entry
+// appeared in the feed, which is the rechirp time for rechirped chirps.
+// Rank is only set for search results ordered by relevance.
+type feedItem struct {
+	Chirp       chirpRow
+	FeedAt      time.Time
+	RechirpedBy uuid.NullUUID
+	Rank        float32
+}
+
+func feedItemsFromChirps[T ~chirpRow](chirps []T) []feedItem {
+	items := make([]feedItem, 0, len(chirps))
+	for _, chirp := range chirps {
+		row := chirpRow(chirp)
+		items = append(items, feedItem{Chirp: row, FeedAt: row.CreatedAt})
+	}
+	return items
+}
+
+// rowChirp gathers the chirp columns of a query row that selects more than
+// them. The literal is unkeyed so that a new chirp column breaks the build
+// here rather than being left out of a feed.
+func rowChirp(id uuid.UUID, createdAt, updatedAt time.Time, body string, userID uuid.UUID, parentID, rootID uuid.NullUUID, deletedAt sql.NullTime) chirpRow {
+	return chirpRow{id, createdAt, updatedAt, body, userID, parentID, rootID, deletedAt}
+}
+
+// pageFeed trims the extra row fetched by queryLimit and builds the cursor
+// for the following page.
+func pageFeed(items []feedItem, page pageParams) ([]feedItem, string) {
+	if len(items) <= int(page.Limit) {
+		return items, ""
+	}
+
+	items = items[:page.Limit]
+	last := items[len(items)-1]
+
+	return items, encodeCursor(chirpCursor{CreatedAt: last.FeedAt, ID: last.Chirp.ID, Rank: last.Rank, Order: page.Order})
+}
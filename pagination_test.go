@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := chirpCursor{
+		CreatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		ID:        uuid.New(),
+	}
+
+	got, err := decodeCursor(encodeCursor(want))
+	if err != nil {
+		t.Fatalf("decodeCursor() error = %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID {
+		t.Errorf("decodeCursor() = %+v, want %+v", got, want)
+	}
+
+	for _, cursor := range []string{"not base64!", "bm90IGpzb24", encodeCursor(chirpCursor{ID: uuid.New()})} {
+		if _, err := decodeCursor(cursor); err == nil {
+			t.Errorf("decodeCursor(%q) succeeded", cursor)
+		}
+	}
+}
+
+func TestParsePageParams(t *testing.T) {
+	cursor := encodeCursor(chirpCursor{CreatedAt: time.Now(), ID: uuid.New()})
+
+	tests := []struct {
+		name      string
+		query     url.Values
+		wantLimit int32
+		wantSort  string
+		wantErr   bool
+	}{
+		{name: "defaults", query: url.Values{}, wantLimit: defaultPageLimit, wantSort: "asc"},
+		{name: "descending", query: url.Values{"sort": {"desc"}}, wantLimit: defaultPageLimit, wantSort: "desc"},
+		{name: "limit", query: url.Values{"limit": {"5"}}, wantLimit: 5, wantSort: "asc"},
+		{name: "limit is capped", query: url.Values{"limit": {"1000"}}, wantLimit: maxPageLimit, wantSort: "asc"},
+		{name: "cursor", query: url.Values{"cursor": {cursor}}, wantLimit: defaultPageLimit, wantSort: "asc"},
+		{name: "zero limit", query: url.Values{"limit": {"0"}}, wantErr: true},
+		{name: "bad limit", query: url.Values{"limit": {"ten"}}, wantErr: true},
+		{name: "bad cursor", query: url.Values{"cursor": {"nope"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/chirps?"+tt.query.Encode(), nil)
+
+			page, err := parsePageParams(r, orderByTime)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parsePageParams() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePageParams() error = %v", err)
+			}
+			if page.Limit != tt.wantLimit || page.SortDirection != tt.wantSort {
+				t.Errorf("parsePageParams() = limit %d, sort %q, want %d, %q", page.Limit, page.SortDirection, tt.wantLimit, tt.wantSort)
+			}
+			if page.queryLimit() != page.Limit+1 {
+				t.Errorf("queryLimit() = %d, want one more than %d", page.queryLimit(), page.Limit)
+			}
+		})
+	}
+}
+
+func TestPageFeed(t *testing.T) {
+	items := make([]feedItem, 3)
+	for i := range items {
+		items[i] = feedItem{FeedAt: time.Date(2024, 3, 1, i, 0, 0, 0, time.UTC)}
+		items[i].Chirp.ID = uuid.New()
+	}
+
+	got, next := pageFeed(items, pageParams{Limit: 3})
+	if len(got) != 3 || next != "" {
+		t.Errorf("pageFeed() of a full last page = %d items, cursor %q, want 3 and none", len(got), next)
+	}
+
+	got, next = pageFeed(items, pageParams{Limit: 2})
+	if len(got) != 2 || next == "" {
+		t.Fatalf("pageFeed() with a row to spare = %d items, cursor %q, want 2 and a cursor", len(got), next)
+	}
+
+	cursor, err := decodeCursor(next)
+	if err != nil {
+		t.Fatalf("decodeCursor() error = %v", err)
+	}
+	if cursor.ID != items[1].Chirp.ID || !cursor.CreatedAt.Equal(items[1].FeedAt) {
+		t.Errorf("cursor = %+v, want the last item on the page", cursor)
+	}
+}
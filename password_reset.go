@@ -0,0 +1,157 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tracevt/chirpy/internal/auth"
+	"github.com/tracevt/chirpy/internal/database"
+	"github.com/tracevt/chirpy/internal/mail"
+)
+
+const passwordResetExpiration = time.Hour
+
+// forgotPassword emails a reset token to the address, if it belongs to a
+// user. The response is the same either way, so it can't be used to find
+// out who has an account.
+func (cfg *apiConfig) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	type forgotData struct {
+		Email string `json:"email"`
+	}
+
+	params := forgotData{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	var v validation
+	v.email("email", normalizeEmail(params.Email))
+	if v.failed(w, r) {
+		return
+	}
+
+	user, err := cfg.userByEmail(r.Context(), params.Email)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithStatusCode(w, http.StatusAccepted)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve user", err)
+		return
+	}
+
+	token, tokenHash := auth.MakeSingleUseToken()
+
+	err = cfg.db.CreatePasswordResetToken(r.Context(), database.CreatePasswordResetTokenParams{
+		TokenHash: tokenHash,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(passwordResetExpiration),
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't create reset token", err)
+		return
+	}
+
+	err = cfg.mailer.Send(r.Context(), mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password of your Chirpy account. If it was you, "+
+			"send this token with your new password to POST /api/password/reset within an hour:\n\n"+
+			"%s\n\nIf it wasn't you, you can ignore this email.\n", token),
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't send the reset email", err)
+		return
+	}
+
+	respondWithStatusCode(w, http.StatusAccepted)
+}
+
+// resetPassword sets a new password with a token from forgotPassword, and
+// logs the user out everywhere.
+func (cfg *apiConfig) resetPassword(w http.ResponseWriter, r *http.Request) {
+	type resetData struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	params := resetData{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	var v validation
+	v.required("token", params.Token, "Token")
+	v.required("password", params.Password, "Password")
+	if v.failed(w, r) {
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(params.Password)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't secure password", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context())
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't reset password", err)
+		return
+	}
+	defer tx.Rollback()
+
+	userID, err := tx.UsePasswordResetToken(r.Context(), auth.HashSingleUseToken(params.Token))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, problemInvalidToken, "Reset token is invalid or has expired", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't reset password", err)
+		return
+	}
+
+	err = tx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		ID:             userID,
+		HashedPassword: hashedPassword,
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't update user", err)
+		return
+	}
+
+	// Any other token sent before this reset is no good anymore
+	err = tx.DeletePasswordResetTokens(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't reset password", err)
+		return
+	}
+
+	err = tx.RevokeUserRefreshTokens(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Refresh tokens couldn't be revoked", err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't reset password", err)
+		return
+	}
+
+	respondWithNoContent(w)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tracevt/chirpy/internal/auth"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+// lastMailToken returns the token in the last email sent to the address.
+func (ts *testServer) lastMailToken(to string) string {
+	ts.t.Helper()
+
+	messages := ts.mail.Messages()
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].To != to {
+			continue
+		}
+		for _, line := range strings.Split(messages[i].Body, "\n") {
+			if len(line) == 64 && !strings.Contains(line, " ") {
+				return line
+			}
+		}
+	}
+	ts.t.Fatalf("no email with a token sent to %s in %+v", to, messages)
+	return ""
+}
+
+func TestPasswordReset(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("skyler@example.com")
+
+	sent := len(ts.mail.Messages())
+	expectStatus(t, ts.do("POST", "/api/password/forgot", map[string]string{"email": "nobody@example.com"}, ""), http.StatusAccepted)
+	if messages := ts.mail.Messages(); len(messages) != sent {
+		t.Fatalf("sent %+v for an unknown address, want nothing", messages[sent:])
+	}
+	expectProblem(t, ts.do("POST", "/api/password/forgot", map[string]string{"email": "not an email"}, ""), problemValidation)
+
+	expectStatus(t, ts.do("POST", "/api/password/forgot", map[string]string{"email": " Skyler@Example.com"}, ""), http.StatusAccepted)
+	older := ts.lastMailToken("skyler@example.com")
+	expectStatus(t, ts.do("POST", "/api/password/forgot", map[string]string{"email": "skyler@example.com"}, ""), http.StatusAccepted)
+	token := ts.lastMailToken("skyler@example.com")
+
+	expectProblem(t, ts.do("POST", "/api/password/reset", map[string]string{"token": "wrong", "password": "new-password"}, ""), problemInvalidToken)
+	expectProblem(t, ts.do("POST", "/api/password/reset", map[string]string{"token": token}, ""), problemValidation)
+
+	expectStatus(t, ts.do("POST", "/api/password/reset", map[string]string{"token": token, "password": "new-password"}, ""), http.StatusNoContent)
+
+	// The token and the one sent before it are used up
+	expectProblem(t, ts.do("POST", "/api/password/reset", map[string]string{"token": token, "password": "again"}, ""), problemInvalidToken)
+	expectProblem(t, ts.do("POST", "/api/password/reset", map[string]string{"token": older, "password": "again"}, ""), problemInvalidToken)
+
+	// Existing sessions are logged out
+	expectProblem(t, ts.do("POST", "/api/refresh", nil, bearer(user.RefreshToken)), problemTokenRevoked)
+
+	expectProblem(t, ts.do("POST", "/api/login", UserCredentials{Email: "skyler@example.com", Password: "hunter2"}, ""), problemBadCredentials)
+	ts.login("skyler@example.com", "new-password")
+}
+
+func TestPasswordResetExpired(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("marie@example.com")
+
+	token, tokenHash := auth.MakeSingleUseToken()
+	err := ts.db.CreatePasswordResetToken(context.Background(), database.CreatePasswordResetTokenParams{
+		TokenHash: tokenHash,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("CreatePasswordResetToken() error = %v", err)
+	}
+
+	expectProblem(t, ts.do("POST", "/api/password/reset", map[string]string{"token": token, "password": "new-password"}, ""), problemInvalidToken)
+	ts.login("marie@example.com", "hunter2")
+}
+
+func TestPasswordResetWestOfUTC(t *testing.T) {
+	westOfUTC(t)
+
+	ts := newTestServer(t)
+	ts.signUp("marie@example.com")
+
+	expectStatus(t, ts.do("POST", "/api/password/forgot", map[string]string{"email": "marie@example.com"}, ""), http.StatusAccepted)
+	token := ts.lastMailToken("marie@example.com")
+
+	expectStatus(t, ts.do("POST", "/api/password/reset", map[string]string{"token": token, "password": "new-password"}, ""), http.StatusNoContent)
+	ts.login("marie@example.com", "new-password")
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// problemTypeBase prefixes the codes to make RFC 7807 type URIs.
+const problemTypeBase = "urn:chirpy:problem:"
+
+// problemType is one entry of the error catalog. Clients match on the code,
+// which never changes once published; the title is its usual wording.
+type problemType struct {
+	code   string
+	status int
+	title  string
+}
+
+var (
+	problemInvalidBody      = problemType{"invalid_body", http.StatusBadRequest, "The request body couldn't be decoded"}
+	problemBodyTooLarge     = problemType{"body_too_large", http.StatusRequestEntityTooLarge, "The request body is too large"}
+	problemValidation       = problemType{"validation_failed", http.StatusBadRequest, "The request has invalid fields"}
+	problemInvalidID        = problemType{"invalid_id", http.StatusBadRequest, "An ID in the URL is not in the correct format"}
+	problemInvalidQuery     = problemType{"invalid_query", http.StatusBadRequest, "A query parameter is invalid"}
+	problemChirpTooLong     = problemType{"chirp_too_long", http.StatusBadRequest, "Chirp is too long"}
+	problemChirpNotAllowed  = problemType{"chirp_not_allowed", http.StatusBadRequest, "Chirp contains words that aren't allowed"}
+	problemSelfFollow       = problemType{"self_follow", http.StatusBadRequest, "You can't follow yourself"}
+	problemMissingToken     = problemType{"missing_token", http.StatusUnauthorized, "Please provide an auth token"}
+	problemInvalidToken     = problemType{"invalid_token", http.StatusUnauthorized, "The token is invalid"}
+	problemTokenExpired     = problemType{"token_expired", http.StatusUnauthorized, "The token has expired"}
+	problemTokenRevoked     = problemType{"token_revoked", http.StatusUnauthorized, "The token has been revoked"}
+	problemBadCredentials   = problemType{"invalid_credentials", http.StatusUnauthorized, "Incorrect email or password"}
+	problemInvalidCode      = problemType{"invalid_code", http.StatusUnauthorized, "Invalid two-factor code"}
+	problemInvalidSignature = problemType{"invalid_signature", http.StatusUnauthorized, "Invalid webhook signature"}
+	problemForbidden        = problemType{"forbidden", http.StatusForbidden, "You're not allowed to do that"}
+	problemAdminRequired    = problemType{"admin_required", http.StatusForbidden, "Admin access required"}
+	problemRedRequired      = problemType{"chirpy_red_required", http.StatusForbidden, "This feature requires Chirpy Red"}
+	problemEmailUnverified  = problemType{"email_not_verified", http.StatusForbidden, "Email address not verified"}
+	problemNotFound         = problemType{"not_found", http.StatusNotFound, "Not found"}
+	problemConflict         = problemType{"conflict", http.StatusConflict, "The request conflicts with the current state"}
+	problemInternal         = problemType{"internal_error", http.StatusInternalServerError, "Something went wrong on our side"}
+)
+
+// problemTypes lists the catalog, so tests can check every code is unique.
+var problemTypes = []problemType{
+	problemInvalidBody,
+	problemBodyTooLarge,
+	problemValidation,
+	problemInvalidID,
+	problemInvalidQuery,
+	problemChirpTooLong,
+	problemChirpNotAllowed,
+	problemSelfFollow,
+	problemMissingToken,
+	problemInvalidToken,
+	problemTokenExpired,
+	problemTokenRevoked,
+	problemBadCredentials,
+	problemInvalidCode,
+	problemInvalidSignature,
+	problemForbidden,
+	problemAdminRequired,
+	problemRedRequired,
+	problemEmailUnverified,
+	problemNotFound,
+	problemConflict,
+	problemInternal,
+}
+
+// Problem is an RFC 7807 problem details body, plus the code and the
+// request ID.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError says what's wrong with one field of the request body.
+type FieldError struct {
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// tokenProblem tells an expired access token from an otherwise invalid one,
+// so clients know when refreshing will help.
+func tokenProblem(err error) problemType {
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return problemTokenExpired
+	}
+	return problemInvalidToken
+}
+
+// respondWithError sends a problem with the given detail to the client and
+// logs err along with it. The request ID is in both, so a report from a
+// client leads to the log line.
+func respondWithError(w http.ResponseWriter, r *http.Request, problem problemType, detail string, err error) {
+	respondWithProblem(w, r, problem, detail, err, nil)
+}
+
+// respondWithFieldErrors sends a problem listing what's wrong with each
+// field of the request body.
+func respondWithFieldErrors(w http.ResponseWriter, r *http.Request, problem problemType, fieldErrors ...FieldError) {
+	detail := fieldErrors[0].Detail
+	if len(fieldErrors) > 1 {
+		detail = problem.title
+	}
+	respondWithProblem(w, r, problem, detail, nil, fieldErrors)
+}
+
+func respondWithProblem(w http.ResponseWriter, r *http.Request, problem problemType, detail string, err error, fieldErrors []FieldError) {
+	attrs := []slog.Attr{slog.Int("status", problem.status), slog.String("code", problem.code)}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	if problem.status > 499 {
+		slog.LogAttrs(r.Context(), slog.LevelError, detail, attrs...)
+	} else if err != nil {
+		slog.LogAttrs(r.Context(), slog.LevelInfo, detail, attrs...)
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	writeJSON(w, problem.status, Problem{
+		Type:      problemTypeBase + problem.code,
+		Title:     problem.title,
+		Status:    problem.status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      problem.code,
+		RequestID: requestID(r.Context()),
+		Errors:    fieldErrors,
+	})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProblemCatalog(t *testing.T) {
+	seen := map[string]bool{}
+	for _, problem := range problemTypes {
+		if seen[problem.code] {
+			t.Errorf("code %s is used twice", problem.code)
+		}
+		seen[problem.code] = true
+
+		if problem.status < 400 || problem.title == "" {
+			t.Errorf("problem %s = %+v", problem.code, problem)
+		}
+	}
+}
+
+func TestProblems(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("jesse@example.com")
+
+	expired, err := ts.cfg.keyring.MakeJWT(user.ID, -time.Minute)
+	if err != nil {
+		t.Fatalf("MakeJWT() error = %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		target        string
+		body          interface{}
+		authorization string
+		want          problemType
+		wantFields    []string
+	}{
+		{
+			name:   "undecodable body",
+			method: "POST",
+			target: "/api/users",
+			body:   "{not json",
+			want:   problemInvalidBody,
+		},
+		{
+			name:       "missing fields",
+			method:     "POST",
+			target:     "/api/users",
+			body:       map[string]string{},
+			want:       problemValidation,
+			wantFields: []string{"email", "password"},
+		},
+		{
+			name:          "chirp too long",
+			method:        "POST",
+			target:        "/api/chirps",
+			body:          map[string]string{"body": strings.Repeat("a", 141)},
+			authorization: bearer(user.Token),
+			want:          problemChirpTooLong,
+			wantFields:    []string{"body"},
+		},
+		{
+			name:   "missing token",
+			method: "GET",
+			target: "/api/sessions",
+			want:   problemMissingToken,
+		},
+		{
+			name:          "invalid token",
+			method:        "GET",
+			target:        "/api/sessions",
+			authorization: bearer("not-a-jwt"),
+			want:          problemInvalidToken,
+		},
+		{
+			name:          "expired token",
+			method:        "GET",
+			target:        "/api/sessions",
+			authorization: bearer(expired),
+			want:          problemTokenExpired,
+		},
+		{
+			name:          "unknown refresh token",
+			method:        "POST",
+			target:        "/api/revoke",
+			authorization: bearer("not-a-refresh-token"),
+			want:          problemInvalidToken,
+		},
+		{
+			name:   "wrong password",
+			method: "POST",
+			target: "/api/login",
+			body:   UserCredentials{Email: "jesse@example.com", Password: "wrong"},
+			want:   problemBadCredentials,
+		},
+		{
+			name:          "not an admin",
+			method:        "GET",
+			target:        "/admin/flagged",
+			authorization: bearer(user.Token),
+			want:          problemAdminRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := expectProblem(t, ts.do(tt.method, tt.target, tt.body, tt.authorization), tt.want)
+
+			if problem.Status != tt.want.status || problem.Instance != tt.target {
+				t.Errorf("problem = %+v", problem)
+			}
+
+			var fields []string
+			for _, fieldError := range problem.Errors {
+				fields = append(fields, fieldError.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("field errors = %+v, want fields %v", problem.Errors, tt.wantFields)
+			}
+		})
+	}
+}
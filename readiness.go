@@ -1,11 +1,120 @@
 package main
 
 import (
+	"context"
+	"log/slog"
 	"net/http"
+	"sync"
+	"time"
 )
 
-func HealthEndpoint(w http.ResponseWriter, r *http.Request) {
+// readinessCheckTimeout bounds every check, so a hanging dependency makes
+// the probe fail instead of time out.
+const readinessCheckTimeout = 2 * time.Second
+
+// HealthCheck is a dependency the server needs to serve traffic.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// CheckResult leaves out why a check failed: /api/readyz is public, so the
+// error is only logged.
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+type ReadinessReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// registerHealthCheck adds a dependency to /api/readyz.
+func (cfg *apiConfig) registerHealthCheck(name string, check func(ctx context.Context) error) {
+	cfg.healthChecks = append(cfg.healthChecks, HealthCheck{Name: name, Check: check})
+}
+
+// pingDatabase checks that the store answers.
+func (cfg *apiConfig) pingDatabase(ctx context.Context) error {
+	return cfg.db.Ping(ctx)
+}
+
+// checkSchemaVersion makes sure the migrations embedded in this build, and
+// no later ones, have been applied.
+func (cfg *apiConfig) checkSchemaVersion(ctx context.Context) error {
+	return verifySchemaVersion(ctx, cfg.migrations)
+}
+
+func (cfg *apiConfig) HealthEndpoint(w http.ResponseWriter, r *http.Request) {
+	// Tell load balancers to stop sending traffic while shutting down
+	if cfg.draining.Load() {
+		w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(http.StatusText(http.StatusServiceUnavailable)))
+		return
+	}
+
 	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(http.StatusText(http.StatusOK)))
 }
+
+// livenessHandler only says the process is up and serving. It doesn't look
+// at dependencies: restarting won't fix a database outage.
+func (cfg *apiConfig) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"ok"})
+}
+
+// readinessHandler runs every registered check concurrently and reports
+// each one. Any failure, or draining, makes the server not ready.
+func (cfg *apiConfig) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	report := ReadinessReport{
+		Status: "ok",
+		Checks: make(map[string]CheckResult, len(cfg.healthChecks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range cfg.healthChecks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(r.Context(), readinessCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(ctx)
+			result := CheckResult{
+				Status:    "ok",
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = "fail"
+				slog.ErrorContext(r.Context(), "Readiness check failed", "check", check.Name, "error", err)
+			}
+
+			mu.Lock()
+			report.Checks[check.Name] = result
+			if err != nil {
+				report.Status = "fail"
+			}
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	if cfg.draining.Load() {
+		report.Status = "draining"
+	}
+
+	code := http.StatusOK
+	if report.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+
+	respondWithJSON(w, code, report)
+}
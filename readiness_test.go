@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	cfg := &apiConfig{}
+	cfg.registerHealthCheck("database", func(ctx context.Context) error { return nil })
+
+	ready := func(t *testing.T, wantCode int, wantStatus string) ReadinessReport {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		cfg.readinessHandler(rec, httptest.NewRequest("GET", "/api/readyz", nil))
+		expectStatus(t, rec, wantCode)
+
+		report := decodeBody[ReadinessReport](t, rec)
+		if report.Status != wantStatus {
+			t.Errorf("status = %q, want %q", report.Status, wantStatus)
+		}
+		return report
+	}
+
+	if report := ready(t, http.StatusOK, "ok"); report.Checks["database"].Status != "ok" {
+		t.Errorf("database check = %+v, want ok", report.Checks["database"])
+	}
+
+	cfg.registerHealthCheck("mail", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	report := ready(t, http.StatusServiceUnavailable, "fail")
+	if got := report.Checks["mail"]; got.Status != "fail" {
+		t.Errorf("mail check = %+v, want the failure", got)
+	}
+	if report.Checks["database"].Status != "ok" {
+		t.Errorf("database check = %+v, want ok", report.Checks["database"])
+	}
+
+	cfg.draining.Store(true)
+	ready(t, http.StatusServiceUnavailable, "draining")
+}
@@ -6,13 +6,13 @@ import (
 
 func (cfg *apiConfig) ResetMetricsHandler(w http.ResponseWriter, r *http.Request) {
 	if !(cfg.platform == "" || cfg.platform == "dev") {
-		w.WriteHeader(http.StatusForbidden)
+		respondWithError(w, r, problemForbidden, "Reset is only allowed in dev", nil)
 		return
 	}
 
 	err := cfg.db.DropUsers(r.Context())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		respondWithError(w, r, problemInternal, "Couldn't reset the database", err)
 		return
 	}
 
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/auth"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+// scheduledChirpInterval is how often due scheduled chirps are published.
+const scheduledChirpInterval = time.Minute
+
+type ScheduledChirp struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Body      string    `json:"body"`
+	UserID    uuid.UUID `json:"user_id"`
+	PublishAt time.Time `json:"publish_at"`
+}
+
+func scheduledChirpFromDB(chirp database.ScheduledChirp) ScheduledChirp {
+	return ScheduledChirp{
+		ID:        chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		Body:      chirp.Body,
+		UserID:    chirp.UserID,
+		PublishAt: chirp.PublishAt,
+	}
+}
+
+// scheduleChirp stores an already moderated chirp to be published later.
+func (cfg *apiConfig) scheduleChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID, entitlements Entitlements, body string, flagMatches []string, publishAt time.Time, inReplyTo string) {
+	if !entitlements.Can(FeatureScheduledPosts) {
+		respondWithError(w, r, problemRedRequired, "This feature requires Chirpy Red", nil)
+		return
+	}
+
+	if inReplyTo != "" {
+		respondWithFieldErrors(w, r, problemValidation, FieldError{Field: "in_reply_to", Code: "not_allowed", Detail: "Replies can't be scheduled"})
+		return
+	}
+
+	if !publishAt.After(time.Now()) {
+		respondWithFieldErrors(w, r, problemValidation, FieldError{Field: "publish_at", Code: "invalid", Detail: "publish_at must be in the future"})
+		return
+	}
+
+	// publish_at has no time zone and is compared with NOW() in UTC
+	scheduled, err := cfg.db.CreateScheduledChirp(r.Context(), database.CreateScheduledChirpParams{
+		UserID:       userID,
+		Body:         body,
+		MatchedWords: strings.Join(flagMatches, ","),
+		PublishAt:    publishAt.UTC(),
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't schedule chirp", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, scheduledChirpFromDB(scheduled))
+}
+
+func (cfg *apiConfig) getScheduledChirps(w http.ResponseWriter, r *http.Request) {
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return
+	}
+
+	scheduled, err := cfg.db.GetScheduledChirps(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve scheduled chirps", err)
+		return
+	}
+
+	jsonChirps := make([]ScheduledChirp, 0)
+	for _, chirp := range scheduled {
+		jsonChirps = append(jsonChirps, scheduledChirpFromDB(chirp))
+	}
+
+	respondWithJSON(w, http.StatusOK, jsonChirps)
+}
+
+func (cfg *apiConfig) deleteScheduledChirp(w http.ResponseWriter, r *http.Request) {
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return
+	}
+
+	scheduledUUID, err := uuid.Parse(r.PathValue("scheduledID"))
+
+	if err != nil {
+		respondWithError(w, r, problemInvalidID, "Scheduled chirp UUID is not in the correct format", err)
+		return
+	}
+
+	deleted, err := cfg.db.DeleteScheduledChirp(r.Context(), database.DeleteScheduledChirpParams{
+		ID:     scheduledUUID,
+		UserID: userID,
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't delete scheduled chirp", err)
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, r, problemNotFound, "Scheduled chirp not found", nil)
+		return
+	}
+
+	respondWithNoContent(w)
+}
+
+// publishScheduledChirps turns every due scheduled chirp into a real one.
+// Claiming and publishing share a transaction, so a failure leaves the
+// chirps scheduled for the next run.
+func (cfg *apiConfig) publishScheduledChirps(ctx context.Context) (int, error) {
+	tx, err := cfg.db.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	due, err := tx.ClaimDueScheduledChirps(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	published := make([]chirpRow, 0, len(due))
+	for _, scheduled := range due {
+		chirp, err := tx.CreateChirp(ctx, database.CreateChirpParams{
+			Body:   scheduled.Body,
+			UserID: scheduled.UserID,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		if scheduled.MatchedWords != "" {
+			err = flagChirp(ctx, tx, chirp.ID, strings.Split(scheduled.MatchedWords, ","))
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		published = append(published, chirp)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	cfg.metrics.chirpsCreated.Add(float64(len(published)))
+	for _, chirp := range published {
+		cfg.publishEvent(ctx, eventChirpCreated, []uuid.UUID{chirp.UserID}, chirpFromDB(chirp))
+	}
+
+	return len(published), nil
+}
+
+// runScheduledChirpPublisher publishes due chirps every interval until ctx
+// is done.
+func (cfg *apiConfig) runScheduledChirpPublisher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			published, err := cfg.publishScheduledChirps(ctx)
+			if err != nil {
+				slog.Error("Couldn't publish scheduled chirps", "error", err)
+				continue
+			}
+			if published > 0 {
+				slog.Info("Published scheduled chirps", "count", published)
+			}
+		}
+	}
+}
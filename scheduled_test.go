@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func TestScheduledChirps(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+	scheduled := map[string]interface{}{"body": "Later", "publish_at": time.Now().Add(time.Hour)}
+
+	expectStatus(t, ts.do("POST", "/api/chirps", scheduled, bearer(user.Token)), http.StatusForbidden)
+
+	ts.upgrade(user.ID)
+
+	past := map[string]interface{}{"body": "Earlier", "publish_at": time.Now().Add(-time.Hour)}
+	expectStatus(t, ts.do("POST", "/api/chirps", past, bearer(user.Token)), http.StatusBadRequest)
+
+	rec := ts.do("POST", "/api/chirps", scheduled, bearer(user.Token))
+	expectStatus(t, rec, http.StatusAccepted)
+	created := decodeBody[ScheduledChirp](t, rec)
+
+	expectStatus(t, ts.do("GET", "/api/scheduled_chirps", nil, ""), http.StatusUnauthorized)
+
+	rec = ts.do("GET", "/api/scheduled_chirps", nil, bearer(user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	if list := decodeBody[[]ScheduledChirp](t, rec); len(list) != 1 || list[0].ID != created.ID {
+		t.Errorf("scheduled chirps = %s", rec.Body.String())
+	}
+
+	expectStatus(t, ts.do("DELETE", "/api/scheduled_chirps/"+uuid.NewString(), nil, bearer(user.Token)), http.StatusNotFound)
+	expectStatus(t, ts.do("DELETE", "/api/scheduled_chirps/"+created.ID.String(), nil, bearer(user.Token)), http.StatusNoContent)
+
+	rec = ts.do("GET", "/api/scheduled_chirps", nil, bearer(user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	if list := decodeBody[[]ScheduledChirp](t, rec); len(list) != 0 {
+		t.Errorf("scheduled chirps after delete = %s", rec.Body.String())
+	}
+}
+
+func TestScheduledChirpWithOffset(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+	ts.upgrade(user.ID)
+
+	// Two hours from now, written in a zone five hours ahead of UTC
+	publishAt := time.Now().Add(2 * time.Hour).Truncate(time.Second).In(time.FixedZone("UTC+5", 5*60*60))
+	rec := ts.do("POST", "/api/chirps", map[string]string{"body": "Later", "publish_at": publishAt.Format(time.RFC3339)}, bearer(user.Token))
+	expectStatus(t, rec, http.StatusAccepted)
+
+	created := decodeBody[ScheduledChirp](t, rec)
+	if !created.PublishAt.Equal(publishAt) || created.PublishAt.Location() != time.UTC {
+		t.Errorf("publish_at = %v, want %v in UTC", created.PublishAt, publishAt.UTC())
+	}
+
+	stored, err := ts.db.GetScheduledChirps(context.Background(), user.ID)
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("GetScheduledChirps() = %v, %v", stored, err)
+	}
+	if !stored[0].PublishAt.Equal(publishAt) || stored[0].PublishAt.Location() != time.UTC {
+		t.Errorf("stored publish_at = %v, want %v in UTC", stored[0].PublishAt, publishAt.UTC())
+	}
+
+	published, err := ts.cfg.publishScheduledChirps(context.Background())
+	if err != nil || published != 0 {
+		t.Errorf("publishScheduledChirps() = %d, %v, want nothing published yet", published, err)
+	}
+}
+
+func TestPublishScheduledChirps(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	for _, publishAt := range []time.Time{time.Now().UTC().Add(-time.Minute), time.Now().UTC().Add(time.Hour)} {
+		_, err := ts.db.CreateScheduledChirp(context.Background(), database.CreateScheduledChirpParams{
+			UserID:    user.ID,
+			Body:      "Scheduled",
+			PublishAt: publishAt,
+		})
+		if err != nil {
+			t.Fatalf("CreateScheduledChirp() error = %v", err)
+		}
+	}
+
+	published, err := ts.cfg.publishScheduledChirps(context.Background())
+	if err != nil {
+		t.Fatalf("publishScheduledChirps() error = %v", err)
+	}
+	if published != 1 {
+		t.Errorf("published %d chirps, want 1", published)
+	}
+
+	rec := ts.do("GET", "/api/chirps", nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	if page := decodeBody[ChirpsPage](t, rec); len(page.Chirps) != 1 || page.Chirps[0].Body != "Scheduled" {
+		t.Errorf("chirps = %s", rec.Body.String())
+	}
+
+	rec = ts.do("GET", "/api/scheduled_chirps", nil, bearer(user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	if list := decodeBody[[]ScheduledChirp](t, rec); len(list) != 1 {
+		t.Errorf("got %d scheduled chirps left, want 1", len(list))
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+// parseSearchQuery splits a search string into the full-text part and the
+// value of an optional from: filter. Everything else, including quoted
+// phrases and -exclusions, is left for websearch_to_tsquery to interpret.
+func parseSearchQuery(q string) (string, string) {
+	from := ""
+	terms := make([]string, 0)
+
+	for _, term := range strings.Fields(q) {
+		if strings.HasPrefix(strings.ToLower(term), "from:") {
+			from = term[len("from:"):]
+			continue
+		}
+		terms = append(terms, term)
+	}
+
+	return strings.Join(terms, " "), from
+}
+
+func (cfg *apiConfig) searchChirps(w http.ResponseWriter, r *http.Request) {
+	text, from := parseSearchQuery(r.URL.Query().Get("q"))
+
+	if text == "" {
+		respondWithError(w, r, problemInvalidQuery, "Please provide a search query", fmt.Errorf("search query not provided"))
+		return
+	}
+
+	order := r.URL.Query().Get("order")
+
+	pageOrder := orderByTime
+	switch order {
+	case "", "relevance":
+		pageOrder = orderByRank
+	case "recency":
+	default:
+		respondWithError(w, r, problemInvalidQuery, "order must be relevance or recency", fmt.Errorf("unknown order %q", order))
+		return
+	}
+
+	page, err := parsePageParams(r, pageOrder)
+	if err != nil {
+		respondWithError(w, r, problemInvalidQuery, "Invalid pagination parameters", err)
+		return
+	}
+
+	// from: accepts either a user ID or an email address
+	author := uuid.NullUUID{}
+	if from != "" {
+		authorUUID, err := uuid.Parse(from)
+
+		if err != nil {
+			user, err := cfg.userByEmail(r.Context(), from)
+
+			if err != nil {
+				// Nobody to search from, so there can't be any results
+				cfg.respondWithFeed(w, r, []feedItem{}, nil, page)
+				return
+			}
+
+			authorUUID = user.ID
+		}
+
+		author = uuid.NullUUID{UUID: authorUUID, Valid: true}
+	}
+
+	if pageOrder == orderByRank {
+		rows, err := cfg.db.SearchChirpsByRelevance(r.Context(), database.SearchChirpsByRelevanceParams{
+			Query:      text,
+			AuthorID:   author,
+			CursorRank: page.cursorRank(),
+			CursorID:   page.cursorID(),
+			PageLimit:  page.queryLimit(),
+		})
+
+		items := make([]feedItem, 0, len(rows))
+		for _, row := range rows {
+			items = append(items, feedItem{
+				Chirp:  rowChirp(row.ID, row.CreatedAt, row.UpdatedAt, row.Body, row.UserID, row.ParentID, row.RootID, row.DeletedAt),
+				FeedAt: row.CreatedAt,
+				Rank:   row.Rank,
+			})
+		}
+
+		cfg.respondWithFeed(w, r, items, err, page)
+		return
+	}
+
+	chirps, err := cfg.db.SearchChirpsByRecency(r.Context(), database.SearchChirpsByRecencyParams{
+		Query:           text,
+		AuthorID:        author,
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		PageLimit:       page.queryLimit(),
+	})
+
+	cfg.respondWithFeed(w, r, feedItemsFromChirps(chirps), err, page)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		wantText string
+		wantFrom string
+	}{
+		{query: "knock knock", wantText: "knock knock"},
+		{query: `"say my name" -heisenberg`, wantText: `"say my name" -heisenberg`},
+		{query: "knock from:walt@example.com", wantText: "knock", wantFrom: "walt@example.com"},
+		{query: "FROM:walt@example.com  who   knocks", wantText: "who knocks", wantFrom: "walt@example.com"},
+		{query: "from:walt@example.com", wantText: "", wantFrom: "walt@example.com"},
+	}
+
+	for _, tt := range tests {
+		text, from := parseSearchQuery(tt.query)
+		if text != tt.wantText || from != tt.wantFrom {
+			t.Errorf("parseSearchQuery(%q) = %q, %q, want %q, %q", tt.query, text, from, tt.wantText, tt.wantFrom)
+		}
+	}
+}
+
+func TestSearchChirps(t *testing.T) {
+	ts := newTestServer(t)
+	walt := ts.signUp("walt@example.com")
+	jesse := ts.signUp("jesse@example.com")
+
+	ts.createChirp(walt.Token, "I am the one who knocks")
+	ts.createChirp(jesse.Token, "Knock knock, who is there")
+	ts.createChirp(walt.Token, "Say my name")
+
+	tests := []struct {
+		name  string
+		query string
+		order string
+		want  int
+	}{
+		{name: "single word", query: "knocks", want: 2},
+		{name: "all words must match", query: "who name", want: 0},
+		{name: "exclusion", query: "knock -there", want: 1},
+		{name: "from filter", query: "who from:" + jesse.ID.String(), want: 1},
+		{name: "by recency", query: "knock", order: "recency", want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{"q": {tt.query}}
+			if tt.order != "" {
+				query.Set("order", tt.order)
+			}
+
+			rec := ts.do("GET", "/api/chirps/search?"+query.Encode(), nil, "")
+			expectStatus(t, rec, http.StatusOK)
+			if page := decodeBody[ChirpsPage](t, rec); len(page.Chirps) != tt.want {
+				t.Errorf("got %d chirps, want %d: %s", len(page.Chirps), tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	expectStatus(t, ts.do("GET", "/api/chirps/search", nil, ""), http.StatusBadRequest)
+	expectStatus(t, ts.do("GET", "/api/chirps/search?q=knock&order=nope", nil, ""), http.StatusBadRequest)
+}
+
+func TestSearchCursorOrder(t *testing.T) {
+	ts := newTestServer(t)
+	walt := ts.signUp("walt@example.com")
+
+	ts.createChirp(walt.Token, "Knock knock")
+	ts.createChirp(walt.Token, "Who knocks")
+
+	nextCursor := func(order string) string {
+		t.Helper()
+
+		query := url.Values{"q": {"knock"}, "limit": {"1"}, "order": {order}}
+		rec := ts.do("GET", "/api/chirps/search?"+query.Encode(), nil, "")
+		expectStatus(t, rec, http.StatusOK)
+
+		page := decodeBody[ChirpsPage](t, rec)
+		if page.NextCursor == "" {
+			t.Fatalf("no next cursor for the %s order", order)
+		}
+		return page.NextCursor
+	}
+
+	relevance := nextCursor("relevance")
+	recency := nextCursor("recency")
+
+	rec := ts.do("GET", "/api/chirps/search?q=knock&order=relevance&cursor="+url.QueryEscape(relevance), nil, "")
+	expectStatus(t, rec, http.StatusOK)
+	if page := decodeBody[ChirpsPage](t, rec); len(page.Chirps) != 1 {
+		t.Errorf("got %d chirps on the second page, want 1", len(page.Chirps))
+	}
+
+	expectProblem(t, ts.do("GET", "/api/chirps/search?q=knock&order=relevance&cursor="+url.QueryEscape(recency), nil, ""), problemInvalidQuery)
+	expectProblem(t, ts.do("GET", "/api/chirps/search?q=knock&order=recency&cursor="+url.QueryEscape(relevance), nil, ""), problemInvalidQuery)
+	expectProblem(t, ts.do("GET", "/api/chirps?cursor="+url.QueryEscape(relevance), nil, ""), problemInvalidQuery)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/auth"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+// Session is one login, tracked as a refresh token family.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (cfg *apiConfig) getSessions(w http.ResponseWriter, r *http.Request) {
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return
+	}
+
+	sessions, err := cfg.db.GetUserSessions(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve sessions", err)
+		return
+	}
+
+	jsonSessions := make([]Session, 0)
+	for _, session := range sessions {
+		jsonSessions = append(jsonSessions, Session{
+			ID:         session.FamilyID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IpAddress,
+			CreatedAt:  session.SignedInAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+		})
+	}
+
+	respondWithJSON(w, http.StatusOK, jsonSessions)
+}
+
+func (cfg *apiConfig) revokeSession(w http.ResponseWriter, r *http.Request) {
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return
+	}
+
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+
+	if err != nil {
+		respondWithError(w, r, problemInvalidID, "Session ID is not in the correct format", err)
+		return
+	}
+
+	revoked, err := cfg.db.RevokeUserRefreshTokenFamily(r.Context(), database.RevokeUserRefreshTokenFamilyParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Session couldn't be revoked", err)
+		return
+	}
+
+	if revoked == 0 {
+		respondWithError(w, r, problemNotFound, "Session not found", nil)
+		return
+	}
+
+	respondWithNoContent(w)
+}
+
+// revokeAllSessions logs the caller out everywhere.
+func (cfg *apiConfig) revokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return
+	}
+
+	err = cfg.db.RevokeUserRefreshTokens(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Sessions couldn't be revoked", err)
+		return
+	}
+
+	respondWithNoContent(w)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSessions(t *testing.T) {
+	ts := newTestServer(t)
+	first := ts.signUp("walt@example.com")
+	second := ts.login("walt@example.com", "hunter2")
+
+	expectStatus(t, ts.do("GET", "/api/sessions", nil, ""), http.StatusUnauthorized)
+
+	rec := ts.do("GET", "/api/sessions", nil, bearer(first.Token))
+	expectStatus(t, rec, http.StatusOK)
+	sessions := decodeBody[[]Session](t, rec)
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(sessions))
+	}
+
+	expectStatus(t, ts.do("DELETE", "/api/sessions/"+uuid.NewString(), nil, bearer(first.Token)), http.StatusNotFound)
+	expectStatus(t, ts.do("DELETE", "/api/sessions/not-a-uuid", nil, bearer(first.Token)), http.StatusBadRequest)
+	expectStatus(t, ts.do("DELETE", "/api/sessions/"+sessions[0].ID.String(), nil, bearer(first.Token)), http.StatusNoContent)
+
+	rec = ts.do("GET", "/api/sessions", nil, bearer(first.Token))
+	expectStatus(t, rec, http.StatusOK)
+	if remaining := decodeBody[[]Session](t, rec); len(remaining) != 1 || remaining[0].ID == sessions[0].ID {
+		t.Errorf("sessions after revoking one = %+v", remaining)
+	}
+
+	expectStatus(t, ts.do("DELETE", "/api/sessions", nil, bearer(second.Token)), http.StatusNoContent)
+
+	rec = ts.do("GET", "/api/sessions", nil, bearer(second.Token))
+	expectStatus(t, rec, http.StatusOK)
+	if remaining := decodeBody[[]Session](t, rec); len(remaining) != 0 {
+		t.Errorf("got %d sessions after revoking all, want 0", len(remaining))
+	}
+	expectStatus(t, ts.do("POST", "/api/refresh", nil, bearer(second.RefreshToken)), http.StatusUnauthorized)
+}
+
+func TestSessionExpiryWestOfUTC(t *testing.T) {
+	westOfUTC(t)
+
+	ts := newTestServer(t)
+	start := time.Now()
+	user := ts.signUp("walt@example.com")
+
+	rec := ts.do("GET", "/api/sessions", nil, bearer(user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	sessions := decodeBody[[]Session](t, rec)
+	if len(sessions) != 1 {
+		t.Fatalf("got %d sessions, want 1", len(sessions))
+	}
+	if got := sessions[0].ExpiresAt.Sub(start); got < refreshTokenExpiration-time.Minute || got > refreshTokenExpiration+time.Minute {
+		t.Errorf("session expires %v from now, want %v", got, refreshTokenExpiration)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+// SiteSettings are the knobs admins can turn while the server runs.
+type SiteSettings struct {
+	AllowUnverifiedChirps bool `json:"allow_unverified_chirps"`
+}
+
+func newSiteSettings(settings database.SiteSetting) SiteSettings {
+	return SiteSettings{
+		AllowUnverifiedChirps: settings.AllowUnverifiedChirps,
+	}
+}
+
+func (cfg *apiConfig) getSiteSettings(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		respondWithError(w, r, problemAdminRequired, "Admin access required", nil)
+		return
+	}
+
+	settings, err := cfg.db.GetSiteSettings(r.Context())
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve settings", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newSiteSettings(settings))
+}
+
+func (cfg *apiConfig) updateSiteSettings(w http.ResponseWriter, r *http.Request) {
+	type settingsData struct {
+		AllowUnverifiedChirps *bool `json:"allow_unverified_chirps"`
+	}
+
+	if !cfg.isAdmin(r) {
+		respondWithError(w, r, problemAdminRequired, "Admin access required", nil)
+		return
+	}
+
+	params := settingsData{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	var v validation
+	if params.AllowUnverifiedChirps == nil {
+		v.add("allow_unverified_chirps", "required", "allow_unverified_chirps is required")
+	}
+	if v.failed(w, r) {
+		return
+	}
+
+	settings, err := cfg.db.UpdateSiteSettings(r.Context(), *params.AllowUnverifiedChirps)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't update settings", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, newSiteSettings(settings))
+}
@@ -1,28 +1,85 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_id, root_id)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
-RETURNING *;
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at;
 
 -- name: DropChirps :exec
 DELETE FROM chirps;
 
--- name: GetChirps :many
-SELECT * FROM chirps
-ORDER BY created_at;
+-- name: GetChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
+WHERE deleted_at IS NULL
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_limit')::int;
 
--- name: GetChirpsByAuthor :many
-SELECT * FROM chirps where user_id = $1
-ORDER BY created_at;
+-- name: GetChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
+WHERE deleted_at IS NULL
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit')::int;
+
+-- name: GetChirpsByAuthorAsc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, feed_at, rechirped_by FROM (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, chirps.created_at AS feed_at, NULL::uuid AS rechirped_by FROM chirps
+    WHERE chirps.user_id = sqlc.arg('user_id')
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, rechirps.created_at AS feed_at, rechirps.user_id AS rechirped_by FROM rechirps
+    JOIN chirps ON chirps.id = rechirps.chirp_id
+    WHERE rechirps.user_id = sqlc.arg('user_id')
+) AS feed
+WHERE deleted_at IS NULL
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (feed_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY feed_at ASC, id ASC
+LIMIT sqlc.arg('page_limit')::int;
+
+-- name: GetChirpsByAuthorDesc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, feed_at, rechirped_by FROM (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, chirps.created_at AS feed_at, NULL::uuid AS rechirped_by FROM chirps
+    WHERE chirps.user_id = sqlc.arg('user_id')
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, rechirps.created_at AS feed_at, rechirps.user_id AS rechirped_by FROM rechirps
+    JOIN chirps ON chirps.id = rechirps.chirp_id
+    WHERE rechirps.user_id = sqlc.arg('user_id')
+) AS feed
+WHERE deleted_at IS NULL
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (feed_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY feed_at DESC, id DESC
+LIMIT sqlc.arg('page_limit')::int;
 
 -- name: GetChirp :one
-SELECT * FROM chirps
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
 WHERE id = $1;
 
 -- name: DeleteChirp :exec
 DELETE FROM chirps WHERE id = $1;
+
+-- name: DeleteChirpWithoutReplies :execrows
+DELETE FROM chirps WHERE id = $1
+    AND NOT EXISTS (SELECT 1 FROM chirps AS replies WHERE replies.parent_id = $1);
+
+-- name: UpdateChirpBody :one
+UPDATE chirps SET body = $2, updated_at = NOW() WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at;
+
+-- name: TombstoneChirp :one
+UPDATE chirps SET body = '', deleted_at = NOW(), updated_at = NOW() WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at;
+
+-- name: GetThread :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
+WHERE id = sqlc.arg('root_id') OR root_id = sqlc.arg('root_id')
+ORDER BY created_at ASC, id ASC;
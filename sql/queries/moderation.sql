@@ -0,0 +1,31 @@
+-- name: GetBadWords :many
+SELECT word FROM bad_words
+ORDER BY word;
+
+-- name: CreateBadWord :exec
+INSERT INTO bad_words (word, created_at)
+VALUES (
+    $1,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteBadWord :execrows
+DELETE FROM bad_words WHERE word = $1;
+
+-- name: CreateChirpFlag :exec
+INSERT INTO chirp_flags (chirp_id, matched_words, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (chirp_id) DO UPDATE SET matched_words = EXCLUDED.matched_words, created_at = NOW();
+
+-- name: GetFlaggedChirps :many
+SELECT chirps.id, chirps.created_at, chirps.body, chirps.user_id, chirp_flags.matched_words, chirp_flags.created_at AS flagged_at FROM chirp_flags
+JOIN chirps ON chirps.id = chirp_flags.chirp_id
+ORDER BY chirp_flags.created_at;
+
+-- name: DeleteChirpFlag :execrows
+DELETE FROM chirp_flags WHERE chirp_id = $1;
@@ -0,0 +1,22 @@
+-- name: SearchChirpsByRecency :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at FROM chirps
+WHERE search_vector @@ websearch_to_tsquery('english', sqlc.arg('query'))
+    AND deleted_at IS NULL
+    AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+    AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+        OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit')::int;
+
+-- name: SearchChirpsByRelevance :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, rank FROM (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, ts_rank(chirps.search_vector, websearch_to_tsquery('english', sqlc.arg('query')))::real AS rank
+    FROM chirps
+    WHERE chirps.search_vector @@ websearch_to_tsquery('english', sqlc.arg('query'))
+        AND chirps.deleted_at IS NULL
+        AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+) AS results
+WHERE sqlc.narg('cursor_rank')::real IS NULL
+    OR (rank, id) < (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_id')::uuid)
+ORDER BY rank DESC, id DESC
+LIMIT sqlc.arg('page_limit')::int;
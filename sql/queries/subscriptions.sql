@@ -0,0 +1,30 @@
+-- name: GetSubscription :one
+SELECT * FROM subscriptions
+WHERE user_id = $1;
+
+-- name: UpsertSubscription :exec
+INSERT INTO subscriptions (user_id, plan, status, current_period_end, grace_period_end, canceled_at, created_at, updated_at)
+VALUES (
+    $1,
+    $2,
+    'active',
+    $3,
+    NULL,
+    NULL,
+    NOW(),
+    NOW()
+)
+ON CONFLICT (user_id) DO UPDATE SET plan = EXCLUDED.plan, status = 'active', current_period_end = EXCLUDED.current_period_end,
+grace_period_end = NULL, canceled_at = NULL, updated_at = NOW();
+
+-- name: RenewSubscription :exec
+UPDATE subscriptions SET status = 'active', current_period_end = $2, grace_period_end = NULL, updated_at = NOW()
+WHERE user_id = $1;
+
+-- name: MarkSubscriptionPastDue :exec
+UPDATE subscriptions SET status = 'past_due', grace_period_end = $2, updated_at = NOW()
+WHERE user_id = $1;
+
+-- name: CancelSubscription :exec
+UPDATE subscriptions SET status = 'canceled', canceled_at = NOW(), updated_at = NOW()
+WHERE user_id = $1;
@@ -0,0 +1,61 @@
+-- name: UpsertUserTOTP :exec
+INSERT INTO user_totp (user_id, secret, created_at, enabled_at, last_step)
+VALUES (
+    $1,
+    $2,
+    NOW(),
+    NULL,
+    0
+)
+ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = NOW(), enabled_at = NULL, last_step = 0;
+
+-- name: GetUserTOTP :one
+SELECT * FROM user_totp
+WHERE user_id = $1;
+
+-- name: EnableUserTOTP :exec
+UPDATE user_totp SET enabled_at = NOW() WHERE user_id = $1;
+
+-- name: UpdateUserTOTPLastStep :execrows
+UPDATE user_totp SET last_step = sqlc.arg('last_step')
+WHERE user_id = sqlc.arg('user_id') AND last_step < sqlc.arg('last_step');
+
+-- name: DeleteUserTOTP :exec
+DELETE FROM user_totp WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (id, user_id, code_hash, created_at, used_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    NOW(),
+    NULL
+);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CreateTwoFactorChallenge :exec
+INSERT INTO two_factor_challenges (id, user_id, attempts, created_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    0,
+    NOW(),
+    $3
+);
+
+-- name: CountTwoFactorAttempt :execrows
+UPDATE two_factor_challenges SET attempts = attempts + 1
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND expires_at > NOW() AND attempts < sqlc.arg('max_attempts');
+
+-- name: DeleteTwoFactorChallenge :execrows
+DELETE FROM two_factor_challenges WHERE id = $1;
+
+-- name: DeleteExpiredTwoFactorChallenges :exec
+DELETE FROM two_factor_challenges WHERE user_id = $1 AND expires_at <= NOW();
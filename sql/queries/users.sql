@@ -1,26 +1,29 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password, is_chirpy_red)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2,
-    false
+    $2
 )
 RETURNING *;
 
 -- name: DropUsers :exec
 DELETE FROM users;
 
+-- name: GetUser :one
+SELECT * FROM users
+WHERE id = $1;
+
 -- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, email, hashed_password, email_verified_at FROM users
 WHERE email = $1;
 
 -- name: UpdateUser :one
 UPDATE users set email = $1, hashed_password = $2 WHERE id = $3
 RETURNING *;
 
--- name: UpdateUserChirpyRed :one
-UPDATE users set is_chirpy_red = true where id = $1
-RETURNING *;
+
+-- name: UpdateUserPassword :exec
+UPDATE users SET hashed_password = $2, updated_at = NOW() WHERE id = $1;
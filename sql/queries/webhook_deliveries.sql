@@ -0,0 +1,53 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (id, user_id, url, secret, events, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetWebhookEndpoint :one
+SELECT * FROM webhook_endpoints
+WHERE id = $1;
+
+-- name: GetWebhookEndpoints :many
+SELECT * FROM webhook_endpoints
+WHERE user_id IS NOT DISTINCT FROM sqlc.narg('user_id')
+ORDER BY created_at ASC;
+
+-- name: DeleteWebhookEndpoint :exec
+DELETE FROM webhook_endpoints WHERE id = $1;
+
+-- name: EnqueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (id, endpoint_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at)
+SELECT gen_random_uuid(), webhook_endpoints.id, sqlc.arg('event_type')::text, sqlc.arg('payload')::text, 'pending', 0, NOW(), 0, '', NOW(), NULL
+FROM webhook_endpoints
+WHERE sqlc.arg('event_type')::text = ANY(string_to_array(webhook_endpoints.events, ','))
+    AND (webhook_endpoints.user_id IS NULL OR webhook_endpoints.user_id = ANY(sqlc.arg('user_ids')::uuid[]));
+
+-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries SET next_attempt_at = sqlc.arg('lease_until')
+FROM webhook_endpoints
+WHERE webhook_endpoints.id = webhook_deliveries.endpoint_id
+    AND webhook_deliveries.id IN (
+        SELECT id FROM webhook_deliveries AS due
+        WHERE due.status = 'pending' AND due.next_attempt_at <= NOW()
+        ORDER BY due.next_attempt_at
+        LIMIT sqlc.arg('batch_size')::int
+        FOR UPDATE SKIP LOCKED
+    )
+RETURNING webhook_deliveries.*, webhook_endpoints.url, webhook_endpoints.secret;
+
+-- name: RecordWebhookDeliveryAttempt :exec
+UPDATE webhook_deliveries SET status = $2, attempts = attempts + 1, next_attempt_at = $3, last_status_code = $4, last_error = $5, delivered_at = $6
+WHERE id = $1;
+
+-- name: GetWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT 100;
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN parent_id uuid REFERENCES chirps(id) ON DELETE SET NULL;
+ALTER TABLE chirps ADD COLUMN root_id uuid REFERENCES chirps(id) ON DELETE SET NULL;
+ALTER TABLE chirps ADD COLUMN deleted_at timestamp; -- Set when a chirp with replies is tombstoned
+
+CREATE INDEX chirps_parent_id_idx ON chirps (parent_id);
+CREATE INDEX chirps_root_id_idx ON chirps (root_id);
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN deleted_at;
+ALTER TABLE chirps DROP COLUMN root_id;
+ALTER TABLE chirps DROP COLUMN parent_id;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE chirp_likes(
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  chirp_id uuid NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  created_at timestamp NOT NULL,
+  PRIMARY KEY (user_id, chirp_id)
+);
+
+CREATE INDEX chirp_likes_chirp_id_idx ON chirp_likes (chirp_id);
+
+CREATE TABLE rechirps(
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  chirp_id uuid NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  created_at timestamp NOT NULL,
+  PRIMARY KEY (user_id, chirp_id)
+);
+
+CREATE INDEX rechirps_chirp_id_idx ON rechirps (chirp_id);
+CREATE INDEX rechirps_user_id_created_at_idx ON rechirps (user_id, created_at);
+
+-- +goose Down
+DROP TABLE rechirps;
+DROP TABLE chirp_likes;
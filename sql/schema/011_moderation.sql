@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE bad_words(
+  word text PRIMARY KEY,
+  created_at timestamp NOT NULL
+);
+
+INSERT INTO bad_words (word, created_at) VALUES
+  ('kerfuffle', NOW()),
+  ('sharbert', NOW()),
+  ('fornax', NOW());
+
+CREATE TABLE chirp_flags(
+  chirp_id uuid PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+  matched_words text NOT NULL,
+  created_at timestamp NOT NULL
+);
+
+-- +goose Down
+DROP TABLE chirp_flags;
+DROP TABLE bad_words;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE refresh_tokens ADD COLUMN family_id uuid; -- Add as nullable first
+UPDATE refresh_tokens SET family_id = gen_random_uuid(); -- Every existing token starts its own family
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL; -- Then make it NOT NULL
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+-- +goose Down
+ALTER TABLE refresh_tokens DROP COLUMN family_id;
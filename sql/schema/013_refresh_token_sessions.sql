@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE refresh_tokens ADD COLUMN user_agent text NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN ip_address text NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN last_used_at timestamp; -- Add as nullable first
+UPDATE refresh_tokens SET last_used_at = updated_at; -- Best guess for existing rows
+ALTER TABLE refresh_tokens ALTER COLUMN last_used_at SET NOT NULL; -- Then make it NOT NULL
+
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+-- +goose Down
+ALTER TABLE refresh_tokens DROP COLUMN last_used_at;
+ALTER TABLE refresh_tokens DROP COLUMN ip_address;
+ALTER TABLE refresh_tokens DROP COLUMN user_agent;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE user_totp(
+  user_id uuid PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  secret text NOT NULL,
+  created_at timestamp NOT NULL,
+  enabled_at timestamp, -- NULL until the user confirms enrollment with a code
+  last_step bigint NOT NULL -- Last accepted time step, so a code can't be used twice
+);
+
+CREATE TABLE recovery_codes(
+  id uuid PRIMARY KEY,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash text NOT NULL,
+  created_at timestamp NOT NULL,
+  used_at timestamp
+);
+
+CREATE INDEX recovery_codes_user_id_idx ON recovery_codes (user_id);
+
+-- +goose Down
+DROP TABLE recovery_codes;
+DROP TABLE user_totp;
@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE webhook_events(
+  id text PRIMARY KEY, -- The provider's event ID, so a redelivered event is recognized
+  event_type text NOT NULL,
+  payload text NOT NULL, -- Raw request body, exactly as it was signed
+  status text NOT NULL, -- received, processed, ignored or failed
+  last_error text NOT NULL,
+  attempts integer NOT NULL,
+  received_at timestamp NOT NULL,
+  processed_at timestamp
+);
+
+CREATE INDEX webhook_events_status_idx ON webhook_events (status, received_at);
+
+-- +goose Down
+DROP TABLE webhook_events;
@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE subscriptions(
+  user_id uuid PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  plan text NOT NULL,
+  status text NOT NULL, -- active, past_due or canceled
+  current_period_end timestamp NOT NULL,
+  grace_period_end timestamp, -- Set when a payment fails, access continues until then
+  canceled_at timestamp,
+  created_at timestamp NOT NULL,
+  updated_at timestamp NOT NULL
+);
+
+-- Existing Chirpy Red users keep their upgrade for one more period
+INSERT INTO subscriptions (user_id, plan, status, current_period_end, created_at, updated_at)
+SELECT id, 'chirpy_red', 'active', NOW() + interval '30 days', NOW(), NOW() FROM users
+WHERE is_chirpy_red;
+
+ALTER TABLE users DROP COLUMN is_chirpy_red; -- Now derived from the subscription
+
+-- +goose Down
+ALTER TABLE users ADD COLUMN is_chirpy_red boolean; -- Add as nullable first
+UPDATE users SET is_chirpy_red = EXISTS (
+  SELECT 1 FROM subscriptions WHERE subscriptions.user_id = users.id AND subscriptions.current_period_end > NOW()
+);
+ALTER TABLE users ALTER COLUMN is_chirpy_red SET NOT NULL; -- Then make it NOT NULL
+
+DROP TABLE subscriptions;
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE scheduled_chirps(
+  id uuid PRIMARY KEY,
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  body text NOT NULL,
+  matched_words text NOT NULL, -- Moderation matches, flagged once the chirp is published
+  publish_at timestamp NOT NULL,
+  created_at timestamp NOT NULL
+);
+
+CREATE INDEX scheduled_chirps_publish_at_idx ON scheduled_chirps (publish_at);
+CREATE INDEX scheduled_chirps_user_id_idx ON scheduled_chirps (user_id, publish_at);
+
+CREATE TABLE chirp_views(
+  chirp_id uuid PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+  views bigint NOT NULL
+);
+
+-- +goose Down
+DROP TABLE chirp_views;
+DROP TABLE scheduled_chirps;
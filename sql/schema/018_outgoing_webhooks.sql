@@ -0,0 +1,32 @@
+-- +goose Up
+CREATE TABLE webhook_endpoints(
+  id uuid PRIMARY KEY,
+  user_id uuid REFERENCES users(id) ON DELETE CASCADE, -- NULL for endpoints an admin registered, which see every user's events
+  url text NOT NULL,
+  secret text NOT NULL,
+  events text NOT NULL, -- Comma separated event types
+  created_at timestamp NOT NULL
+);
+
+CREATE INDEX webhook_endpoints_user_id_idx ON webhook_endpoints (user_id);
+
+CREATE TABLE webhook_deliveries(
+  id uuid PRIMARY KEY,
+  endpoint_id uuid NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+  event_type text NOT NULL,
+  payload text NOT NULL,
+  status text NOT NULL, -- pending, succeeded or failed
+  attempts integer NOT NULL,
+  next_attempt_at timestamp NOT NULL,
+  last_status_code integer NOT NULL, -- 0 when the endpoint didn't respond
+  last_error text NOT NULL,
+  created_at timestamp NOT NULL,
+  delivered_at timestamp
+);
+
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_endpoint_id_idx ON webhook_deliveries (endpoint_id, created_at);
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhook_endpoints;
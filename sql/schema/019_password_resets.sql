@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE password_reset_tokens(
+  token_hash text PRIMARY KEY, -- SHA-256 of the token, which only the email has
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at timestamp NOT NULL,
+  expires_at timestamp NOT NULL
+);
+
+CREATE INDEX password_reset_tokens_user_id_idx ON password_reset_tokens (user_id);
+
+-- +goose Down
+DROP TABLE password_reset_tokens;
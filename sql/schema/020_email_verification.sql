@@ -0,0 +1,27 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN email_verified_at timestamp; -- NULL until the user follows the link we emailed
+
+-- Accounts from before verification existed keep being trusted
+UPDATE users SET email_verified_at = created_at;
+
+CREATE TABLE email_verification_tokens(
+  token_hash text PRIMARY KEY, -- SHA-256 of the token, which only the email has
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  email text NOT NULL, -- The address the token was sent to, which becomes the user's when verified
+  created_at timestamp NOT NULL,
+  expires_at timestamp NOT NULL
+);
+
+CREATE INDEX email_verification_tokens_user_id_idx ON email_verification_tokens (user_id);
+
+CREATE TABLE site_settings(
+  id boolean PRIMARY KEY DEFAULT true CHECK (id), -- There's only ever this one row
+  allow_unverified_chirps boolean NOT NULL
+);
+
+INSERT INTO site_settings (id, allow_unverified_chirps) VALUES (true, true);
+
+-- +goose Down
+DROP TABLE site_settings;
+DROP TABLE email_verification_tokens;
+ALTER TABLE users DROP COLUMN email_verified_at;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE two_factor_challenges(
+  id uuid PRIMARY KEY, -- The jti of the challenge token
+  user_id uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  attempts integer NOT NULL, -- Codes tried with the token, counted before checking them
+  created_at timestamp NOT NULL,
+  expires_at timestamp NOT NULL
+);
+
+CREATE INDEX two_factor_challenges_user_id_idx ON two_factor_challenges (user_id);
+
+-- +goose Down
+DROP TABLE two_factor_challenges;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Signups and logins normalize emails, so stored emails have to be
+-- normalized too. When accounts only differ in case, the oldest one gets
+-- the normalized address and the others keep theirs; logins still find
+-- those when the address is typed exactly as stored.
+UPDATE users SET email = lower(trim(email))
+WHERE email <> lower(trim(email))
+    AND NOT EXISTS (SELECT 1 FROM users AS taken WHERE taken.email = lower(trim(users.email)))
+    AND id = (
+        SELECT oldest.id FROM users AS oldest
+        WHERE lower(trim(oldest.email)) = lower(trim(users.email))
+        ORDER BY oldest.created_at, oldest.id
+        LIMIT 1
+    );
+
+-- +goose Down
+-- The original case isn't kept, and lower case addresses keep working
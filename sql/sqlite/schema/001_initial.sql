@@ -0,0 +1,198 @@
+-- The SQLite schema matches the Postgres one as of sql/schema/018, with the
+-- columns in the same order so both run the same generated code.
+--
+-- SQLite has no uuid type or gen_random_uuid(), ids are random version 4
+-- UUIDs in their text form, filled in by the column default. Timestamps are
+-- UTC text with microseconds, which sorts the same as the time it stands for.
+
+-- +goose Up
+CREATE TABLE users(
+  id text PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+  created_at timestamp NOT NULL,
+  updated_at timestamp NOT NULL,
+  email text NOT NULL UNIQUE,
+  hashed_password text NOT NULL
+);
+
+CREATE TABLE chirps(
+  id text PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+  created_at timestamp NOT NULL,
+  updated_at timestamp NOT NULL,
+  body text NOT NULL,
+  user_id text NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  parent_id text REFERENCES chirps(id) ON DELETE SET NULL,
+  root_id text REFERENCES chirps(id) ON DELETE SET NULL,
+  deleted_at timestamp,
+  search_vector text GENERATED ALWAYS AS (NULL) VIRTUAL -- Only lines SELECT * up with Postgres, search ranks the body itself
+);
+
+CREATE INDEX chirps_created_at_id_idx ON chirps (created_at, id);
+CREATE INDEX chirps_user_id_created_at_id_idx ON chirps (user_id, created_at, id);
+CREATE INDEX chirps_parent_id_idx ON chirps (parent_id);
+CREATE INDEX chirps_root_id_idx ON chirps (root_id);
+
+CREATE TABLE refresh_tokens(
+  token text PRIMARY KEY,
+  created_at timestamp NOT NULL,
+  updated_at timestamp NOT NULL,
+  user_id text NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  expires_at timestamp NOT NULL,
+  revoked_at timestamp,
+  family_id text NOT NULL,
+  user_agent text NOT NULL DEFAULT '',
+  ip_address text NOT NULL DEFAULT '',
+  last_used_at timestamp NOT NULL
+);
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+CREATE TABLE follows(
+  follower_id text NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  followee_id text NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at timestamp NOT NULL,
+  PRIMARY KEY (follower_id, followee_id),
+  CHECK (follower_id <> followee_id)
+);
+
+CREATE INDEX follows_followee_id_idx ON follows (followee_id);
+
+CREATE TABLE chirp_likes(
+  user_id text NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  chirp_id text NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  created_at timestamp NOT NULL,
+  PRIMARY KEY (user_id, chirp_id)
+);
+
+CREATE INDEX chirp_likes_chirp_id_idx ON chirp_likes (chirp_id);
+
+CREATE TABLE rechirps(
+  user_id text NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  chirp_id text NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  created_at timestamp NOT NULL,
+  PRIMARY KEY (user_id, chirp_id)
+);
+
+CREATE INDEX rechirps_chirp_id_idx ON rechirps (chirp_id);
+CREATE INDEX rechirps_user_id_created_at_idx ON rechirps (user_id, created_at);
+
+CREATE TABLE bad_words(
+  word text PRIMARY KEY,
+  created_at timestamp NOT NULL
+);
+
+INSERT INTO bad_words (word, created_at) VALUES
+  ('kerfuffle', strftime('%Y-%m-%d %H:%M:%f000+00:00', 'now')),
+  ('sharbert', strftime('%Y-%m-%d %H:%M:%f000+00:00', 'now')),
+  ('fornax', strftime('%Y-%m-%d %H:%M:%f000+00:00', 'now'));
+
+CREATE TABLE chirp_flags(
+  chirp_id text PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+  matched_words text NOT NULL,
+  created_at timestamp NOT NULL
+);
+
+CREATE TABLE user_totp(
+  user_id text PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  secret text NOT NULL,
+  created_at timestamp NOT NULL,
+  enabled_at timestamp,
+  last_step integer NOT NULL
+);
+
+CREATE TABLE recovery_codes(
+  id text PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+  user_id text NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash text NOT NULL,
+  created_at timestamp NOT NULL,
+  used_at timestamp
+);
+
+CREATE INDEX recovery_codes_user_id_idx ON recovery_codes (user_id);
+
+CREATE TABLE webhook_events(
+  id text PRIMARY KEY,
+  event_type text NOT NULL,
+  payload text NOT NULL,
+  status text NOT NULL,
+  last_error text NOT NULL,
+  attempts integer NOT NULL,
+  received_at timestamp NOT NULL,
+  processed_at timestamp
+);
+
+CREATE INDEX webhook_events_status_idx ON webhook_events (status, received_at);
+
+CREATE TABLE subscriptions(
+  user_id text PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  plan text NOT NULL,
+  status text NOT NULL,
+  current_period_end timestamp NOT NULL,
+  grace_period_end timestamp,
+  canceled_at timestamp,
+  created_at timestamp NOT NULL,
+  updated_at timestamp NOT NULL
+);
+
+CREATE TABLE scheduled_chirps(
+  id text PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+  user_id text NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  body text NOT NULL,
+  matched_words text NOT NULL,
+  publish_at timestamp NOT NULL,
+  created_at timestamp NOT NULL
+);
+
+CREATE INDEX scheduled_chirps_publish_at_idx ON scheduled_chirps (publish_at);
+CREATE INDEX scheduled_chirps_user_id_idx ON scheduled_chirps (user_id, publish_at);
+
+CREATE TABLE chirp_views(
+  chirp_id text PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+  views integer NOT NULL
+);
+
+CREATE TABLE webhook_endpoints(
+  id text PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+  user_id text REFERENCES users(id) ON DELETE CASCADE,
+  url text NOT NULL,
+  secret text NOT NULL,
+  events text NOT NULL,
+  created_at timestamp NOT NULL
+);
+
+CREATE INDEX webhook_endpoints_user_id_idx ON webhook_endpoints (user_id);
+
+CREATE TABLE webhook_deliveries(
+  id text PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+  endpoint_id text NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+  event_type text NOT NULL,
+  payload text NOT NULL,
+  status text NOT NULL,
+  attempts integer NOT NULL,
+  next_attempt_at timestamp NOT NULL,
+  last_status_code integer NOT NULL,
+  last_error text NOT NULL,
+  created_at timestamp NOT NULL,
+  delivered_at timestamp
+);
+
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_endpoint_id_idx ON webhook_deliveries (endpoint_id, created_at);
+
+-- +goose Down
+DROP TABLE webhook_deliveries;
+DROP TABLE webhook_endpoints;
+DROP TABLE chirp_views;
+DROP TABLE scheduled_chirps;
+DROP TABLE subscriptions;
+DROP TABLE webhook_events;
+DROP TABLE recovery_codes;
+DROP TABLE user_totp;
+DROP TABLE chirp_flags;
+DROP TABLE bad_words;
+DROP TABLE rechirps;
+DROP TABLE chirp_likes;
+DROP TABLE follows;
+DROP TABLE refresh_tokens;
+DROP TABLE chirps;
+DROP TABLE users;
@@ -0,0 +1,14 @@
+-- Matches sql/schema/019_password_resets.sql.
+
+-- +goose Up
+CREATE TABLE password_reset_tokens(
+  token_hash text PRIMARY KEY,
+  user_id text NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at timestamp NOT NULL,
+  expires_at timestamp NOT NULL
+);
+
+CREATE INDEX password_reset_tokens_user_id_idx ON password_reset_tokens (user_id);
+
+-- +goose Down
+DROP TABLE password_reset_tokens;
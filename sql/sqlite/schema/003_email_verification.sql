@@ -0,0 +1,28 @@
+-- Matches sql/schema/020_email_verification.sql.
+
+-- +goose Up
+ALTER TABLE users ADD COLUMN email_verified_at timestamp;
+
+UPDATE users SET email_verified_at = created_at;
+
+CREATE TABLE email_verification_tokens(
+  token_hash text PRIMARY KEY,
+  user_id text NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  email text NOT NULL,
+  created_at timestamp NOT NULL,
+  expires_at timestamp NOT NULL
+);
+
+CREATE INDEX email_verification_tokens_user_id_idx ON email_verification_tokens (user_id);
+
+CREATE TABLE site_settings(
+  id boolean PRIMARY KEY DEFAULT true CHECK (id),
+  allow_unverified_chirps boolean NOT NULL
+);
+
+INSERT INTO site_settings (id, allow_unverified_chirps) VALUES (true, true);
+
+-- +goose Down
+DROP TABLE site_settings;
+DROP TABLE email_verification_tokens;
+ALTER TABLE users DROP COLUMN email_verified_at;
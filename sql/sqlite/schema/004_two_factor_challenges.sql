@@ -0,0 +1,15 @@
+-- Matches sql/schema/021_two_factor_challenges.sql.
+
+-- +goose Up
+CREATE TABLE two_factor_challenges(
+  id text PRIMARY KEY,
+  user_id text NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  attempts integer NOT NULL,
+  created_at timestamp NOT NULL,
+  expires_at timestamp NOT NULL
+);
+
+CREATE INDEX two_factor_challenges_user_id_idx ON two_factor_challenges (user_id);
+
+-- +goose Down
+DROP TABLE two_factor_challenges;
@@ -0,0 +1,11 @@
+-- Matches sql/schema/022_follows_keyset_indexes.sql.
+
+-- +goose Up
+DROP INDEX follows_followee_id_idx;
+CREATE INDEX follows_followee_id_created_at_idx ON follows (followee_id, created_at, follower_id);
+CREATE INDEX follows_follower_id_created_at_idx ON follows (follower_id, created_at, followee_id);
+
+-- +goose Down
+DROP INDEX follows_follower_id_created_at_idx;
+DROP INDEX follows_followee_id_created_at_idx;
+CREATE INDEX follows_followee_id_idx ON follows (followee_id);
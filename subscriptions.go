@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/auth"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+const (
+	chirpyRedPlan = "chirpy_red"
+	// subscriptionPeriod is used when Polka doesn't tell us when the paid
+	// period ends.
+	subscriptionPeriod = 30 * 24 * time.Hour
+	// subscriptionGracePeriod is how long a user keeps Chirpy Red after a
+	// failed payment, giving Polka time to retry the charge.
+	subscriptionGracePeriod = 7 * 24 * time.Hour
+)
+
+const (
+	subscriptionStatusActive   = "active"
+	subscriptionStatusPastDue  = "past_due"
+	subscriptionStatusCanceled = "canceled"
+	// subscriptionStatusExpired is never stored, it's reported for
+	// subscriptions whose access has run out
+	subscriptionStatusExpired = "expired"
+)
+
+var errWebhookSubscriptionNotFound = errors.New("subscription not found")
+
+type Subscription struct {
+	Plan             string     `json:"plan"`
+	Status           string     `json:"status"`
+	CurrentPeriodEnd time.Time  `json:"current_period_end"`
+	GracePeriodEnd   *time.Time `json:"grace_period_end"`
+	CanceledAt       *time.Time `json:"canceled_at"`
+}
+
+// subscriptionActive reports whether a subscription still grants Chirpy
+// Red. Canceled subscriptions run until the end of the paid period, past due
+// ones until the grace period is over. Nothing has to run for a subscription
+// to expire.
+func subscriptionActive(sub database.Subscription, now time.Time) bool {
+	switch sub.Status {
+	case subscriptionStatusActive, subscriptionStatusCanceled:
+		return now.Before(sub.CurrentPeriodEnd)
+	case subscriptionStatusPastDue:
+		return now.Before(sub.CurrentPeriodEnd) ||
+			(sub.GracePeriodEnd.Valid && now.Before(sub.GracePeriodEnd.Time))
+	}
+	return false
+}
+
+// isChirpyRed derives the Chirpy Red flag from the user's subscription.
+func (cfg *apiConfig) isChirpyRed(ctx context.Context, userID uuid.UUID) (bool, error) {
+	sub, err := cfg.db.GetSubscription(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return subscriptionActive(sub, time.Now()), nil
+}
+
+// processSubscriptionEvent applies a Polka billing event to the user's
+// subscription.
+func (cfg *apiConfig) processSubscriptionEvent(ctx context.Context, event WebhookEvent) (string, error) {
+	userID, err := uuid.Parse(event.Data.UserID)
+	if err != nil {
+		return webhookStatusIgnored, nil
+	}
+
+	_, err = cfg.db.GetUser(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhookStatusFailed, errWebhookUserNotFound
+	}
+	if err != nil {
+		return webhookStatusFailed, err
+	}
+
+	now := time.Now().UTC()
+
+	if event.Event == "user.upgraded" {
+		plan := event.Data.Plan
+		if plan == "" {
+			plan = chirpyRedPlan
+		}
+
+		currentPeriodEnd := periodEnd(event, now)
+		err = cfg.db.UpsertSubscription(ctx, database.UpsertSubscriptionParams{
+			UserID:           userID,
+			Plan:             plan,
+			CurrentPeriodEnd: currentPeriodEnd,
+		})
+		if err != nil {
+			return webhookStatusFailed, err
+		}
+
+		cfg.publishEvent(ctx, eventUserUpgraded, []uuid.UUID{userID}, struct {
+			UserID           uuid.UUID `json:"user_id"`
+			Plan             string    `json:"plan"`
+			CurrentPeriodEnd time.Time `json:"current_period_end"`
+		}{userID, plan, currentPeriodEnd})
+
+		return webhookStatusProcessed, nil
+	}
+
+	sub, err := cfg.db.GetSubscription(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return webhookStatusFailed, errWebhookSubscriptionNotFound
+	}
+	if err != nil {
+		return webhookStatusFailed, err
+	}
+
+	switch event.Event {
+	case "subscription.renewed":
+		// Without an explicit end, the new period starts where the old one
+		// ended, unless it lapsed already
+		start := sub.CurrentPeriodEnd
+		if start.Before(now) {
+			start = now
+		}
+
+		err = cfg.db.RenewSubscription(ctx, database.RenewSubscriptionParams{
+			UserID:           userID,
+			CurrentPeriodEnd: periodEnd(event, start),
+		})
+	case "payment.failed":
+		// Repeated failures don't extend the grace period
+		gracePeriodEnd := sub.GracePeriodEnd
+		if !gracePeriodEnd.Valid {
+			gracePeriodEnd = sql.NullTime{Time: now.Add(subscriptionGracePeriod), Valid: true}
+		}
+
+		err = cfg.db.MarkSubscriptionPastDue(ctx, database.MarkSubscriptionPastDueParams{
+			UserID:         userID,
+			GracePeriodEnd: gracePeriodEnd,
+		})
+	case "user.downgraded":
+		err = cfg.db.CancelSubscription(ctx, userID)
+	}
+
+	if err != nil {
+		return webhookStatusFailed, err
+	}
+
+	return webhookStatusProcessed, nil
+}
+
+// periodEnd is the end of the paid period the event reports, or one
+// subscription period after start.
+func periodEnd(event WebhookEvent, start time.Time) time.Time {
+	// Stored without a time zone, so it has to be in UTC like everything else
+	if event.Data.CurrentPeriodEnd != nil {
+		return event.Data.CurrentPeriodEnd.UTC()
+	}
+	return start.Add(subscriptionPeriod)
+}
+
+func (cfg *apiConfig) getSubscription(w http.ResponseWriter, r *http.Request) {
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return
+	}
+
+	sub, err := cfg.db.GetSubscription(r.Context(), userID)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, problemNotFound, "Subscription not found", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve subscription", err)
+		return
+	}
+
+	jsonSub := Subscription{
+		Plan:             sub.Plan,
+		Status:           sub.Status,
+		CurrentPeriodEnd: sub.CurrentPeriodEnd,
+	}
+
+	if !subscriptionActive(sub, time.Now()) {
+		jsonSub.Status = subscriptionStatusExpired
+	}
+
+	if sub.GracePeriodEnd.Valid {
+		jsonSub.GracePeriodEnd = &sub.GracePeriodEnd.Time
+	}
+
+	if sub.CanceledAt.Valid {
+		jsonSub.CanceledAt = &sub.CanceledAt.Time
+	}
+
+	respondWithJSON(w, http.StatusOK, jsonSub)
+}
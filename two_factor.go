@@ -0,0 +1,383 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/auth"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+const (
+	totpIssuer        = "Chirpy"
+	recoveryCodeCount = 10
+)
+
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// checkSecondFactor accepts either a TOTP code or an unused recovery code.
+// Both are single use: a TOTP code's time step is remembered and a recovery
+// code is marked as used.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, totp database.UserTotp, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		step, ok := auth.ValidateTOTP(totp.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+
+		updated, err := cfg.db.UpdateUserTOTPLastStep(ctx, database.UpdateUserTOTPLastStepParams{
+			LastStep: step,
+			UserID:   totp.UserID,
+		})
+		return updated > 0, err
+	}
+
+	if recoveryCode != "" {
+		used, err := cfg.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+			UserID:   totp.UserID,
+			CodeHash: auth.HashRecoveryCode(recoveryCode),
+		})
+		return used > 0, err
+	}
+
+	return false, nil
+}
+
+// startTwoFactorChallenge records a challenge for the user and returns its
+// token. The record is what makes the token single use and limits the codes
+// tried with it.
+func (cfg *apiConfig) startTwoFactorChallenge(ctx context.Context, userID uuid.UUID) (string, error) {
+	err := cfg.db.DeleteExpiredTwoFactorChallenges(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	// Stored without a time zone and compared with NOW(), so it has to be
+	// in UTC
+	challengeID := uuid.New()
+	err = cfg.db.CreateTwoFactorChallenge(ctx, database.CreateTwoFactorChallengeParams{
+		ID:        challengeID,
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(twoFactorChallengeExpiration),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.keyring.MakeChallengeJWT(userID, challengeID, twoFactorChallengeExpiration)
+}
+
+func (cfg *apiConfig) enrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemNotFound, "User not found", err)
+		return
+	}
+
+	totp, err := cfg.db.GetUserTOTP(r.Context(), userID)
+
+	if err == nil && totp.EnabledAt.Valid {
+		respondWithError(w, r, problemConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, problemInternal, "Couldn't check two-factor authentication", err)
+		return
+	}
+
+	secret := auth.MakeTOTPSecret()
+
+	err = cfg.db.UpsertUserTOTP(r.Context(), database.UpsertUserTOTPParams{
+		UserID: userID,
+		Secret: secret,
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't start two-factor enrollment", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, TwoFactorEnrollment{
+		Secret:     secret,
+		OtpauthURI: auth.TOTPURI(totpIssuer, user.Email, secret),
+	})
+}
+
+func (cfg *apiConfig) confirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type confirmation struct {
+		Code string `json:"code"`
+	}
+
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return
+	}
+
+	params := confirmation{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	totp, err := cfg.db.GetUserTOTP(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemNotFound, "Two-factor enrollment not started", err)
+		return
+	}
+
+	if totp.EnabledAt.Valid {
+		respondWithError(w, r, problemConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(r.Context(), totp, params.Code, "")
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't check the code", err)
+		return
+	}
+
+	if !ok {
+		respondWithError(w, r, problemInvalidCode, "Invalid code", fmt.Errorf("invalid TOTP code for user %s", userID))
+		return
+	}
+
+	codes := auth.MakeRecoveryCodes(recoveryCodeCount)
+
+	tx, err := cfg.db.BeginTx(r.Context())
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't enable two-factor authentication", err)
+		return
+	}
+	defer tx.Rollback()
+
+	err = tx.DeleteRecoveryCodes(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't enable two-factor authentication", err)
+		return
+	}
+
+	for _, code := range codes {
+		err = tx.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashRecoveryCode(code),
+		})
+
+		if err != nil {
+			respondWithError(w, r, problemInternal, "Couldn't enable two-factor authentication", err)
+			return
+		}
+	}
+
+	err = tx.EnableUserTOTP(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't enable two-factor authentication", err)
+		return
+	}
+
+	err = tx.Commit()
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't enable two-factor authentication", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, RecoveryCodes{RecoveryCodes: codes})
+}
+
+func (cfg *apiConfig) disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type secondFactor struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	// Check for the token in the headers
+	token, err := auth.GetBearerToken(r.Header)
+
+	if err != nil {
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
+		return
+	}
+
+	userID, err := cfg.keyring.ValidateJWT(token)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
+		return
+	}
+
+	params := secondFactor{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	totp, err := cfg.db.GetUserTOTP(r.Context(), userID)
+
+	if err != nil || !totp.EnabledAt.Valid {
+		respondWithError(w, r, problemNotFound, "Two-factor authentication is not enabled", err)
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(r.Context(), totp, params.Code, params.RecoveryCode)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't check the code", err)
+		return
+	}
+
+	if !ok {
+		respondWithError(w, r, problemInvalidCode, "Invalid code", fmt.Errorf("invalid second factor for user %s", userID))
+		return
+	}
+
+	err = cfg.db.DeleteUserTOTP(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't disable two-factor authentication", err)
+		return
+	}
+
+	err = cfg.db.DeleteRecoveryCodes(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't disable two-factor authentication", err)
+		return
+	}
+
+	respondWithNoContent(w)
+}
+
+// loginTwoFactor is the second step of a login with 2FA on: it trades the
+// challenge token from login and a code for access and refresh tokens. A
+// challenge is good for one login and twoFactorMaxAttempts codes, after
+// which the password has to be entered again.
+func (cfg *apiConfig) loginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type challengeResponse struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+
+	params := challengeResponse{}
+	if !decodeJSON(w, r, &params) {
+		return
+	}
+
+	userID, challengeID, err := cfg.keyring.ValidateChallengeJWT(params.ChallengeToken)
+
+	if err != nil {
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate challenge token", err)
+		return
+	}
+
+	if params.Code == "" && params.RecoveryCode == "" {
+		respondWithFieldErrors(w, r, problemValidation, FieldError{Field: "code", Code: "required", Detail: "Code or recovery code is required"})
+		return
+	}
+
+	// The attempt counts before the code is checked, so concurrent guesses
+	// can't get past the limit
+	counted, err := cfg.db.CountTwoFactorAttempt(r.Context(), database.CountTwoFactorAttemptParams{
+		ID:          challengeID,
+		UserID:      userID,
+		MaxAttempts: twoFactorMaxAttempts,
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't check the challenge", err)
+		return
+	}
+
+	if counted == 0 {
+		respondWithError(w, r, problemTokenRevoked, "Challenge already used, please log in again", fmt.Errorf("challenge %s used up for user %s", challengeID, userID))
+		return
+	}
+
+	totp, err := cfg.db.GetUserTOTP(r.Context(), userID)
+
+	if err != nil || !totp.EnabledAt.Valid {
+		respondWithError(w, r, problemInvalidToken, "Two-factor authentication is not enabled", err)
+		return
+	}
+
+	ok, err := cfg.checkSecondFactor(r.Context(), totp, params.Code, params.RecoveryCode)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't check the code", err)
+		return
+	}
+
+	if !ok {
+		cfg.metrics.logins.WithLabelValues("failed").Inc()
+		respondWithError(w, r, problemInvalidCode, "Invalid code", fmt.Errorf("invalid second factor for user %s", userID))
+		return
+	}
+
+	deleted, err := cfg.db.DeleteTwoFactorChallenge(r.Context(), challengeID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't check the challenge", err)
+		return
+	}
+
+	if deleted == 0 {
+		respondWithError(w, r, problemTokenRevoked, "Challenge already used, please log in again", fmt.Errorf("challenge %s used concurrently for user %s", challengeID, userID))
+		return
+	}
+
+	user, err := cfg.db.GetUser(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemInvalidToken, "User not found", err)
+		return
+	}
+
+	cfg.respondWithTokens(w, r, user)
+}
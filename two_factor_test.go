@@ -0,0 +1,131 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tracevt/chirpy/internal/auth"
+)
+
+func TestTwoFactorLifecycle(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	rec := ts.do("POST", "/api/2fa/enroll", nil, bearer(user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	enrollment := decodeBody[TwoFactorEnrollment](t, rec)
+
+	expectStatus(t, ts.do("POST", "/api/2fa/confirm", map[string]string{"code": "000000"}, bearer(user.Token)), http.StatusUnauthorized)
+
+	code, err := auth.TOTPCode(enrollment.Secret, auth.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatalf("TOTPCode() error = %v", err)
+	}
+
+	rec = ts.do("POST", "/api/2fa/confirm", map[string]string{"code": code}, bearer(user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	codes := decodeBody[RecoveryCodes](t, rec).RecoveryCodes
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	expectStatus(t, ts.do("POST", "/api/2fa/enroll", nil, bearer(user.Token)), http.StatusConflict)
+
+	// The password alone only gets a challenge now
+	rec = ts.do("POST", "/api/login", UserCredentials{Email: "walt@example.com", Password: "hunter2"}, "")
+	expectStatus(t, rec, http.StatusOK)
+	challenge := decodeBody[TwoFactorChallenge](t, rec)
+	if !challenge.TwoFactorRequired || challenge.ChallengeToken == "" {
+		t.Fatalf("login = %s, want a challenge", rec.Body.String())
+	}
+
+	secondFactor := map[string]string{"challenge_token": challenge.ChallengeToken, "recovery_code": codes[0]}
+	rec = ts.do("POST", "/api/login/2fa", secondFactor, "")
+	expectStatus(t, rec, http.StatusOK)
+	if decodeBody[UserWithToken](t, rec).Token == "" {
+		t.Errorf("two-factor login didn't return a token")
+	}
+
+	// Challenges are single use
+	expectProblem(t, ts.do("POST", "/api/login/2fa", secondFactor, ""), problemTokenRevoked)
+
+	// And so are recovery codes
+	secondFactor["challenge_token"] = ts.twoFactorChallenge("walt@example.com")
+	expectProblem(t, ts.do("POST", "/api/login/2fa", secondFactor, ""), problemInvalidCode)
+
+	expectStatus(t, ts.do("POST", "/api/2fa/disable", map[string]string{"recovery_code": codes[0]}, bearer(user.Token)), http.StatusUnauthorized)
+	expectStatus(t, ts.do("POST", "/api/2fa/disable", map[string]string{"recovery_code": codes[1]}, bearer(user.Token)), http.StatusNoContent)
+
+	ts.login("walt@example.com", "hunter2")
+	expectStatus(t, ts.do("POST", "/api/2fa/disable", map[string]string{"recovery_code": codes[2]}, bearer(user.Token)), http.StatusNotFound)
+}
+
+func TestTwoFactorAttemptLimit(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	rec := ts.do("POST", "/api/2fa/enroll", nil, bearer(user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	secret := decodeBody[TwoFactorEnrollment](t, rec).Secret
+
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatalf("TOTPCode() error = %v", err)
+	}
+	rec = ts.do("POST", "/api/2fa/confirm", map[string]string{"code": code}, bearer(user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	codes := decodeBody[RecoveryCodes](t, rec).RecoveryCodes
+
+	challenge := ts.twoFactorChallenge("walt@example.com")
+	for i := 0; i < twoFactorMaxAttempts; i++ {
+		wrong := map[string]string{"challenge_token": challenge, "recovery_code": "not-a-code"}
+		expectProblem(t, ts.do("POST", "/api/login/2fa", wrong, ""), problemInvalidCode)
+	}
+
+	// Even a good code is refused once the challenge is used up
+	secondFactor := map[string]string{"challenge_token": challenge, "recovery_code": codes[0]}
+	expectProblem(t, ts.do("POST", "/api/login/2fa", secondFactor, ""), problemTokenRevoked)
+
+	// Logging in with the password again starts over
+	secondFactor["challenge_token"] = ts.twoFactorChallenge("walt@example.com")
+	expectStatus(t, ts.do("POST", "/api/login/2fa", secondFactor, ""), http.StatusOK)
+}
+
+func TestTwoFactorWestOfUTC(t *testing.T) {
+	westOfUTC(t)
+
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	rec := ts.do("POST", "/api/2fa/enroll", nil, bearer(user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	secret := decodeBody[TwoFactorEnrollment](t, rec).Secret
+
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatalf("TOTPCode() error = %v", err)
+	}
+	rec = ts.do("POST", "/api/2fa/confirm", map[string]string{"code": code}, bearer(user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	codes := decodeBody[RecoveryCodes](t, rec).RecoveryCodes
+
+	// The challenge mustn't have expired before it's used
+	secondFactor := map[string]string{"challenge_token": ts.twoFactorChallenge("walt@example.com"), "recovery_code": codes[0]}
+	expectStatus(t, ts.do("POST", "/api/login/2fa", secondFactor, ""), http.StatusOK)
+}
+
+// twoFactorChallenge logs in as a user with 2FA on and returns the
+// challenge token.
+func (ts *testServer) twoFactorChallenge(email string) string {
+	ts.t.Helper()
+
+	rec := ts.do("POST", "/api/login", UserCredentials{Email: email, Password: "hunter2"}, "")
+	expectStatus(ts.t, rec, http.StatusOK)
+
+	challenge := decodeBody[TwoFactorChallenge](ts.t, rec)
+	if !challenge.TwoFactorRequired || challenge.ChallengeToken == "" {
+		ts.t.Fatalf("login = %s, want a challenge", rec.Body.String())
+	}
+	return challenge.ChallengeToken
+}
@@ -1,32 +1,41 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/tracevt/chirpy/internal/auth"
 	"github.com/tracevt/chirpy/internal/database"
+	"github.com/tracevt/chirpy/internal/store"
 )
 
 type User struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	// PendingEmail is the new address of an email change, until it's
+	// verified
+	PendingEmail string `json:"pending_email,omitempty"`
+	IsChirpyRed  bool   `json:"is_chirpy_red"`
 }
 
 type UserWithToken struct {
-	ID           uuid.UUID `json:"id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	Email        string    `json:"email"`
-	Token        string    `json:"token"`
-	RefreshToken string    `json:"refresh_token"`
-	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Token         string    `json:"token"`
+	RefreshToken  string    `json:"refresh_token"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
 }
 
 type UserCredentials struct {
@@ -40,28 +49,24 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		Password string `json:"password"`
 	}
 
-	decoder := json.NewDecoder(r.Body)
 	params := userData{}
-	err := decoder.Decode(&params)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+	if !decodeJSON(w, r, &params) {
 		return
 	}
 
-	if params.Email == "" {
-		respondWithError(w, http.StatusBadRequest, "Email is required", fmt.Errorf("Email is required"))
-		return
-	}
+	params.Email = normalizeEmail(params.Email)
 
-	if params.Password == "" {
-		respondWithError(w, http.StatusBadRequest, "Password is required", fmt.Errorf("Email is required"))
+	var v validation
+	v.email("email", params.Email)
+	v.required("password", params.Password, "Password")
+	if v.failed(w, r) {
 		return
 	}
 
 	hashedPassword, err := auth.HashPassword(params.Password)
 
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't secure password", err)
+		respondWithError(w, r, problemInternal, "Couldn't secure password", err)
 		return
 	}
 
@@ -70,17 +75,30 @@ func (cfg *apiConfig) createUser(w http.ResponseWriter, r *http.Request) {
 		HashedPassword: hashedPassword,
 	})
 
+	if store.IsUniqueViolation(err) {
+		respondWithError(w, r, problemConflict, "Email is already in use", err)
+		return
+	}
+
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't create user", err)
+		respondWithError(w, r, problemInternal, "Couldn't create user", err)
 		return
 	}
 
+	// The account works right away, the link only proves the address is theirs
+	err = cfg.sendEmailVerification(r.Context(), user.ID, user.Email)
+
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Couldn't send the verification email", "error", err)
+	}
+
+	// A new user has no subscription yet, so is never Chirpy Red
 	jsonUser := &User{
-		ID:          user.ID,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		Email:       user.Email,
-		IsChirpyRed: user.IsChirpyRed,
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
 	}
 
 	respondWithJSON(w, http.StatusCreated, jsonUser)
@@ -91,40 +109,73 @@ func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
 	token, err := auth.GetBearerToken(r.Header)
 
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Please provide an auth token", err)
+		respondWithError(w, r, problemMissingToken, "Please provide an auth token", err)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.secret)
+	userID, err := cfg.keyring.ValidateJWT(token)
 
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't validate token", err)
+		respondWithError(w, r, tokenProblem(err), "Couldn't validate token", err)
 		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
 	params := UserCredentials{}
-	err = decoder.Decode(&params)
+	if !decodeJSON(w, r, &params) {
+		return
+	}
 
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+	params.Email = normalizeEmail(params.Email)
+
+	var v validation
+	v.email("email", params.Email)
+	v.required("password", params.Password, "Password")
+	if v.failed(w, r) {
 		return
 	}
 
-	if params.Email == "" || params.Password == "" {
-		respondWithError(w, http.StatusBadRequest, "Please provide email and/or password", err)
+	currentUser, err := cfg.db.GetUser(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemNotFound, "User not found", err)
 		return
 	}
 
+	passwordChanged := auth.CheckPasswordHash(params.Password, currentUser.HashedPassword) != nil
+
 	newHashedPassword, err := auth.HashPassword(params.Password)
 
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't secure password", err)
+		respondWithError(w, r, problemInternal, "Couldn't secure password", err)
 		return
 	}
 
+	// A new address only replaces the current one once it's verified
+	pendingEmail := ""
+	if params.Email != currentUser.Email {
+		_, err = cfg.db.GetUserByEmail(r.Context(), params.Email)
+
+		if err == nil {
+			respondWithError(w, r, problemConflict, "Email is already in use", nil)
+			return
+		}
+
+		if !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, r, problemInternal, "Couldn't check the email address", err)
+			return
+		}
+
+		err = cfg.sendEmailVerification(r.Context(), userID, params.Email)
+
+		if err != nil {
+			respondWithError(w, r, problemInternal, "Couldn't send the verification email", err)
+			return
+		}
+		pendingEmail = params.Email
+	}
+
 	updateParams := &database.UpdateUserParams{
-		Email:          params.Email,
+		Email:          currentUser.Email,
 		HashedPassword: newHashedPassword,
 		ID:             userID,
 	}
@@ -132,17 +183,50 @@ func (cfg *apiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
 	updatedUser, err := cfg.db.UpdateUser(r.Context(), *updateParams)
 
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't update user", err)
+		respondWithError(w, r, problemInternal, "Couldn't update user", err)
+		return
+	}
+
+	// A new password logs the user out everywhere
+	if passwordChanged {
+		err = cfg.db.RevokeUserRefreshTokens(r.Context(), userID)
+
+		if err != nil {
+			respondWithError(w, r, problemInternal, "Couldn't revoke sessions", err)
+			return
+		}
+	}
+
+	isChirpyRed, err := cfg.isChirpyRed(r.Context(), userID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve subscription", err)
 		return
 	}
 
 	userResponse := &User{
-		ID:          updatedUser.ID,
-		Email:       updatedUser.Email,
-		CreatedAt:   updatedUser.CreatedAt,
-		UpdatedAt:   updatedUser.UpdatedAt,
-		IsChirpyRed: updatedUser.IsChirpyRed,
+		ID:            updatedUser.ID,
+		Email:         updatedUser.Email,
+		EmailVerified: updatedUser.EmailVerifiedAt.Valid,
+		PendingEmail:  pendingEmail,
+		CreatedAt:     updatedUser.CreatedAt,
+		UpdatedAt:     updatedUser.UpdatedAt,
+		IsChirpyRed:   isChirpyRed,
 	}
 
 	respondWithJSON(w, http.StatusOK, userResponse)
 }
+
+// userByEmail finds the account for an address as a user typed it. Stored
+// addresses are normalized, except for accounts that only differed in case
+// from an older one when they were migrated, which are found by the address
+// typed exactly as stored.
+func (cfg *apiConfig) userByEmail(ctx context.Context, typed string) (database.User, error) {
+	if exact := strings.TrimSpace(typed); exact != normalizeEmail(typed) {
+		user, err := cfg.db.GetUserByEmail(ctx, exact)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return user, err
+		}
+	}
+	return cfg.db.GetUserByEmail(ctx, normalizeEmail(typed))
+}
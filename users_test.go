@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/tracevt/chirpy/internal/auth"
+	"github.com/tracevt/chirpy/internal/database"
+)
+
+func TestCreateUser(t *testing.T) {
+	ts := newTestServer(t)
+
+	tests := []struct {
+		name string
+		body UserCredentials
+		want int
+	}{
+		{name: "valid", body: UserCredentials{Email: "walt@example.com", Password: "hunter2"}, want: http.StatusCreated},
+		{name: "missing email", body: UserCredentials{Password: "hunter2"}, want: http.StatusBadRequest},
+		{name: "missing password", body: UserCredentials{Email: "jesse@example.com"}, want: http.StatusBadRequest},
+		{name: "taken email", body: UserCredentials{Email: "Walt@Example.com", Password: "hunter3"}, want: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do("POST", "/api/users", tt.body, "")
+			expectStatus(t, rec, tt.want)
+
+			if tt.want == http.StatusConflict {
+				expectProblem(t, rec, problemConflict)
+			}
+
+			if tt.want == http.StatusCreated {
+				user := decodeBody[User](t, rec)
+				if user.Email != tt.body.Email || user.IsChirpyRed {
+					t.Errorf("user = %+v", user)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	expectStatus(t, ts.do("PUT", "/api/users", UserCredentials{Email: "heisenberg@example.com", Password: "hunter2"}, ""), http.StatusUnauthorized)
+
+	rec := ts.do("PUT", "/api/users", UserCredentials{Email: "heisenberg@example.com", Password: "hunter3"}, bearer(user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	if updated := decodeBody[User](t, rec); updated.Email != "walt@example.com" || updated.PendingEmail != "heisenberg@example.com" {
+		t.Errorf("user = %+v, want walt@example.com until heisenberg@example.com is verified", updated)
+	}
+
+	// A new password logs the user out everywhere
+	expectStatus(t, ts.do("POST", "/api/refresh", nil, bearer(user.RefreshToken)), http.StatusUnauthorized)
+
+	ts.login("walt@example.com", "hunter3")
+
+	expectStatus(t, ts.do("GET", ts.verificationLink("heisenberg@example.com"), nil, ""), http.StatusOK)
+	ts.login("heisenberg@example.com", "hunter3")
+}
+
+func TestLogin(t *testing.T) {
+	ts := newTestServer(t)
+	ts.signUp("walt@example.com")
+
+	tests := []struct {
+		name string
+		body UserCredentials
+		want int
+	}{
+		{name: "valid", body: UserCredentials{Email: "walt@example.com", Password: "hunter2"}, want: http.StatusOK},
+		{name: "wrong password", body: UserCredentials{Email: "walt@example.com", Password: "hunter3"}, want: http.StatusUnauthorized},
+		{name: "unknown user", body: UserCredentials{Email: "jesse@example.com", Password: "hunter2"}, want: http.StatusUnauthorized},
+		{name: "missing password", body: UserCredentials{Email: "walt@example.com"}, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := ts.do("POST", "/api/login", tt.body, "")
+			expectStatus(t, rec, tt.want)
+
+			if tt.want == http.StatusOK {
+				user := decodeBody[UserWithToken](t, rec)
+				if user.Token == "" || user.RefreshToken == "" {
+					t.Errorf("login didn't return tokens: %+v", user)
+				}
+			}
+		})
+	}
+}
+
+func TestLoginWithMigratedEmails(t *testing.T) {
+	ts := newTestServer(t)
+	ts.signUp("walt@example.com")
+
+	// What the email migration leaves behind when accounts only differ in case
+	hash, err := auth.HashPassword("hunter3")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	legacy, err := ts.db.CreateUser(context.Background(), database.CreateUserParams{Email: "Walt@Example.com", HashedPassword: hash})
+	if err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+
+	if user := ts.login("Walt@Example.com", "hunter3"); user.ID != legacy.ID {
+		t.Errorf("logged in as %v, want the account stored as Walt@Example.com", user.ID)
+	}
+	if user := ts.login("WALT@example.com", "hunter2"); user.ID == legacy.ID {
+		t.Errorf("logged in as the account stored as Walt@Example.com, want walt@example.com")
+	}
+
+	expectStatus(t, ts.do("POST", "/api/password/forgot", map[string]string{"email": "Walt@Example.com"}, ""), http.StatusAccepted)
+	if messages := ts.mail.Messages(); len(messages) == 0 || messages[len(messages)-1].To != "Walt@Example.com" {
+		t.Errorf("reset email wasn't sent to Walt@Example.com: %+v", messages)
+	}
+}
+
+func TestRefreshRotatesTokens(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	type tokens struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	rec := ts.do("POST", "/api/refresh", nil, bearer(user.RefreshToken))
+	expectStatus(t, rec, http.StatusOK)
+	rotated := decodeBody[tokens](t, rec)
+
+	if rotated.RefreshToken == user.RefreshToken {
+		t.Fatalf("refresh token wasn't rotated")
+	}
+	expectStatus(t, ts.do("GET", "/api/sessions", nil, bearer(rotated.Token)), http.StatusOK)
+
+	// Reusing the old token revokes the whole family, the new token included
+	expectStatus(t, ts.do("POST", "/api/refresh", nil, bearer(user.RefreshToken)), http.StatusUnauthorized)
+	expectStatus(t, ts.do("POST", "/api/refresh", nil, bearer(rotated.RefreshToken)), http.StatusUnauthorized)
+}
+
+func TestRevoke(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	expectStatus(t, ts.do("POST", "/api/revoke", nil, ""), http.StatusUnauthorized)
+	expectStatus(t, ts.do("POST", "/api/revoke", nil, bearer(user.RefreshToken)), http.StatusNoContent)
+	expectStatus(t, ts.do("POST", "/api/refresh", nil, bearer(user.RefreshToken)), http.StatusUnauthorized)
+}
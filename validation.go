@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+// maxRequestBodyBytes bounds every JSON request body. The largest thing
+// anyone sends is a premium chirp, well under this.
+const maxRequestBodyBytes = 64 << 10
+
+// decodeJSON decodes the request body into v, which must be exactly one
+// JSON value with no fields v doesn't know about. When it returns false it
+// has already responded with the problem.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(v)
+	if err == nil && decoder.Decode(&struct{}{}) != io.EOF {
+		err = errTrailingData
+	}
+	if err == nil {
+		return true
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		respondWithError(w, r, problemBodyTooLarge, fmt.Sprintf("Request body is larger than %d bytes", maxBytesErr.Limit), err)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		respondWithFieldErrors(w, r, problemInvalidBody, FieldError{
+			Field:  typeErr.Field,
+			Code:   "invalid_type",
+			Detail: fmt.Sprintf("%s must be a %s", typeErr.Field, jsonTypeName(typeErr.Type.Kind().String())),
+		})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no error type for these
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		respondWithFieldErrors(w, r, problemInvalidBody, FieldError{
+			Field:  field,
+			Code:   "unknown",
+			Detail: "Unknown field " + field,
+		})
+	case errors.Is(err, errTrailingData):
+		respondWithError(w, r, problemInvalidBody, "Request body must be a single JSON value", err)
+	default:
+		respondWithError(w, r, problemInvalidBody, "Couldn't decode parameters", err)
+	}
+	return false
+}
+
+var errTrailingData = errors.New("trailing data after the JSON value")
+
+// jsonTypeName names a Go kind the way a JSON client would.
+func jsonTypeName(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "number"
+	case kind == "bool":
+		return "boolean"
+	case kind == "slice", kind == "array":
+		return "list"
+	case kind == "struct", kind == "map", kind == "ptr":
+		return "object"
+	}
+	return kind
+}
+
+// validation collects what's wrong with a request body, so clients can fix
+// every field in one go.
+type validation struct {
+	errors []FieldError
+}
+
+func (v *validation) add(field, code, detail string) {
+	v.errors = append(v.errors, FieldError{Field: field, Code: code, Detail: detail})
+}
+
+func (v *validation) required(field, value, name string) {
+	if value == "" {
+		v.add(field, "required", name+" is required")
+	}
+}
+
+// email checks a normalized email address.
+func (v *validation) email(field, value string) {
+	if value == "" {
+		v.add(field, "required", "Email is required")
+		return
+	}
+
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Address != value {
+		v.add(field, "invalid", "Please provide a valid email address")
+	}
+}
+
+// failed responds with the collected field errors, if there are any.
+func (v *validation) failed(w http.ResponseWriter, r *http.Request) bool {
+	if len(v.errors) == 0 {
+		return false
+	}
+	respondWithFieldErrors(w, r, problemValidation, v.errors...)
+	return true
+}
+
+// normalizeEmail makes the addresses users type match the ones they signed
+// up with.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// chirpLength counts what readers see as characters, so an emoji made of
+// several code points is one of them.
+func chirpLength(body string) int {
+	return uniseg.GraphemeClusterCount(body)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	ts := newTestServer(t)
+
+	tests := []struct {
+		name      string
+		body      string
+		want      problemType
+		wantField string
+	}{
+		{
+			name:      "unknown field",
+			body:      `{"email": "mike@example.com", "password": "hunter2", "admin": true}`,
+			want:      problemInvalidBody,
+			wantField: "admin",
+		},
+		{
+			name: "trailing data",
+			body: `{"email": "mike@example.com", "password": "hunter2"} {}`,
+			want: problemInvalidBody,
+		},
+		{
+			name:      "wrong type",
+			body:      `{"email": 42, "password": "hunter2"}`,
+			want:      problemInvalidBody,
+			wantField: "email",
+		},
+		{
+			name: "too large",
+			body: `{"email": "mike@example.com", "password": "` + strings.Repeat("a", maxRequestBodyBytes) + `"}`,
+			want: problemBodyTooLarge,
+		},
+		{
+			name:      "invalid email",
+			body:      `{"email": "mike at example.com", "password": "hunter2"}`,
+			want:      problemValidation,
+			wantField: "email",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := expectProblem(t, ts.do("POST", "/api/users", tt.body, ""), tt.want)
+
+			var field string
+			if len(problem.Errors) > 0 {
+				field = problem.Errors[0].Field
+			}
+			if field != tt.wantField {
+				t.Errorf("field errors = %+v, want field %q", problem.Errors, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestEmailNormalization(t *testing.T) {
+	ts := newTestServer(t)
+
+	rec := ts.do("POST", "/api/users", UserCredentials{Email: "  Gus@Example.COM ", Password: "hunter2"}, "")
+	expectStatus(t, rec, http.StatusCreated)
+	if user := decodeBody[User](t, rec); user.Email != "gus@example.com" {
+		t.Errorf("email = %q, want gus@example.com", user.Email)
+	}
+
+	user := ts.login("GUS@example.com", "hunter2")
+
+	rec = ts.do("PUT", "/api/users", UserCredentials{Email: " Lydia@Example.com", Password: "hunter2"}, bearer(user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	if updated := decodeBody[User](t, rec); updated.PendingEmail != "lydia@example.com" {
+		t.Errorf("pending email = %q, want lydia@example.com", updated.PendingEmail)
+	}
+}
+
+func TestChirpLength(t *testing.T) {
+	tests := []struct {
+		body string
+		want int
+	}{
+		{"hello", 5},
+		{"héllo", 5},
+		{"é", 1},
+		{"👍🏽", 1},
+		{"👨‍👩‍👧‍👦 family", 8},
+		{"🇫🇷", 1},
+	}
+
+	for _, tt := range tests {
+		if got := chirpLength(tt.body); got != tt.want {
+			t.Errorf("chirpLength(%q) = %d, want %d", tt.body, got, tt.want)
+		}
+	}
+
+	ts := newTestServer(t)
+	user := ts.signUp("hank@example.com")
+
+	// Each family emoji is 25 bytes but one character
+	ts.createChirp(user.Token, strings.Repeat("👨‍👩‍👧‍👦", 140))
+	expectProblem(t, ts.do("POST", "/api/chirps", map[string]string{"body": strings.Repeat("👨‍👩‍👧‍👦", 141)}, bearer(user.Token)), problemChirpTooLong)
+}
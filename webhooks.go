@@ -1,59 +1,262 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"time"
 
-	"github.com/google/uuid"
-	"github.com/tracevt/chirpy/internal/auth"
+	"github.com/tracevt/chirpy/internal/database"
+	"github.com/tracevt/chirpy/internal/webhook"
 )
 
+const (
+	polkaSignatureHeader = "X-Polka-Signature"
+	// polkaSignatureTolerance is how far a delivery's signed timestamp may be
+	// from our clock. Older deliveries are rejected as replays.
+	polkaSignatureTolerance = 5 * time.Minute
+	maxWebhookBodyBytes     = 1 << 20
+)
+
+const (
+	webhookStatusReceived  = "received"
+	webhookStatusProcessed = "processed"
+	webhookStatusIgnored   = "ignored"
+	webhookStatusFailed    = "failed"
+)
+
+var errWebhookUserNotFound = errors.New("user not found")
+
 type UserData struct {
-	UserID string `json:"user_id"`
+	UserID           string     `json:"user_id"`
+	Plan             string     `json:"plan"`
+	CurrentPeriodEnd *time.Time `json:"current_period_end"`
 }
 
 type WebhookEvent struct {
+	ID    string   `json:"id"`
 	Event string   `json:"event"`
 	Data  UserData `json:"data"`
 }
 
+type StoredWebhookEvent struct {
+	ID          string          `json:"id"`
+	Event       string          `json:"event"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Error       string          `json:"error,omitempty"`
+	Attempts    int32           `json:"attempts"`
+	ReceivedAt  time.Time       `json:"received_at"`
+	ProcessedAt *time.Time      `json:"processed_at"`
+}
+
+func storedWebhookEventFromDB(event database.WebhookEvent) StoredWebhookEvent {
+	stored := StoredWebhookEvent{
+		ID:         event.ID,
+		Event:      event.EventType,
+		Payload:    json.RawMessage(event.Payload),
+		Status:     event.Status,
+		Error:      event.LastError,
+		Attempts:   event.Attempts,
+		ReceivedAt: event.ReceivedAt,
+	}
+	if event.ProcessedAt.Valid {
+		stored.ProcessedAt = &event.ProcessedAt.Time
+	}
+	return stored
+}
+
 func (cfg *apiConfig) parseEvent(w http.ResponseWriter, r *http.Request) {
-	// Check for authorization
-	providedKey, err := auth.GetAPIKey(r.Header)
+	// Without a key nothing can be verified, so nothing is accepted
+	if cfg.polka == "" {
+		respondWithError(w, r, problemInvalidSignature, "Webhooks aren't configured", webhook.ErrNoSecret)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
 
-	if err != nil || providedKey != cfg.polka {
-		respondWithJSON(w, http.StatusUnauthorized, struct{}{})
+	if err != nil {
+		respondWithError(w, r, problemInvalidBody, "Couldn't read the request body", err)
+		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
-	event := WebhookEvent{}
-	err = decoder.Decode(&event)
+	signature := r.Header.Get(polkaSignatureHeader)
+	err = webhook.Verify(cfg.polka, signature, payload, polkaSignatureTolerance, time.Now())
 
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		respondWithError(w, r, problemInvalidSignature, "Invalid webhook signature", err)
 		return
 	}
 
-	if event.Event != "user.upgraded" {
-		respondWithNoContent(w)
+	event := WebhookEvent{}
+	err = json.Unmarshal(payload, &event)
+
+	if err != nil {
+		respondWithError(w, r, problemInvalidBody, "Couldn't decode parameters", err)
 		return
 	}
 
-	if event.Event == "user.upgraded" {
-		// Upgrade the user
-		userID, err := uuid.Parse(event.Data.UserID)
+	// Events without an ID are identified by their content and the time
+	// they were signed. The same payload can legitimately come again, like
+	// an upgrade after a downgrade, but not with the same signature.
+	if event.ID == "" {
+		signedAt, err := webhook.Timestamp(signature)
 
 		if err != nil {
-			respondWithNoContent(w)
+			respondWithError(w, r, problemInvalidSignature, "Invalid webhook signature", err)
 			return
 		}
 
-		_, err = cfg.db.UpdateUserChirpyRed(r.Context(), userID)
+		sum := sha256.Sum256(fmt.Appendf(nil, "%d.%s", signedAt.Unix(), payload))
+		event.ID = hex.EncodeToString(sum[:])
+	}
+
+	created, err := cfg.db.CreateWebhookEvent(r.Context(), database.CreateWebhookEventParams{
+		ID:        event.ID,
+		EventType: event.Event,
+		Payload:   string(payload),
+	})
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't record the webhook event", err)
+		return
+	}
+
+	if created == 0 {
+		stored, err := cfg.db.GetWebhookEvent(r.Context(), event.ID)
 
 		if err != nil {
-			respondWithStatusCode(w, http.StatusNotFound)
+			respondWithError(w, r, problemInternal, "Couldn't retrieve the webhook event", err)
+			return
+		}
+
+		// Anything but a failure was handled already, or is being handled by
+		// a concurrent delivery
+		if stored.Status != webhookStatusFailed {
+			respondWithNoContent(w)
+			return
 		}
+	}
+
+	err = cfg.handleWebhookEvent(r.Context(), event)
+
+	if errors.Is(err, errWebhookUserNotFound) {
+		respondWithError(w, r, problemNotFound, "User not found", err)
+		return
+	}
+
+	if errors.Is(err, errWebhookSubscriptionNotFound) {
+		respondWithError(w, r, problemNotFound, "Subscription not found", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't process the webhook event", err)
+		return
+	}
+
+	respondWithNoContent(w)
+}
+
+// handleWebhookEvent processes a recorded event and stores the outcome.
+func (cfg *apiConfig) handleWebhookEvent(ctx context.Context, event WebhookEvent) error {
+	status, err := cfg.processWebhookEvent(ctx, event)
+	cfg.metrics.webhookEvents.WithLabelValues(status).Inc()
 
-		respondWithJSON(w, http.StatusNoContent, struct{}{})
+	lastError := ""
+	if err != nil {
+		lastError = err.Error()
+	}
+
+	updateErr := cfg.db.UpdateWebhookEventStatus(ctx, database.UpdateWebhookEventStatusParams{
+		ID:        event.ID,
+		Status:    status,
+		LastError: lastError,
+	})
+	if updateErr != nil {
+		return updateErr
+	}
+
+	return err
+}
+
+func (cfg *apiConfig) processWebhookEvent(ctx context.Context, event WebhookEvent) (string, error) {
+	switch event.Event {
+	case "user.upgraded", "user.downgraded", "subscription.renewed", "payment.failed":
+		return cfg.processSubscriptionEvent(ctx, event)
+	}
+
+	return webhookStatusIgnored, nil
+}
+
+func (cfg *apiConfig) getFailedWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		respondWithError(w, r, problemAdminRequired, "Admin access required", nil)
+		return
+	}
+
+	events, err := cfg.db.GetWebhookEventsByStatus(r.Context(), webhookStatusFailed)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve webhook events", err)
+		return
+	}
+
+	jsonEvents := make([]StoredWebhookEvent, 0)
+	for _, event := range events {
+		jsonEvents = append(jsonEvents, storedWebhookEventFromDB(event))
+	}
+
+	respondWithJSON(w, http.StatusOK, jsonEvents)
+}
+
+func (cfg *apiConfig) replayWebhookEvent(w http.ResponseWriter, r *http.Request) {
+	if !cfg.isAdmin(r) {
+		respondWithError(w, r, problemAdminRequired, "Admin access required", nil)
+		return
 	}
+
+	stored, err := cfg.db.GetWebhookEvent(r.Context(), r.PathValue("eventID"))
+
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, r, problemNotFound, "Webhook event not found", err)
+		return
+	}
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve the webhook event", err)
+		return
+	}
+
+	if stored.Status != webhookStatusFailed {
+		respondWithError(w, r, problemConflict, "Only failed webhook events can be replayed", nil)
+		return
+	}
+
+	event := WebhookEvent{}
+	err = json.Unmarshal([]byte(stored.Payload), &event)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't decode the stored payload", err)
+		return
+	}
+	event.ID = stored.ID
+
+	// A failed replay is recorded on the event, which is returned either way
+	cfg.handleWebhookEvent(r.Context(), event)
+
+	stored, err = cfg.db.GetWebhookEvent(r.Context(), stored.ID)
+
+	if err != nil {
+		respondWithError(w, r, problemInternal, "Couldn't retrieve the webhook event", err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, storedWebhookEventFromDB(stored))
 }
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tracevt/chirpy/internal/database"
+	"github.com/tracevt/chirpy/internal/webhook"
+)
+
+func TestPolkaWebhooks(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	rec := ts.do("POST", "/api/polka/webhooks", WebhookEvent{Event: "user.upgraded", Data: UserData{UserID: user.ID.String()}}, "")
+	expectStatus(t, rec, http.StatusUnauthorized)
+
+	expectStatus(t, ts.do("GET", "/api/subscription", nil, ""), http.StatusUnauthorized)
+	expectStatus(t, ts.do("GET", "/api/subscription", nil, bearer(user.Token)), http.StatusNotFound)
+
+	event := WebhookEvent{ID: "evt_1", Event: "user.upgraded", Data: UserData{UserID: user.ID.String()}}
+	expectStatus(t, ts.sendPolkaEvent(event), http.StatusNoContent)
+	// Redeliveries are acknowledged without being processed again
+	expectStatus(t, ts.sendPolkaEvent(event), http.StatusNoContent)
+
+	rec = ts.do("GET", "/api/subscription", nil, bearer(user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	if sub := decodeBody[Subscription](t, rec); sub.Plan != chirpyRedPlan || sub.Status != subscriptionStatusActive {
+		t.Errorf("subscription = %s", rec.Body.String())
+	}
+
+	expectStatus(t, ts.sendPolkaEvent(WebhookEvent{ID: "evt_2", Event: "user.created"}), http.StatusNoContent)
+}
+
+func TestPolkaEventsWithoutIDs(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	upgraded := WebhookEvent{Event: "user.upgraded", Data: UserData{UserID: user.ID.String()}}
+	downgraded := WebhookEvent{Event: "user.downgraded", Data: UserData{UserID: user.ID.String()}}
+
+	// Identical payloads are told apart by the time they were signed
+	signedAt := time.Now().Add(-time.Minute)
+	for i, tt := range []struct {
+		event      WebhookEvent
+		wantStatus string
+	}{
+		{upgraded, subscriptionStatusActive},
+		{downgraded, subscriptionStatusCanceled},
+		{upgraded, subscriptionStatusActive},
+	} {
+		expectStatus(t, ts.sendPolkaEventAt(tt.event, signedAt.Add(time.Duration(i)*time.Second)), http.StatusNoContent)
+
+		rec := ts.do("GET", "/api/subscription", nil, bearer(user.Token))
+		expectStatus(t, rec, http.StatusOK)
+		if sub := decodeBody[Subscription](t, rec); sub.Status != tt.wantStatus {
+			t.Fatalf("after %s, status = %q, want %q", tt.event.Event, sub.Status, tt.wantStatus)
+		}
+	}
+
+	// A redelivery of the last one is still recognized
+	expectStatus(t, ts.sendPolkaEventAt(downgraded, signedAt.Add(time.Second)), http.StatusNoContent)
+	rec := ts.do("GET", "/api/subscription", nil, bearer(user.Token))
+	if sub := decodeBody[Subscription](t, rec); sub.Status != subscriptionStatusActive {
+		t.Errorf("a redelivered downgrade was processed again: %s", rec.Body.String())
+	}
+}
+
+func TestPolkaPeriodEndWithOffset(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	periodEnd := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second).In(time.FixedZone("UTC-7", -7*60*60))
+	event := WebhookEvent{ID: "evt_offset", Event: "user.upgraded", Data: UserData{UserID: user.ID.String(), CurrentPeriodEnd: &periodEnd}}
+	expectStatus(t, ts.sendPolkaEvent(event), http.StatusNoContent)
+
+	rec := ts.do("GET", "/api/subscription", nil, bearer(user.Token))
+	expectStatus(t, rec, http.StatusOK)
+	if sub := decodeBody[Subscription](t, rec); !sub.CurrentPeriodEnd.Equal(periodEnd) || sub.CurrentPeriodEnd.Location() != time.UTC {
+		t.Errorf("current_period_end = %v, want %v in UTC", sub.CurrentPeriodEnd, periodEnd.UTC())
+	}
+}
+
+func TestPolkaPeriodsWestOfUTC(t *testing.T) {
+	westOfUTC(t)
+
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+
+	subscription := func() Subscription {
+		t.Helper()
+
+		rec := ts.do("GET", "/api/subscription", nil, bearer(user.Token))
+		expectStatus(t, rec, http.StatusOK)
+		return decodeBody[Subscription](t, rec)
+	}
+
+	// Periods Polka doesn't supply start now, not hours off by the zone
+	start := time.Now()
+	ts.upgrade(user.ID)
+	if got := subscription().CurrentPeriodEnd.Sub(start); got < subscriptionPeriod-time.Minute || got > subscriptionPeriod+time.Minute {
+		t.Errorf("current period ends %v from now, want %v", got, subscriptionPeriod)
+	}
+
+	expectStatus(t, ts.sendPolkaEvent(WebhookEvent{ID: "evt_failed", Event: "payment.failed", Data: UserData{UserID: user.ID.String()}}), http.StatusNoContent)
+	sub := subscription()
+	if sub.GracePeriodEnd == nil {
+		t.Fatalf("subscription = %+v, want a grace period", sub)
+	}
+	if got := sub.GracePeriodEnd.Sub(start); got < subscriptionGracePeriod-time.Minute || got > subscriptionGracePeriod+time.Minute {
+		t.Errorf("grace period ends %v from now, want %v", got, subscriptionGracePeriod)
+	}
+}
+
+func TestPolkaWebhooksWithoutKey(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.signUp("walt@example.com")
+	ts.cfg.polka = ""
+
+	event := WebhookEvent{ID: "evt_forged", Event: "user.upgraded", Data: UserData{UserID: user.ID.String()}}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	// Signed with the empty key, as anyone could
+	req := httptest.NewRequest("POST", "/api/polka/webhooks", bytes.NewReader(payload))
+	req.Header.Set(polkaSignatureHeader, webhook.Sign("", time.Now(), payload))
+	rec := httptest.NewRecorder()
+	ts.handler.ServeHTTP(rec, req)
+	expectProblem(t, rec, problemInvalidSignature)
+
+	expectStatus(t, ts.do("GET", "/api/subscription", nil, bearer(user.Token)), http.StatusNotFound)
+}
+
+func TestReplayFailedWebhookEvents(t *testing.T) {
+	ts := newTestServer(t)
+	userID := uuid.New()
+
+	event := WebhookEvent{ID: "evt_early", Event: "user.upgraded", Data: UserData{UserID: userID.String()}}
+	expectStatus(t, ts.sendPolkaEvent(event), http.StatusNotFound)
+	expectStatus(t, ts.sendPolkaEvent(WebhookEvent{ID: "evt_ignored", Event: "user.created"}), http.StatusNoContent)
+
+	expectStatus(t, ts.do("GET", "/admin/webhooks/failed", nil, ""), http.StatusForbidden)
+
+	rec := ts.do("GET", "/admin/webhooks/failed", nil, adminKey())
+	expectStatus(t, rec, http.StatusOK)
+	failed := decodeBody[[]StoredWebhookEvent](t, rec)
+	if len(failed) != 1 || failed[0].ID != event.ID || failed[0].Status != webhookStatusFailed {
+		t.Fatalf("failed events = %s", rec.Body.String())
+	}
+
+	expectStatus(t, ts.do("POST", "/admin/webhooks/evt_missing/replay", nil, adminKey()), http.StatusNotFound)
+	expectStatus(t, ts.do("POST", "/admin/webhooks/evt_ignored/replay", nil, adminKey()), http.StatusConflict)
+
+	// Replaying before the user exists fails again
+	rec = ts.do("POST", "/admin/webhooks/evt_early/replay", nil, adminKey())
+	expectStatus(t, rec, http.StatusOK)
+	if replayed := decodeBody[StoredWebhookEvent](t, rec); replayed.Status != webhookStatusFailed || replayed.Attempts != 2 {
+		t.Errorf("replayed event = %s", rec.Body.String())
+	}
+
+	// Once whatever failed is fixed, the replay goes through
+	user := ts.signUp("walt@example.com")
+	payload, err := json.Marshal(WebhookEvent{ID: "evt_fixed", Event: "user.upgraded", Data: UserData{UserID: user.ID.String()}})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	ctx := context.Background()
+	if _, err := ts.db.CreateWebhookEvent(ctx, database.CreateWebhookEventParams{ID: "evt_fixed", EventType: "user.upgraded", Payload: string(payload)}); err != nil {
+		t.Fatalf("CreateWebhookEvent() error = %v", err)
+	}
+	if err := ts.db.UpdateWebhookEventStatus(ctx, database.UpdateWebhookEventStatusParams{ID: "evt_fixed", Status: webhookStatusFailed, LastError: "database unavailable"}); err != nil {
+		t.Fatalf("UpdateWebhookEventStatus() error = %v", err)
+	}
+
+	rec = ts.do("POST", "/admin/webhooks/evt_fixed/replay", nil, adminKey())
+	expectStatus(t, rec, http.StatusOK)
+	if replayed := decodeBody[StoredWebhookEvent](t, rec); replayed.Status != webhookStatusProcessed || replayed.Error != "" {
+		t.Errorf("replayed event = %s", rec.Body.String())
+	}
+
+	rec = ts.do("GET", "/api/subscription", nil, bearer(user.Token))
+	expectStatus(t, rec, http.StatusOK)
+}